@@ -2,12 +2,18 @@ package assistant
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 
+	"github.com/maksymenkoml/lingoose/event"
 	obs "github.com/maksymenkoml/lingoose/observer"
+	"github.com/maksymenkoml/lingoose/prompt"
 	"github.com/maksymenkoml/lingoose/thread"
+	"github.com/maksymenkoml/lingoose/tool"
+	"github.com/maksymenkoml/lingoose/tool/llm_with_usage"
 	"github.com/maksymenkoml/lingoose/types"
+	"github.com/maksymenkoml/lingoose/usage"
 )
 
 type Parameters struct {
@@ -31,7 +37,13 @@ type Assistant struct {
 	llm           LLM
 	rag           RAG
 	thread        *thread.Thread
+	store         Store
+	compactor     Compactor
 	parameters    Parameters
+	prompts       *prompt.Registry
+	pricing       usage.Pricing
+	budget        usage.Budget
+	tracker       *usage.Tracker
 	maxIterations uint
 }
 
@@ -43,6 +55,15 @@ type RAG interface {
 	Retrieve(ctx context.Context, query string) ([]string, error)
 }
 
+type Compactor interface {
+	Compact(context.Context, *thread.Thread) error
+}
+
+type Store interface {
+	Save(ctx context.Context, id string, t *thread.Thread) error
+	Load(ctx context.Context, id string) (*thread.Thread, error)
+}
+
 func New(llm LLM) *Assistant {
 	assistant := &Assistant{
 		llm:    llm,
@@ -70,47 +91,165 @@ func (a *Assistant) WithRAG(rag RAG) *Assistant {
 	return a
 }
 
+// WithStore sets the store used by RunWithSession to persist threads.
+func (a *Assistant) WithStore(store Store) *Assistant {
+	a.store = store
+	return a
+}
+
+// WithCompactor sets the compactor used to condense the thread history before each
+// iteration, e.g. when the thread exceeds a token budget.
+func (a *Assistant) WithCompactor(compactor Compactor) *Assistant {
+	a.compactor = compactor
+	return a
+}
+
 func (a *Assistant) WithParameters(parameters Parameters) *Assistant {
 	a.parameters = parameters
 	return a
 }
 
+// WithPrompts sets the registry used to override the built-in prompts, see
+// SystemPromptName and RAGPromptName.
+func (a *Assistant) WithPrompts(prompts *prompt.Registry) *Assistant {
+	a.prompts = prompts
+	return a
+}
+
+// WithPricing sets the pricing table used to compute the cost of the runs. The
+// usage.DefaultPricing is used by default.
+func (a *Assistant) WithPricing(pricing usage.Pricing) *Assistant {
+	a.pricing = pricing
+	return a
+}
+
+// WithBudget caps the usage of each run. A run exceeding the budget is aborted before
+// its next iteration with usage.ErrBudgetExceeded. The budget of a usage.Tracker in the
+// context of the run is enforced too.
+func (a *Assistant) WithBudget(budget usage.Budget) *Assistant {
+	a.budget = budget
+	return a
+}
+
+// Usage returns the usage of the last run, as recorded by the LLM and the RAG
+// embedder.
+func (a *Assistant) Usage() usage.Usage {
+	if a.tracker == nil {
+		return usage.Usage{}
+	}
+
+	return a.tracker.Total()
+}
+
 func (a *Assistant) Run(ctx context.Context) error {
+	_, err := a.run(ctx)
+	return err
+}
+
+// run runs the assistant and returns the messages generated by the run: the messages of
+// the LLM and the tool responses, without the system and RAG prompts of the run.
+func (a *Assistant) run(ctx context.Context) ([]*thread.Message, error) {
 	if a.thread == nil {
-		return nil
+		return nil, nil
+	}
+
+	// the usage of the run is recorded into the tracker of the context too, if any
+	a.tracker = usage.NewTracker().
+		WithBudget(a.budget).
+		WithParent(usage.ContextValueTracker(ctx))
+	if a.pricing != nil {
+		a.tracker.WithPricing(a.pricing)
 	}
+	ctx = usage.ContextWithTracker(ctx, a.tracker)
+	// the tool calls of the run are a single chain, whose repairs are bounded
+	ctx = tool.ContextWithRepairs(ctx)
 
 	ctx, spanAssistant, err := a.startObserveSpan(ctx, "assistant")
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	if a.rag != nil {
 		errGenerate := a.generateRAGMessage(ctx)
 		if errGenerate != nil {
-			return errGenerate
+			return nil, errGenerate
 		}
 	} else {
-		a.injectSystemMessage()
+		errInject := a.injectSystemMessage()
+		if errInject != nil {
+			return nil, errInject
+		}
+	}
+
+	promptMessages := make(map[*thread.Message]bool, len(a.thread.Messages))
+	for _, message := range a.thread.Messages {
+		promptMessages[message] = true
 	}
 
 	for i := 0; i < int(a.maxIterations); i++ {
+		err = a.tracker.CheckBudget()
+		if err != nil {
+			return nil, err
+		}
+
 		err = a.runIteration(ctx, i)
 		if err != nil {
-			return err
+			return nil, err
+		}
+
+		err = tool.CheckRepairs(ctx)
+		if err != nil {
+			return nil, err
 		}
 
-		if a.thread.LastMessage().Role != thread.RoleTool {
+		if a.thread.LastMessage().Role != thread.RoleTool || handoffRequested(ctx) {
 			break
 		}
 	}
 
 	err = a.stopObserveSpan(ctx, spanAssistant)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return nil
+	event.Emit(ctx, event.NewFinalMessage(a.thread.LastMessage()))
+
+	var generatedMessages []*thread.Message
+	for _, message := range a.thread.Messages {
+		// the summaries of the compactor are system messages
+		if !promptMessages[message] && message.Role != thread.RoleSystem {
+			generatedMessages = append(generatedMessages, message)
+		}
+	}
+
+	return generatedMessages, nil
+}
+
+// RunStream runs the assistant in background and returns the channel of the events
+// emitted during the run. The channel is closed when the run ends, a failed run ends
+// with an event of type event.TypeError. Text deltas are emitted only if the LLM has
+// streaming enabled.
+func (a *Assistant) RunStream(ctx context.Context) <-chan event.Event {
+	events := make(chan event.Event)
+	done := ctx.Done()
+
+	emit := func(e event.Event) {
+		select {
+		case events <- e:
+		case <-done:
+		}
+	}
+
+	go func() {
+		defer close(events)
+
+		err := a.Run(event.ContextWithEmitter(ctx, emit))
+		if err != nil {
+			emit(event.NewError(err))
+		}
+	}()
+
+	return events
 }
 
 func (a *Assistant) runIteration(ctx context.Context, iteration int) error {
@@ -119,7 +258,16 @@ func (a *Assistant) runIteration(ctx context.Context, iteration int) error {
 		return err
 	}
 
-	err = a.llm.Generate(ctx, a.thread)
+	event.Emit(ctx, event.NewIteration(iteration+1))
+
+	if a.compactor != nil {
+		err = a.compactor.Compact(ctx, a.thread)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = a.generate(ctx)
 	if err != nil {
 		return err
 	}
@@ -132,11 +280,57 @@ func (a *Assistant) runIteration(ctx context.Context, iteration int) error {
 	return nil
 }
 
+// generate lets the LLM generate the next messages of the thread. When the run has an
+// event emitter and the LLM reports the tokens used, a usage event is emitted.
+func (a *Assistant) generate(ctx context.Context) error {
+	llmWithUsage, ok := a.llm.(llm_with_usage.LLMWithUsage)
+	if !ok || event.ContextValueEmitter(ctx) == nil {
+		return a.llm.Generate(ctx, a.thread)
+	}
+
+	usage, err := llmWithUsage.GenerateWithUsage(ctx, a.thread)
+	if err != nil {
+		return err
+	}
+
+	if usage != nil {
+		event.Emit(ctx, event.NewUsage(event.Usage{
+			PromptTokens:     usage.PromptTokens,
+			CompletionTokens: usage.CompletionTokens,
+		}))
+	}
+
+	return nil
+}
+
 func (a *Assistant) RunWithThread(ctx context.Context, thread *thread.Thread) error {
 	a.thread = thread
 	return a.Run(ctx)
 }
 
+// RunWithSession loads the thread of the given session from the store, appends the
+// given messages and runs the assistant. The resulting thread is saved back to the store,
+// even if the run fails, so that the appended messages are not lost. A new thread is
+// started if the store has no thread for the session.
+func (a *Assistant) RunWithSession(ctx context.Context, sessionID string, messages ...*thread.Message) error {
+	if a.store == nil {
+		return fmt.Errorf("store is not set")
+	}
+
+	t, err := a.store.Load(ctx, sessionID)
+	if errors.Is(err, thread.ErrThreadNotFound) {
+		t = thread.New()
+	} else if err != nil {
+		return err
+	}
+
+	a.thread = t.AddMessages(messages...)
+
+	runErr := a.Run(ctx)
+
+	return errors.Join(runErr, a.store.Save(ctx, sessionID, a.thread))
+}
+
 func (a *Assistant) Thread() *thread.Thread {
 	return a.thread
 }
@@ -155,27 +349,23 @@ func (a *Assistant) generateRAGMessage(ctx context.Context) error {
 		return err
 	}
 
-	a.thread.AddMessage(thread.NewSystemMessage().AddContent(
-		thread.NewTextContent(
-			systemPrompt,
-		).Format(
-			types.M{
-				"assistantName":      a.parameters.AssistantName,
-				"assistantIdentity":  a.parameters.AssistantIdentity,
-				"assistantScope":     a.parameters.AssistantScope,
-				"companyName":        a.parameters.CompanyName,
-				"companyDescription": a.parameters.CompanyDescription,
-			},
-		),
-	)).AddMessage(thread.NewUserMessage().AddContent(
-		thread.NewTextContent(
-			baseRAGPrompt,
-		).Format(
-			types.M{
-				"question": query,
-				"results":  searchResults,
-			},
-		),
+	event.Emit(ctx, event.NewRetrieval(query, searchResults))
+
+	systemMessage, err := a.systemMessage()
+	if err != nil {
+		return err
+	}
+
+	ragPrompt, err := prompt.Format(a.prompts, RAGPromptName, baseRAGPrompt, types.M{
+		"question": query,
+		"results":  searchResults,
+	})
+	if err != nil {
+		return err
+	}
+
+	a.thread.AddMessage(systemMessage).AddMessage(thread.NewUserMessage().AddContent(
+		thread.NewTextContent(ragPrompt),
 	))
 
 	return nil
@@ -223,26 +413,36 @@ func (a *Assistant) stopObserveSpan(ctx context.Context, span *obs.Span) error {
 	return err
 }
 
-func (a *Assistant) injectSystemMessage() {
+func (a *Assistant) injectSystemMessage() error {
 	for _, message := range a.thread.Messages {
 		if message.Role == thread.RoleSystem {
-			return
+			return nil
 		}
 	}
 
-	systemMessage := thread.NewSystemMessage().AddContent(
-		thread.NewTextContent(
-			systemPrompt,
-		).Format(
-			types.M{
-				"assistantName":      a.parameters.AssistantName,
-				"assistantIdentity":  a.parameters.AssistantIdentity,
-				"assistantScope":     a.parameters.AssistantScope,
-				"companyName":        a.parameters.CompanyName,
-				"companyDescription": a.parameters.CompanyDescription,
-			},
-		),
-	)
+	systemMessage, err := a.systemMessage()
+	if err != nil {
+		return err
+	}
 
 	a.thread.Messages = append([]*thread.Message{systemMessage}, a.thread.Messages...)
+
+	return nil
+}
+
+func (a *Assistant) systemMessage() (*thread.Message, error) {
+	text, err := prompt.Format(a.prompts, SystemPromptName, systemPrompt, types.M{
+		"assistantName":      a.parameters.AssistantName,
+		"assistantIdentity":  a.parameters.AssistantIdentity,
+		"assistantScope":     a.parameters.AssistantScope,
+		"companyName":        a.parameters.CompanyName,
+		"companyDescription": a.parameters.CompanyDescription,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return thread.NewSystemMessage().AddContent(
+		thread.NewTextContent(text),
+	), nil
 }
@@ -0,0 +1,225 @@
+package assistant
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/maksymenkoml/lingoose/event"
+	"github.com/maksymenkoml/lingoose/prompt"
+	"github.com/maksymenkoml/lingoose/thread"
+	"github.com/maksymenkoml/lingoose/thread/store/filesystem"
+	"github.com/maksymenkoml/lingoose/tool"
+	"github.com/maksymenkoml/lingoose/usage"
+)
+
+type echoInput struct {
+	Text string `json:"text"`
+}
+
+type toolLLM struct {
+	tools     *tool.Registry
+	err       error
+	usage     usage.Usage
+	arguments string
+}
+
+func (l *toolLLM) Generate(ctx context.Context, t *thread.Thread) error {
+	if l.err != nil {
+		return l.err
+	}
+
+	usage.Record(ctx, l.usage)
+
+	arguments := l.arguments
+	if arguments == "" {
+		if t.LastMessage().Role == thread.RoleTool {
+			t.AddMessage(thread.NewAssistantMessage().AddContent(thread.NewTextContent("done")))
+			return nil
+		}
+
+		arguments = `{"text":"hi"}`
+	}
+
+	toolCalls := []thread.ToolCallData{{ID: "1", Name: "echo", Arguments: arguments}}
+	t.AddMessage(thread.NewAssistantMessage().AddContent(thread.NewToolCallContent(toolCalls)))
+	t.AddMessages(l.tools.CallTools(ctx, toolCalls)...)
+
+	return nil
+}
+
+func TestAssistant_RunStream(t *testing.T) {
+	tools := tool.NewRegistry()
+	err := tools.Bind(func(i echoInput) string { return i.Text }, "echo", "echo the text")
+	if err != nil {
+		t.Fatalf("Registry.Bind() error = %v", err)
+	}
+
+	a := New(&toolLLM{tools: tools}).WithThread(
+		thread.New().AddMessage(thread.NewUserMessage().AddContent(thread.NewTextContent("echo hi"))),
+	)
+
+	var types []event.Type
+	var last event.Event
+	for e := range a.RunStream(context.Background()) {
+		types = append(types, e.Type)
+		last = e
+	}
+
+	want := []event.Type{
+		event.TypeIteration,
+		event.TypeToolCall,
+		event.TypeToolResult,
+		event.TypeIteration,
+		event.TypeFinalMessage,
+	}
+	if !reflect.DeepEqual(types, want) {
+		t.Fatalf("Assistant.RunStream() events = %v, want %v", types, want)
+	}
+
+	if last.Message != a.Thread().LastMessage() {
+		t.Errorf("Assistant.RunStream() final message = %v, want %v", last.Message, a.Thread().LastMessage())
+	}
+}
+
+func TestAssistant_RunStreamError(t *testing.T) {
+	errGenerate := errors.New("generate failed")
+	a := New(&toolLLM{err: errGenerate}).WithThread(
+		thread.New().AddMessage(thread.NewUserMessage().AddContent(thread.NewTextContent("hi"))),
+	)
+
+	var last event.Event
+	for e := range a.RunStream(context.Background()) {
+		last = e
+	}
+
+	if last.Type != event.TypeError || !errors.Is(last.Err, errGenerate) {
+		t.Errorf("Assistant.RunStream() last event = %v, want error event", last)
+	}
+}
+
+func TestAssistant_WithPrompts(t *testing.T) {
+	prompts := prompt.NewRegistry()
+	err := prompts.Register(SystemPromptName, "1", "You are {{.assistantName}}, answer {{.tone}}.")
+	if err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	llm := &toolLLM{err: errors.New("generate called")}
+	a := New(llm).WithPrompts(prompts).WithThread(thread.New().AddMessage(
+		thread.NewUserMessage().AddContent(thread.NewTextContent("hi")),
+	))
+
+	err = a.Run(context.Background())
+	if !errors.Is(err, prompt.ErrMissingVariable) {
+		t.Fatalf("Run() error = %v, want %v", err, prompt.ErrMissingVariable)
+	}
+
+	err = prompts.Register(SystemPromptName, "2", "You are {{.assistantName}}.")
+	if err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	err = a.Run(context.Background())
+	if !errors.Is(err, llm.err) {
+		t.Fatalf("Run() error = %v, want %v", err, llm.err)
+	}
+
+	if got := a.Thread().Messages[0].Contents[0].AsString(); got != "You are AI assistant." {
+		t.Errorf("system prompt = %q", got)
+	}
+}
+
+func TestAssistant_WithBudget(t *testing.T) {
+	tools := tool.NewRegistry()
+	err := tools.Bind(func(i echoInput) string { return i.Text }, "echo", "echo the text")
+	if err != nil {
+		t.Fatalf("Registry.Bind() error = %v", err)
+	}
+
+	llm := &toolLLM{
+		tools: tools,
+		usage: usage.Usage{Model: "gpt-4o", PromptTokens: 1000000, CompletionTokens: 100000},
+	}
+	newThread := func() *thread.Thread {
+		return thread.New().AddMessage(thread.NewUserMessage().AddContent(thread.NewTextContent("echo hi")))
+	}
+
+	// the tool call of the first iteration needs a second one, that exceeds the budget
+	process := usage.NewTracker()
+	ctx := usage.ContextWithTracker(context.Background(), process)
+
+	a := New(llm).WithThread(newThread()).WithBudget(usage.Budget{MaxCost: 3})
+	err = a.Run(ctx)
+	if !errors.Is(err, usage.ErrBudgetExceeded) {
+		t.Fatalf("Assistant.Run() error = %v, want %v", err, usage.ErrBudgetExceeded)
+	}
+
+	if got := a.Usage(); got.TotalTokens() != 1100000 || got.Cost != 3.5 {
+		t.Errorf("Assistant.Usage() = %+v, want 1100000 tokens and 3.5 USD", got)
+	}
+
+	// the budget of the process is shared by the runs
+	process.WithBudget(usage.Budget{MaxTokens: 2000000})
+
+	err = New(llm).WithThread(newThread()).Run(ctx)
+	if !errors.Is(err, usage.ErrBudgetExceeded) {
+		t.Fatalf("Assistant.Run() error = %v, want %v", err, usage.ErrBudgetExceeded)
+	}
+
+	if got := process.Total().TotalTokens(); got != 2200000 {
+		t.Errorf("process usage = %d tokens, want 2200000", got)
+	}
+}
+
+func TestAssistant_RepairsExhausted(t *testing.T) {
+	tools := tool.NewRegistry().WithMaxRepairs(1)
+	err := tools.Bind(func(i echoInput) string { return i.Text }, "echo", "echo the text")
+	if err != nil {
+		t.Fatalf("Registry.Bind() error = %v", err)
+	}
+
+	// the model keeps sending invalid arguments
+	llm := &toolLLM{tools: tools, arguments: `{"text":1}`}
+
+	for run := 0; run < 2; run++ {
+		a := New(llm).
+			WithMaxIterations(10).
+			WithThread(thread.New().AddMessage(thread.NewUserMessage().AddContent(thread.NewTextContent("echo hi"))))
+
+		err = a.Run(context.Background())
+		if !errors.Is(err, tool.ErrRepairsExhausted) {
+			t.Fatalf("run %d: Assistant.Run() error = %v, want %v", run, err, tool.ErrRepairsExhausted)
+		}
+
+		// system, user, then a repair round trip and the final error, each run has its own repairs
+		if got := a.Thread().CountMessages(); got != 6 {
+			t.Errorf("run %d: thread has %d messages, want 6", run, got)
+		}
+	}
+}
+
+func TestAssistant_RunWithSessionError(t *testing.T) {
+	errGenerate := errors.New("generate failed")
+	store := filesystem.New(t.TempDir())
+
+	a := New(&toolLLM{err: errGenerate}).WithStore(store)
+	err := a.RunWithSession(
+		context.Background(),
+		"session-1",
+		thread.NewUserMessage().AddContent(thread.NewTextContent("hi")),
+	)
+	if !errors.Is(err, errGenerate) {
+		t.Fatalf("Assistant.RunWithSession() error = %v, want %v", err, errGenerate)
+	}
+
+	// the user message is saved even if the run failed
+	saved, err := store.Load(context.Background(), "session-1")
+	if err != nil {
+		t.Fatalf("Store.Load() error = %v", err)
+	}
+	if got := saved.LastMessage(); got.Role != thread.RoleUser || got.Contents[0].AsString() != "hi" {
+		t.Errorf("saved thread = %v, want the user message", saved)
+	}
+}
@@ -0,0 +1,305 @@
+package assistant
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/maksymenkoml/lingoose/thread"
+	"github.com/maksymenkoml/lingoose/tool"
+)
+
+var (
+	ErrUnknownAgent = errors.New("unknown agent")
+	ErrMaxHandoffs  = errors.New("maximum number of handoffs reached")
+)
+
+// ThreadMode defines how the agents of a coordinator share the thread.
+type ThreadMode int
+
+const (
+	// ThreadModeShared lets the agents hand the conversation off to each other. All
+	// the agents work on the coordinator thread, each one with its own system prompt.
+	ThreadModeShared ThreadMode = iota
+	// ThreadModeIsolated lets the agents delegate sub-tasks to each other. The delegated
+	// agent works on a new thread and its answer is returned as the tool result.
+	ThreadModeIsolated
+)
+
+const (
+	DefaultMaxHandoffs = 3
+	handoffToolPrefix  = "handoff_to_"
+)
+
+type handoffContextKey struct{}
+
+type agent struct {
+	name        string
+	description string
+	assistant   *Assistant
+}
+
+// handoff holds the agent the conversation has been handed off to during a run.
+type handoff struct {
+	mu     sync.Mutex
+	target string
+}
+
+// Coordinator runs a set of named assistants that can hand the conversation, or a
+// sub-task, off to each other through the tools returned by HandoffTools. The first
+// agent added is the one that starts the conversation.
+type Coordinator struct {
+	agents      map[string]*agent
+	names       []string
+	active      string
+	thread      *thread.Thread
+	threadMode  ThreadMode
+	maxHandoffs uint
+}
+
+func NewCoordinator() *Coordinator {
+	return &Coordinator{
+		agents:      make(map[string]*agent),
+		thread:      thread.New(),
+		threadMode:  ThreadModeShared,
+		maxHandoffs: DefaultMaxHandoffs,
+	}
+}
+
+// WithAgent adds a named assistant. The description tells the other agents when to
+// hand off to it.
+func (c *Coordinator) WithAgent(name string, description string, assistant *Assistant) *Coordinator {
+	if _, ok := c.agents[name]; !ok {
+		c.names = append(c.names, name)
+	}
+
+	c.agents[name] = &agent{
+		name:        name,
+		description: description,
+		assistant:   assistant,
+	}
+
+	if c.active == "" {
+		c.active = name
+	}
+
+	return c
+}
+
+func (c *Coordinator) WithThread(thread *thread.Thread) *Coordinator {
+	c.thread = thread
+	return c
+}
+
+func (c *Coordinator) WithThreadMode(threadMode ThreadMode) *Coordinator {
+	c.threadMode = threadMode
+	return c
+}
+
+// WithMaxHandoffs sets the maximum number of conversation handoffs in a single run.
+func (c *Coordinator) WithMaxHandoffs(maxHandoffs uint) *Coordinator {
+	c.maxHandoffs = maxHandoffs
+	return c
+}
+
+// HandoffTools returns the tools the named agent can use to hand off to the other
+// agents. They must be added to the LLM of the agent.
+func (c *Coordinator) HandoffTools(from string) []tool.Tool {
+	var tools []tool.Tool
+	for _, name := range c.names {
+		if name == from {
+			continue
+		}
+
+		tools = append(tools, &handoffTool{coordinator: c, target: name})
+	}
+
+	return tools
+}
+
+// ActiveAgent returns the name of the agent holding the conversation.
+func (c *Coordinator) ActiveAgent() string {
+	return c.active
+}
+
+func (c *Coordinator) Thread() *thread.Thread {
+	return c.thread
+}
+
+// Run runs the active agent on the coordinator thread. In shared mode, when the agent
+// hands the conversation off, the target agent becomes the active one and continues
+// the run. If the agents keep handing off beyond the maximum number of handoffs, Run
+// fails with ErrMaxHandoffs, leaving the last target agent active.
+func (c *Coordinator) Run(ctx context.Context) error {
+	if c.thread == nil {
+		return nil
+	}
+
+	for handoffs := 0; ; handoffs++ {
+		activeAgent, ok := c.agents[c.active]
+		if !ok {
+			return fmt.Errorf("%w %s", ErrUnknownAgent, c.active)
+		}
+
+		h := &handoff{}
+		err := c.runAgent(context.WithValue(ctx, handoffContextKey{}, h), activeAgent)
+		if err != nil {
+			return err
+		}
+
+		if h.target == "" {
+			return nil
+		}
+
+		c.active = h.target
+
+		if handoffs == int(c.maxHandoffs) {
+			return fmt.Errorf("%w: %d", ErrMaxHandoffs, c.maxHandoffs)
+		}
+	}
+}
+
+func (c *Coordinator) RunWithThread(ctx context.Context, thread *thread.Thread) error {
+	c.thread = thread
+	return c.Run(ctx)
+}
+
+// runAgent runs the agent on a view of the coordinator thread that starts with the
+// system prompt of the agent. The system messages of the coordinator thread, e.g. the
+// instructions of the user or the summaries of a compactor, are kept. Only the messages
+// generated by the agent are then added to the coordinator thread, not its own system
+// prompts nor the user message rewritten by its RAG.
+func (c *Coordinator) runAgent(ctx context.Context, a *agent) error {
+	systemMessage, err := a.assistant.systemMessage()
+	if err != nil {
+		return err
+	}
+
+	view := thread.New().AddMessage(systemMessage).AddMessages(c.thread.Messages...)
+
+	generatedMessages, err := a.run(ctx, view)
+	if err != nil {
+		return err
+	}
+
+	c.thread.AddMessages(generatedMessages...)
+
+	return nil
+}
+
+// run runs the assistant of the agent on t and returns the messages it generated. The
+// thread of the assistant is left unchanged.
+func (a *agent) run(ctx context.Context, t *thread.Thread) ([]*thread.Message, error) {
+	previousThread := a.assistant.thread
+	defer func() {
+		a.assistant.thread = previousThread
+	}()
+
+	a.assistant.thread = t
+
+	return a.assistant.run(ctx)
+}
+
+// delegate runs the target agent on a new thread holding the task and returns its answer.
+func (c *Coordinator) delegate(ctx context.Context, target *agent, task string) (string, error) {
+	t := thread.New().AddMessage(
+		thread.NewUserMessage().AddContent(
+			thread.NewTextContent(task),
+		),
+	)
+
+	// the delegated agent must not end the run of the delegating one
+	ctx = context.WithValue(ctx, handoffContextKey{}, nil)
+
+	_, err := target.run(ctx, t)
+	if err != nil {
+		return "", err
+	}
+
+	var answer string
+	for _, content := range t.LastMessage().Contents {
+		if content.Type == thread.ContentTypeText {
+			answer += content.AsString()
+		}
+	}
+
+	return answer, nil
+}
+
+// handoffRequested reports whether the agent running in ctx has handed the
+// conversation off, in which case it must stop.
+func handoffRequested(ctx context.Context) bool {
+	h, ok := ctx.Value(handoffContextKey{}).(*handoff)
+	if !ok || h == nil {
+		return false
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.target != ""
+}
+
+type handoffTool struct {
+	coordinator *Coordinator
+	target      string
+}
+
+type HandoffInput struct {
+	Task string `json:"task" jsonschema:"description=the request of the user or the task to carry out, with all the details needed"`
+}
+
+type HandoffOutput struct {
+	Error  string `json:"error,omitempty"`
+	Result string `json:"result,omitempty"`
+}
+
+func (t *handoffTool) Name() string {
+	return handoffToolPrefix + t.target
+}
+
+func (t *handoffTool) Description() string {
+	description := t.coordinator.agents[t.target].description
+	if t.coordinator.threadMode == ThreadModeIsolated {
+		return "Delegate a task to the " + t.target + " agent and get its answer. The agent " + description
+	}
+
+	return "Hand the conversation off to the " + t.target + " agent. The agent " + description
+}
+
+func (t *handoffTool) Fn() any {
+	return t.fn
+}
+
+func (t *handoffTool) fn(ctx context.Context, i HandoffInput) HandoffOutput {
+	target, ok := t.coordinator.agents[t.target]
+	if !ok {
+		return HandoffOutput{Error: fmt.Sprintf("%s %s", ErrUnknownAgent, t.target)}
+	}
+
+	if t.coordinator.threadMode == ThreadModeIsolated {
+		answer, err := t.coordinator.delegate(ctx, target, i.Task)
+		if err != nil {
+			return HandoffOutput{Error: err.Error()}
+		}
+
+		return HandoffOutput{Result: answer}
+	}
+
+	h, ok := ctx.Value(handoffContextKey{}).(*handoff)
+	if !ok || h == nil {
+		return HandoffOutput{Error: "handoff is only available when running the coordinator"}
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.target != "" && h.target != t.target {
+		return HandoffOutput{Error: "the conversation has already been handed off to the " + h.target + " agent"}
+	}
+
+	h.target = t.target
+
+	return HandoffOutput{Result: "the conversation has been handed off to the " + t.target + " agent"}
+}
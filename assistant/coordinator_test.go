@@ -0,0 +1,199 @@
+package assistant
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/maksymenkoml/lingoose/thread"
+	"github.com/maksymenkoml/lingoose/tool"
+)
+
+// handoffLLM calls the handoff tool when the last message is from the user, otherwise
+// it answers with the name of the assistant found in its system prompt.
+type handoffLLM struct {
+	tools   *tool.Registry
+	handoff string
+	view    []*thread.Message
+}
+
+func (l *handoffLLM) Generate(ctx context.Context, t *thread.Thread) error {
+	l.view = append([]*thread.Message(nil), t.Messages...)
+
+	if l.handoff != "" && t.LastMessage().Role == thread.RoleUser {
+		toolCalls := []thread.ToolCallData{{ID: "1", Name: l.handoff, Arguments: `{"task":"refund my invoice"}`}}
+		t.AddMessage(thread.NewAssistantMessage().AddContent(thread.NewToolCallContent(toolCalls)))
+		t.AddMessages(l.tools.CallTools(ctx, toolCalls)...)
+		return nil
+	}
+
+	systemPrompt := t.Messages[0].Contents[0].AsString()
+	t.AddMessage(thread.NewAssistantMessage().AddContent(
+		thread.NewTextContent(systemPrompt[:strings.Index(systemPrompt, ",")]),
+	))
+
+	return nil
+}
+
+func newTestCoordinator(threadMode ThreadMode) *Coordinator {
+	triageLLM := &handoffLLM{tools: tool.NewRegistry(), handoff: "handoff_to_billing"}
+	billingLLM := &handoffLLM{tools: tool.NewRegistry()}
+
+	c := NewCoordinator().WithThreadMode(threadMode).WithAgent(
+		"triage",
+		"routes the user to the right agent",
+		New(triageLLM).WithParameters(Parameters{AssistantName: "Triage"}),
+	).WithAgent(
+		"billing",
+		"handles invoices and refunds",
+		New(billingLLM).WithParameters(Parameters{AssistantName: "Billing"}),
+	).WithThread(
+		thread.New().AddMessage(thread.NewUserMessage().AddContent(thread.NewTextContent("I want a refund"))),
+	)
+
+	_ = triageLLM.tools.AddTools(c.HandoffTools("triage")...)
+	_ = billingLLM.tools.AddTools(c.HandoffTools("billing")...)
+
+	return c
+}
+
+func agentLLM(c *Coordinator, name string) *handoffLLM {
+	return c.agents[name].assistant.llm.(*handoffLLM)
+}
+
+func TestCoordinator_RunShared(t *testing.T) {
+	c := newTestCoordinator(ThreadModeShared)
+
+	err := c.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Coordinator.Run() error = %v", err)
+	}
+
+	if c.ActiveAgent() != "billing" {
+		t.Errorf("Coordinator.ActiveAgent() = %s, want billing", c.ActiveAgent())
+	}
+
+	// user, handoff tool call, handoff tool response, billing answer
+	messages := c.Thread().Messages
+	if len(messages) != 4 {
+		t.Fatalf("Coordinator.Run() messages = %d, want 4", len(messages))
+	}
+
+	if got := messages[2].Contents[0].AsToolResponseData(); got.Name != "handoff_to_billing" {
+		t.Errorf("Coordinator.Run() handoff = %v, want handoff_to_billing", got)
+	}
+
+	if got := c.Thread().LastMessage().Contents[0].AsString(); !strings.Contains(got, "Billing") {
+		t.Errorf("Coordinator.Run() answer = %s, want Billing system prompt", got)
+	}
+}
+
+func TestCoordinator_RunIsolated(t *testing.T) {
+	c := newTestCoordinator(ThreadModeIsolated)
+
+	err := c.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Coordinator.Run() error = %v", err)
+	}
+
+	if c.ActiveAgent() != "triage" {
+		t.Errorf("Coordinator.ActiveAgent() = %s, want triage", c.ActiveAgent())
+	}
+
+	toolResponse := c.Thread().Messages[2].Contents[0].AsToolResponseData()
+	if !strings.Contains(toolResponse.Result, "Billing") {
+		t.Errorf("Coordinator.Run() tool result = %s, want billing answer", toolResponse.Result)
+	}
+
+	if got := c.Thread().LastMessage().Contents[0].AsString(); !strings.Contains(got, "Triage") {
+		t.Errorf("Coordinator.Run() answer = %s, want Triage system prompt", got)
+	}
+}
+
+func TestCoordinator_RunSystemMessages(t *testing.T) {
+	c := newTestCoordinator(ThreadModeShared)
+	instructions := thread.NewSystemMessage().AddContent(thread.NewTextContent("Answer in Italian."))
+	c.Thread().Messages = append([]*thread.Message{instructions}, c.Thread().Messages...)
+
+	err := c.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Coordinator.Run() error = %v", err)
+	}
+
+	// the agent sees its own prompt and the instructions of the user
+	view := agentLLM(c, "billing").view
+	if !strings.Contains(view[0].Contents[0].AsString(), "Billing") || view[1] != instructions {
+		t.Errorf("billing view = %v, want its system prompt and the instructions", view)
+	}
+
+	// the prompts of the agents are not added to the coordinator thread
+	for _, message := range c.Thread().Messages {
+		if message.Role == thread.RoleSystem && message != instructions {
+			t.Errorf("Coordinator.Run() added system message %v", message)
+		}
+	}
+}
+
+func TestCoordinator_RunMaxHandoffs(t *testing.T) {
+	c := newTestCoordinator(ThreadModeShared).WithMaxHandoffs(0)
+
+	err := c.Run(context.Background())
+	if !errors.Is(err, ErrMaxHandoffs) {
+		t.Fatalf("Coordinator.Run() error = %v, want %v", err, ErrMaxHandoffs)
+	}
+
+	if c.ActiveAgent() != "billing" || c.Thread().LastMessage().Role != thread.RoleTool {
+		t.Errorf("Coordinator.Run() active = %s, want billing with the handoff pending", c.ActiveAgent())
+	}
+
+	// a new run continues with the target agent
+	err = c.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Coordinator.Run() error = %v", err)
+	}
+
+	if got := c.Thread().LastMessage().Contents[0].AsString(); !strings.Contains(got, "Billing") {
+		t.Errorf("Coordinator.Run() answer = %s, want Billing system prompt", got)
+	}
+}
+
+type staticRAG struct{}
+
+func (staticRAG) Retrieve(context.Context, string) ([]string, error) {
+	return []string{"refunds take 5 days"}, nil
+}
+
+func TestCoordinator_RunRAG(t *testing.T) {
+	c := newTestCoordinator(ThreadModeShared)
+	triage := c.agents["triage"].assistant.WithRAG(staticRAG{})
+	triageThread := triage.Thread()
+	userMessage := c.Thread().Messages[0]
+
+	err := c.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Coordinator.Run() error = %v", err)
+	}
+
+	// the triage agent is asked the question rewritten with the RAG results
+	view := agentLLM(c, "triage").view
+	if got := view[len(view)-1].Contents[0].AsString(); !strings.Contains(got, "refunds take 5 days") {
+		t.Errorf("triage view = %v, want the RAG prompt", got)
+	}
+
+	// user, handoff tool call, handoff tool response, billing answer
+	messages := c.Thread().Messages
+	if len(messages) != 4 || messages[0] != userMessage {
+		t.Fatalf("Coordinator.Run() messages = %v, want the user message and 3 generated ones", messages)
+	}
+	for _, message := range messages[1:] {
+		if message.Role == thread.RoleUser || message.Role == thread.RoleSystem {
+			t.Errorf("Coordinator.Run() added %s message %v", message.Role, message)
+		}
+	}
+
+	// the agents keep their own thread
+	if triage.Thread() != triageThread || triageThread.CountMessages() != 0 {
+		t.Errorf("triage thread = %v, want its own empty thread", triage.Thread())
+	}
+}
@@ -1,5 +1,15 @@
 package assistant
 
+const (
+	// SystemPromptName is the name of the system prompt in the prompt registry. The
+	// template receives the Parameters as assistantName, assistantIdentity,
+	// assistantScope, companyName and companyDescription.
+	SystemPromptName = "assistant/system"
+	// RAGPromptName is the name of the RAG prompt in the prompt registry. The template
+	// receives the question and the retrieved results.
+	RAGPromptName = "assistant/rag"
+)
+
 const (
 	//nolint:lll
 	baseRAGPrompt = "Use the following pieces of retrieved context to answer the question.\n\nQuestion: {{.question}}\nContext:\n{{range .results}}{{.}}\n\n{{end}}"
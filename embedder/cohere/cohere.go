@@ -11,6 +11,9 @@ import (
 
 	"github.com/maksymenkoml/lingoose/embedder"
 	embobserver "github.com/maksymenkoml/lingoose/embedder/observer"
+	"github.com/maksymenkoml/lingoose/llm/apierror"
+	"github.com/maksymenkoml/lingoose/llm/retry"
+	"github.com/maksymenkoml/lingoose/usage"
 )
 
 type EmbedderModel = model.EmbedModel
@@ -40,6 +43,7 @@ var EmbedderModelsSize = map[EmbedderModel]int{
 type Embedder struct {
 	model  EmbedderModel
 	client *coherego.Client
+	retry  *retry.Policy
 	name   string
 }
 
@@ -47,6 +51,7 @@ func New() *Embedder {
 	return &Embedder{
 		client: coherego.New(os.Getenv("COHERE_API_KEY")),
 		model:  defaultEmbedderModel,
+		retry:  retry.New(),
 		name:   "cohere",
 	}
 }
@@ -63,7 +68,15 @@ func (e *Embedder) WithModel(model EmbedderModel) *Embedder {
 	return e
 }
 
-// Embed returns the embeddings for the given texts
+// WithRetry sets the policy retrying the requests failing with a transient error. A nil
+// policy disables the retries.
+func (e *Embedder) WithRetry(policy *retry.Policy) *Embedder {
+	e.retry = policy
+	return e
+}
+
+// Embed returns the embeddings for the given texts. The cohere-go client does not decode
+// the billed units, the usage recorded into the tracker of the context is estimated.
 func (e *Embedder) Embed(ctx context.Context, texts []string) ([]embedder.Embedding, error) {
 	observerEmbedding, err := embobserver.StartObserveEmbedding(
 		ctx,
@@ -95,18 +108,41 @@ func (e *Embedder) Embed(ctx context.Context, texts []string) ([]embedder.Embedd
 
 func (e *Embedder) embed(ctx context.Context, texts []string) ([]embedder.Embedding, error) {
 	resp := &response.Embed{}
-	err := e.client.Embed(
-		ctx,
-		&request.Embed{
-			Texts: texts,
-			Model: e.model,
-		},
-		resp,
-	)
+	err := e.retry.Do(ctx, func(ctx context.Context) error {
+		*resp = response.Embed{}
+		err := e.client.Embed(
+			ctx,
+			&request.Embed{
+				Texts: texts,
+				Model: e.model,
+			},
+			resp,
+		)
+		if err != nil {
+			return err
+		}
+
+		if !resp.IsSuccess() {
+			var body string
+			if resp.RawBody != nil {
+				body = *resp.RawBody
+			}
+			return apierror.New(resp.Code, []byte(body))
+		}
+
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
 
+	usage.Record(ctx, usage.Usage{
+		Provider:     e.name,
+		Model:        string(e.model),
+		PromptTokens: embedder.EstimateTokens(texts),
+		Estimated:    true,
+	})
+
 	embeddings := make([]embedder.Embedding, len(resp.Embeddings))
 
 	for i, embedding := range resp.Embeddings {
@@ -1,5 +1,9 @@
 package embedder
 
+const (
+	estimatedCharsPerToken = 4
+)
+
 var (
 	ErrCreateEmbedding = "unable to create embedding"
 )
@@ -15,3 +19,14 @@ func (e Embedding) ToFloat32() []float32 {
 
 	return vect
 }
+
+// EstimateTokens roughly estimates the number of tokens of the texts without a
+// tokenizer, for the embedding APIs that do not report them.
+func EstimateTokens(texts []string) int {
+	tokens := 0
+	for _, text := range texts {
+		tokens += (len(text) + estimatedCharsPerToken - 1) / estimatedCharsPerToken
+	}
+
+	return tokens
+}
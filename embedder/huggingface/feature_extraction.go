@@ -30,7 +30,11 @@ func (h *HuggingFaceEmbedder) featureExtraction(ctx context.Context, text []stri
 		return nil, err
 	}
 
-	respBody, err := h.doRequest(ctx, jsonBuf, h.model)
+	var respBody []byte
+	err = h.retry.Do(ctx, func(ctx context.Context) error {
+		respBody, err = h.doRequest(ctx, jsonBuf, h.model)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -7,6 +7,8 @@ import (
 	"errors"
 	"io"
 	"net/http"
+
+	"github.com/maksymenkoml/lingoose/llm/apierror"
 )
 
 const APIBaseURL = "https://api-inference.huggingface.co/pipeline/feature-extraction/"
@@ -33,6 +35,10 @@ func (h *HuggingFaceEmbedder) doRequest(ctx context.Context, jsonBody []byte, mo
 		return nil, err
 	}
 
+	if resp.StatusCode >= http.StatusBadRequest {
+		return nil, apierror.New(resp.StatusCode, respBody).WithRetryAfter(resp.Header.Get("Retry-After"))
+	}
+
 	err = checkRespForError(respBody)
 	if err != nil {
 		return nil, err
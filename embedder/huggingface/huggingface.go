@@ -7,6 +7,8 @@ import (
 
 	"github.com/maksymenkoml/lingoose/embedder"
 	embobserver "github.com/maksymenkoml/lingoose/embedder/observer"
+	"github.com/maksymenkoml/lingoose/llm/retry"
+	"github.com/maksymenkoml/lingoose/usage"
 )
 
 const (
@@ -17,6 +19,7 @@ type HuggingFaceEmbedder struct {
 	token      string
 	model      string
 	httpClient *http.Client
+	retry      *retry.Policy
 	name       string
 }
 
@@ -25,6 +28,7 @@ func New() *HuggingFaceEmbedder {
 		token:      os.Getenv("HUGGING_FACE_HUB_TOKEN"),
 		model:      hfDefaultEmbedderModel,
 		httpClient: http.DefaultClient,
+		retry:      retry.New(),
 		name:       "huggingface",
 	}
 }
@@ -47,7 +51,15 @@ func (h *HuggingFaceEmbedder) WithHTTPClient(httpClient *http.Client) *HuggingFa
 	return h
 }
 
-// Embed returns the embeddings for the given texts
+// WithRetry sets the policy retrying the requests failing with a transient error. A nil
+// policy disables the retries.
+func (h *HuggingFaceEmbedder) WithRetry(policy *retry.Policy) *HuggingFaceEmbedder {
+	h.retry = policy
+	return h
+}
+
+// Embed returns the embeddings for the given texts. The inference API does not report
+// the tokens, the usage recorded into the tracker of the context is estimated.
 func (h *HuggingFaceEmbedder) Embed(ctx context.Context, texts []string) ([]embedder.Embedding, error) {
 	observerEmbedding, err := embobserver.StartObserveEmbedding(
 		ctx,
@@ -65,6 +77,13 @@ func (h *HuggingFaceEmbedder) Embed(ctx context.Context, texts []string) ([]embe
 		return nil, err
 	}
 
+	usage.Record(ctx, usage.Usage{
+		Provider:     h.name,
+		Model:        h.model,
+		PromptTokens: embedder.EstimateTokens(texts),
+		Estimated:    true,
+	})
+
 	err = embobserver.StopObserveEmbedding(
 		ctx,
 		observerEmbedding,
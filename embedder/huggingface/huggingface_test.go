@@ -0,0 +1,67 @@
+package huggingfaceembedder
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/maksymenkoml/lingoose/embedder"
+	"github.com/maksymenkoml/lingoose/usage"
+)
+
+// serverTransport sends the requests to the test server.
+type serverTransport struct {
+	url *url.URL
+}
+
+func (s serverTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req.URL.Scheme, req.URL.Host = s.url.Scheme, s.url.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestHuggingFaceEmbedder_Embed(t *testing.T) {
+	var path string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+
+		var req featureExtractionRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+
+		embeddings := make([]embedder.Embedding, len(req.Inputs))
+		for i, input := range req.Inputs {
+			embeddings[i] = embedder.Embedding{float64(len(input))}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(embeddings)
+	}))
+	defer server.Close()
+
+	serverURL, _ := url.Parse(server.URL)
+	h := New().WithHTTPClient(&http.Client{Transport: serverTransport{url: serverURL}})
+
+	tracker := usage.NewTracker()
+	ctx := usage.ContextWithTracker(context.Background(), tracker)
+
+	embeddings, err := h.Embed(ctx, []string{"hello", "hi"})
+	if err != nil {
+		t.Fatalf("Embed() error = %v", err)
+	}
+
+	if want := "/pipeline/feature-extraction/" + hfDefaultEmbedderModel; path != want {
+		t.Errorf("request path = %s, want %s", path, want)
+	}
+	if want := []embedder.Embedding{{5}, {2}}; !reflect.DeepEqual(embeddings, want) {
+		t.Errorf("Embed() = %v, want %v", embeddings, want)
+	}
+
+	// the API does not report the tokens
+	want := usage.Usage{Provider: "huggingface", Model: hfDefaultEmbedderModel, PromptTokens: 3, Estimated: true}
+	if got := tracker.Total(); got != want {
+		t.Errorf("recorded usage = %+v, want %+v", got, want)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"io"
+	"net/http"
 
 	"github.com/henomis/restclientgo"
 )
@@ -52,6 +53,7 @@ type response struct {
 	Embeddings     []embedder.Embedding `json:"embeddings"`
 	Usage          Usage                `json:"usage"`
 	RawBody        string               `json:"-"`
+	retryAfter     string
 }
 
 type Usage struct {
@@ -81,4 +83,7 @@ func (r *response) SetStatusCode(code int) error {
 	return nil
 }
 
-func (r *response) SetHeaders(_ restclientgo.Headers) error { return nil }
+func (r *response) SetHeaders(headers restclientgo.Headers) error {
+	r.retryAfter = http.Header(headers).Get(retryAfterHeader)
+	return nil
+}
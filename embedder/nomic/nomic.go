@@ -9,17 +9,22 @@ import (
 
 	"github.com/maksymenkoml/lingoose/embedder"
 	embobserver "github.com/maksymenkoml/lingoose/embedder/observer"
+	"github.com/maksymenkoml/lingoose/llm/apierror"
+	"github.com/maksymenkoml/lingoose/llm/retry"
+	"github.com/maksymenkoml/lingoose/usage"
 )
 
 const (
-	defaultEndpoint = "https://api-atlas.nomic.ai/v1"
-	defaultModel    = ModelNomicEmbedTextV1
+	defaultEndpoint  = "https://api-atlas.nomic.ai/v1"
+	defaultModel     = ModelNomicEmbedTextV1
+	retryAfterHeader = "Retry-After"
 )
 
 type Embedder struct {
 	taskType   TaskType
 	model      Model
 	restClient *restclientgo.RestClient
+	retry      *retry.Policy
 	name       string
 }
 
@@ -34,6 +39,7 @@ func New() *Embedder {
 			},
 		),
 		model: defaultModel,
+		retry: retry.New(),
 		name:  "nomic",
 	}
 }
@@ -58,6 +64,13 @@ func (e *Embedder) WithModel(model Model) *Embedder {
 	return e
 }
 
+// WithRetry sets the policy retrying the requests failing with a transient error. A nil
+// policy disables the retries.
+func (e *Embedder) WithRetry(policy *retry.Policy) *Embedder {
+	e.retry = policy
+	return e
+}
+
 // Embed returns the embeddings for the given texts
 func (e *Embedder) Embed(ctx context.Context, texts []string) ([]embedder.Embedding, error) {
 	observerEmbedding, err := embobserver.StartObserveEmbedding(
@@ -72,19 +85,37 @@ func (e *Embedder) Embed(ctx context.Context, texts []string) ([]embedder.Embedd
 	}
 
 	var resp response
-	err = e.restClient.Post(
-		ctx,
-		&request{
-			Texts:    texts,
-			Model:    string(e.model),
-			TaskType: e.taskType,
-		},
-		&resp,
-	)
+	err = e.retry.Do(ctx, func(ctx context.Context) error {
+		resp = response{}
+		err := e.restClient.Post(
+			ctx,
+			&request{
+				Texts:    texts,
+				Model:    string(e.model),
+				TaskType: e.taskType,
+			},
+			&resp,
+		)
+		if err != nil {
+			return err
+		}
+
+		if resp.HTTPStatusCode >= http.StatusBadRequest {
+			return apierror.New(resp.HTTPStatusCode, []byte(resp.RawBody)).WithRetryAfter(resp.retryAfter)
+		}
+
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
 
+	usage.Record(ctx, usage.Usage{
+		Provider:     e.name,
+		Model:        string(e.model),
+		PromptTokens: resp.Usage.TotalTokens,
+	})
+
 	err = embobserver.StopObserveEmbedding(
 		ctx,
 		observerEmbedding,
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"io"
+	"net/http"
 
 	"github.com/henomis/restclientgo"
 )
@@ -34,6 +35,7 @@ func (r *request) ContentType() string {
 type response struct {
 	HTTPStatusCode    int       `json:"-"`
 	acceptContentType string    `json:"-"`
+	retryAfter        string    `json:"-"`
 	RawBody           []byte    `json:"-"`
 	Embedding         []float64 `json:"embedding"`
 	CreatedAt         string    `json:"created_at"`
@@ -69,7 +71,10 @@ func (r *response) SetStatusCode(code int) error {
 	return nil
 }
 
-func (r *response) SetHeaders(_ restclientgo.Headers) error { return nil }
+func (r *response) SetHeaders(headers restclientgo.Headers) error {
+	r.retryAfter = http.Header(headers).Get(retryAfterHeader)
+	return nil
+}
 
 type options struct {
 	Temperature float64 `json:"temperature"`
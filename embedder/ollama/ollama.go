@@ -2,7 +2,6 @@ package ollamaembedder
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"net/http"
 
@@ -10,11 +9,15 @@ import (
 
 	"github.com/maksymenkoml/lingoose/embedder"
 	embobserver "github.com/maksymenkoml/lingoose/embedder/observer"
+	"github.com/maksymenkoml/lingoose/llm/apierror"
+	"github.com/maksymenkoml/lingoose/llm/retry"
+	"github.com/maksymenkoml/lingoose/usage"
 )
 
 const (
-	defaultModel    = "llama2"
-	defaultEndpoint = "http://localhost:11434/api"
+	defaultModel     = "llama2"
+	defaultEndpoint  = "http://localhost:11434/api"
+	retryAfterHeader = "Retry-After"
 )
 
 type OllamaEmbedError struct {
@@ -25,9 +28,14 @@ func (e *OllamaEmbedError) Error() string {
 	return fmt.Sprintf("Error embedding text: %v", e.Err)
 }
 
+func (e *OllamaEmbedError) Unwrap() error {
+	return e.Err
+}
+
 type Embedder struct {
 	model      string
 	restClient *restclientgo.RestClient
+	retry      *retry.Policy
 	name       string
 }
 
@@ -35,6 +43,7 @@ func New() *Embedder {
 	return &Embedder{
 		restClient: restclientgo.New(defaultEndpoint),
 		model:      defaultModel,
+		retry:      retry.New(),
 		name:       "ollama",
 	}
 }
@@ -49,7 +58,15 @@ func (e *Embedder) WithModel(model string) *Embedder {
 	return e
 }
 
-// Embed returns the embeddings for the given texts
+// WithRetry sets the policy retrying the requests failing with a transient error. A nil
+// policy disables the retries.
+func (e *Embedder) WithRetry(policy *retry.Policy) *Embedder {
+	e.retry = policy
+	return e
+}
+
+// Embed returns the embeddings for the given texts. The embeddings API does not report
+// the tokens, the usage recorded into the tracker of the context is estimated.
 func (e *Embedder) Embed(ctx context.Context, texts []string) ([]embedder.Embedding, error) {
 	observerEmbedding, err := embobserver.StartObserveEmbedding(
 		ctx,
@@ -72,6 +89,13 @@ func (e *Embedder) Embed(ctx context.Context, texts []string) ([]embedder.Embedd
 		embeddings[i] = embedding
 	}
 
+	usage.Record(ctx, usage.Usage{
+		Provider:     e.name,
+		Model:        e.model,
+		PromptTokens: embedder.EstimateTokens(texts),
+		Estimated:    true,
+	})
+
 	err = embobserver.StopObserveEmbedding(
 		ctx,
 		observerEmbedding,
@@ -87,22 +111,30 @@ func (e *Embedder) Embed(ctx context.Context, texts []string) ([]embedder.Embedd
 // Embed returns the embeddings for the given texts
 func (e *Embedder) embed(ctx context.Context, text string) (embedder.Embedding, error) {
 	resp := &response{}
-	err := e.restClient.Post(
-		ctx,
-		&request{
-			Prompt: text,
-			Model:  e.model,
-		},
-		resp,
-	)
-	if err != nil {
-		return nil, err
-	}
+	err := e.retry.Do(ctx, func(ctx context.Context) error {
+		*resp = response{}
+		err := e.restClient.Post(
+			ctx,
+			&request{
+				Prompt: text,
+				Model:  e.model,
+			},
+			resp,
+		)
+		if err != nil {
+			return err
+		}
 
-	if resp.HTTPStatusCode >= http.StatusBadRequest {
-		return nil, &OllamaEmbedError{
-			Err: errors.New(string(resp.RawBody)),
+		if resp.HTTPStatusCode >= http.StatusBadRequest {
+			return &OllamaEmbedError{
+				Err: apierror.New(resp.HTTPStatusCode, resp.RawBody).WithRetryAfter(resp.retryAfter),
+			}
 		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return resp.Embedding, nil
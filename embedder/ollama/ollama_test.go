@@ -0,0 +1,42 @@
+package ollamaembedder
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/maksymenkoml/lingoose/embedder"
+	"github.com/maksymenkoml/lingoose/usage"
+)
+
+func TestEmbedder_Embed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req request
+		_ = json.NewDecoder(r.Body).Decode(&req)
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(response{Embedding: []float64{float64(len(req.Prompt))}})
+	}))
+	defer server.Close()
+
+	tracker := usage.NewTracker()
+	ctx := usage.ContextWithTracker(context.Background(), tracker)
+
+	embeddings, err := New().WithEndpoint(server.URL).WithModel("nomic-embed-text").Embed(ctx, []string{"hello", "hi"})
+	if err != nil {
+		t.Fatalf("Embed() error = %v", err)
+	}
+
+	if want := []embedder.Embedding{{5}, {2}}; !reflect.DeepEqual(embeddings, want) {
+		t.Errorf("Embed() = %v, want %v", embeddings, want)
+	}
+
+	// the API does not report the tokens
+	want := usage.Usage{Provider: "ollama", Model: "nomic-embed-text", PromptTokens: 3, Estimated: true}
+	if got := tracker.Total(); got != want {
+		t.Errorf("recorded usage = %+v, want %+v", got, want)
+	}
+}
@@ -8,6 +8,8 @@ import (
 
 	"github.com/maksymenkoml/lingoose/embedder"
 	embobserver "github.com/maksymenkoml/lingoose/embedder/observer"
+	"github.com/maksymenkoml/lingoose/llm/retry"
+	"github.com/maksymenkoml/lingoose/usage"
 )
 
 type Model = openai.EmbeddingModel
@@ -21,6 +23,7 @@ const (
 type OpenAIEmbedder struct {
 	openAIClient *openai.Client
 	model        Model
+	retry        *retry.Policy
 	Name         string
 }
 
@@ -30,6 +33,7 @@ func New(model Model) *OpenAIEmbedder {
 	return &OpenAIEmbedder{
 		openAIClient: openai.NewClient(openAIKey),
 		model:        model,
+		retry:        retry.New(),
 		Name:         "openai",
 	}
 }
@@ -40,6 +44,13 @@ func (o *OpenAIEmbedder) WithClient(client *openai.Client) *OpenAIEmbedder {
 	return o
 }
 
+// WithRetry sets the policy retrying the requests failing with a transient error. A nil
+// policy disables the retries.
+func (o *OpenAIEmbedder) WithRetry(policy *retry.Policy) *OpenAIEmbedder {
+	o.retry = policy
+	return o
+}
+
 // Embed returns the embeddings for the given texts
 func (o *OpenAIEmbedder) Embed(ctx context.Context, texts []string) ([]embedder.Embedding, error) {
 	observerEmbedding, err := embobserver.StartObserveEmbedding(
@@ -71,17 +82,28 @@ func (o *OpenAIEmbedder) Embed(ctx context.Context, texts []string) ([]embedder.
 }
 
 func (o *OpenAIEmbedder) openAICreateEmebeddings(ctx context.Context, texts []string) ([]embedder.Embedding, error) {
-	resp, err := o.openAIClient.CreateEmbeddings(
-		ctx,
-		openai.EmbeddingRequest{
-			Input: texts,
-			Model: o.model,
-		},
-	)
+	var resp openai.EmbeddingResponse
+	err := o.retry.Do(ctx, func(ctx context.Context) error {
+		var err error
+		resp, err = o.openAIClient.CreateEmbeddings(
+			ctx,
+			openai.EmbeddingRequest{
+				Input: texts,
+				Model: o.model,
+			},
+		)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
 
+	usage.Record(ctx, usage.Usage{
+		Provider:     o.Name,
+		Model:        string(o.model),
+		PromptTokens: resp.Usage.PromptTokens,
+	})
+
 	var embeddings []embedder.Embedding
 
 	for _, obj := range resp.Data {
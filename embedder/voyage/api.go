@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"io"
+	"net/http"
 
 	"github.com/henomis/restclientgo"
 )
@@ -31,12 +32,18 @@ func (r *request) ContentType() string {
 }
 
 type response struct {
-	HTTPStatusCode    int    `json:"-"`
-	acceptContentType string `json:"-"`
-	Object            string `json:"object"`
-	Data              []data `json:"data"`
-	Model             string `json:"model"`
-	RawBody           []byte `json:"-"`
+	HTTPStatusCode    int        `json:"-"`
+	acceptContentType string     `json:"-"`
+	retryAfter        string     `json:"-"`
+	Object            string     `json:"object"`
+	Data              []data     `json:"data"`
+	Model             string     `json:"model"`
+	Usage             tokenUsage `json:"usage"`
+	RawBody           []byte     `json:"-"`
+}
+
+type tokenUsage struct {
+	TotalTokens int `json:"total_tokens"`
 }
 
 type data struct {
@@ -75,4 +82,7 @@ func (r *response) SetStatusCode(code int) error {
 	return nil
 }
 
-func (r *response) SetHeaders(_ restclientgo.Headers) error { return nil }
+func (r *response) SetHeaders(headers restclientgo.Headers) error {
+	r.retryAfter = http.Header(headers).Get(retryAfterHeader)
+	return nil
+}
@@ -9,16 +9,21 @@ import (
 
 	"github.com/maksymenkoml/lingoose/embedder"
 	embobserver "github.com/maksymenkoml/lingoose/embedder/observer"
+	"github.com/maksymenkoml/lingoose/llm/apierror"
+	"github.com/maksymenkoml/lingoose/llm/retry"
+	"github.com/maksymenkoml/lingoose/usage"
 )
 
 const (
-	defaultModel    = "voyage-2"
-	defaultEndpoint = "https://api.voyageai.com/v1"
+	defaultModel     = "voyage-2"
+	defaultEndpoint  = "https://api.voyageai.com/v1"
+	retryAfterHeader = "Retry-After"
 )
 
 type Embedder struct {
 	model      string
 	restClient *restclientgo.RestClient
+	retry      *retry.Policy
 	name       string
 }
 
@@ -32,6 +37,7 @@ func New() *Embedder {
 				return req
 			}),
 		model: defaultModel,
+		retry: retry.New(),
 		name:  "voyage",
 	}
 }
@@ -41,6 +47,13 @@ func (e *Embedder) WithModel(model string) *Embedder {
 	return e
 }
 
+// WithRetry sets the policy retrying the requests failing with a transient error. A nil
+// policy disables the retries.
+func (e *Embedder) WithRetry(policy *retry.Policy) *Embedder {
+	e.retry = policy
+	return e
+}
+
 // Embed returns the embeddings for the given texts
 func (e *Embedder) Embed(ctx context.Context, texts []string) ([]embedder.Embedding, error) {
 	observerEmbedding, err := embobserver.StartObserveEmbedding(
@@ -74,18 +87,36 @@ func (e *Embedder) Embed(ctx context.Context, texts []string) ([]embedder.Embedd
 // Embed returns the embeddings for the given texts
 func (e *Embedder) embed(ctx context.Context, text []string) ([]embedder.Embedding, error) {
 	resp := &response{}
-	err := e.restClient.Post(
-		ctx,
-		&request{
-			Input: text,
-			Model: e.model,
-		},
-		resp,
-	)
+	err := e.retry.Do(ctx, func(ctx context.Context) error {
+		*resp = response{}
+		err := e.restClient.Post(
+			ctx,
+			&request{
+				Input: text,
+				Model: e.model,
+			},
+			resp,
+		)
+		if err != nil {
+			return err
+		}
+
+		if resp.HTTPStatusCode >= http.StatusBadRequest {
+			return apierror.New(resp.HTTPStatusCode, resp.RawBody).WithRetryAfter(resp.retryAfter)
+		}
+
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
 
+	usage.Record(ctx, usage.Usage{
+		Provider:     e.name,
+		Model:        e.model,
+		PromptTokens: resp.Usage.TotalTokens,
+	})
+
 	embeddings := make([]embedder.Embedding, len(resp.Data))
 	for i, data := range resp.Data {
 		embeddings[i] = data.Embedding
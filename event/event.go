@@ -0,0 +1,109 @@
+// Package event provides the typed events emitted while an assistant runs, e.g. text
+// deltas, tool calls and tool results, and the context plumbing used to deliver them.
+package event
+
+import (
+	"context"
+
+	"github.com/maksymenkoml/lingoose/thread"
+)
+
+type Type string
+
+const (
+	// TypeTextDelta is emitted for each chunk of text streamed by the model.
+	TypeTextDelta Type = "text_delta"
+	// TypeToolCall is emitted before a tool call is executed.
+	TypeToolCall Type = "tool_call"
+	// TypeToolResult is emitted after a tool call has been executed.
+	TypeToolResult Type = "tool_result"
+	// TypeIteration is emitted at the beginning of each assistant iteration.
+	TypeIteration Type = "iteration"
+	// TypeRetrieval is emitted with the results retrieved by the RAG.
+	TypeRetrieval Type = "retrieval"
+	// TypeUsage is emitted with the tokens used by a generation.
+	TypeUsage Type = "usage"
+	// TypeFinalMessage is emitted with the last message of the thread at the end of a run.
+	TypeFinalMessage Type = "final_message"
+	// TypeError is emitted when a run fails. It is always the last event of the run.
+	TypeError Type = "error"
+)
+
+type contextKey string
+
+const (
+	contextKeyEmitter contextKey = "event-emitter"
+)
+
+type Usage struct {
+	PromptTokens     int
+	CompletionTokens int
+}
+
+// Event is a typed event. Only the fields related to its Type are set.
+type Event struct {
+	Type         Type
+	Iteration    int
+	Text         string
+	ToolCall     *thread.ToolCallData
+	ToolResponse *thread.ToolResponseData
+	Query        string
+	Results      []string
+	Usage        *Usage
+	Message      *thread.Message
+	Err          error
+}
+
+// Emitter receives the events emitted during a run.
+type Emitter func(Event)
+
+func NewTextDelta(text string) Event {
+	return Event{Type: TypeTextDelta, Text: text}
+}
+
+func NewToolCall(toolCall thread.ToolCallData) Event {
+	return Event{Type: TypeToolCall, ToolCall: &toolCall}
+}
+
+func NewToolResult(toolResponse thread.ToolResponseData) Event {
+	return Event{Type: TypeToolResult, ToolResponse: &toolResponse}
+}
+
+func NewIteration(iteration int) Event {
+	return Event{Type: TypeIteration, Iteration: iteration}
+}
+
+func NewRetrieval(query string, results []string) Event {
+	return Event{Type: TypeRetrieval, Query: query, Results: results}
+}
+
+func NewUsage(usage Usage) Event {
+	return Event{Type: TypeUsage, Usage: &usage}
+}
+
+func NewFinalMessage(message *thread.Message) Event {
+	return Event{Type: TypeFinalMessage, Message: message}
+}
+
+func NewError(err error) Event {
+	return Event{Type: TypeError, Err: err}
+}
+
+func ContextWithEmitter(ctx context.Context, emitter Emitter) context.Context {
+	return context.WithValue(ctx, contextKeyEmitter, emitter)
+}
+
+func ContextValueEmitter(ctx context.Context) Emitter {
+	emitter, ok := ctx.Value(contextKeyEmitter).(Emitter)
+	if !ok {
+		return nil
+	}
+	return emitter
+}
+
+// Emit delivers the event to the emitter of the context, if any.
+func Emit(ctx context.Context, e Event) {
+	if emitter := ContextValueEmitter(ctx); emitter != nil {
+		emitter(e)
+	}
+}
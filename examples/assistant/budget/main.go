@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/maksymenkoml/lingoose/assistant"
+	"github.com/maksymenkoml/lingoose/llm/openai"
+	"github.com/maksymenkoml/lingoose/observer"
+	"github.com/maksymenkoml/lingoose/thread"
+	"github.com/maksymenkoml/lingoose/usage"
+)
+
+func main() {
+	// the tracker of the context aggregates the usage of every run, per trace too
+	tracker := usage.NewTracker().WithBudget(usage.Budget{MaxCost: 1})
+	ctx := usage.ContextWithTracker(context.Background(), tracker)
+	ctx = observer.ContextWithTraceID(ctx, "budget-example")
+
+	myAssistant := assistant.New(
+		openai.New().WithModel(openai.GPT4o),
+	).WithBudget(
+		usage.Budget{MaxTokens: 10000},
+	).WithThread(
+		thread.New().AddMessages(
+			thread.NewUserMessage().AddContent(
+				thread.NewTextContent("What is a goroutine?"),
+			),
+		),
+	)
+
+	err := myAssistant.Run(ctx)
+	if errors.Is(err, usage.ErrBudgetExceeded) {
+		fmt.Println("run aborted:", err)
+	} else if err != nil {
+		panic(err)
+	}
+
+	fmt.Println(myAssistant.Thread())
+
+	runUsage := myAssistant.Usage()
+	fmt.Printf("run: %d tokens, %.6f USD\n", runUsage.TotalTokens(), runUsage.Cost)
+
+	traceUsage := tracker.Trace("budget-example")
+	fmt.Printf("trace: %d tokens, %.6f USD\n", traceUsage.TotalTokens(), traceUsage.Cost)
+}
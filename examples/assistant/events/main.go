@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/maksymenkoml/lingoose/assistant"
+	"github.com/maksymenkoml/lingoose/event"
+	"github.com/maksymenkoml/lingoose/llm/openai"
+	"github.com/maksymenkoml/lingoose/thread"
+	"github.com/maksymenkoml/lingoose/tool/python"
+)
+
+func main() {
+	myAssistant := assistant.New(
+		openai.New().WithModel(openai.GPT4o).WithStream(true, func(string) {}).WithTools(
+			python.New(),
+		),
+	).WithThread(
+		thread.New().AddMessages(
+			thread.NewUserMessage().AddContent(
+				thread.NewTextContent("calculate reverse string of 'ailatiditalia', don't try to guess, let's use appropriate tool"),
+			),
+		),
+	)
+
+	for e := range myAssistant.RunStream(context.Background()) {
+		switch e.Type {
+		case event.TypeIteration:
+			fmt.Printf("\n--- iteration %d ---\n", e.Iteration)
+		case event.TypeTextDelta:
+			fmt.Print(e.Text)
+		case event.TypeToolCall:
+			fmt.Printf("calling %s(%s)\n", e.ToolCall.Name, e.ToolCall.Arguments)
+		case event.TypeToolResult:
+			fmt.Printf("%s returned %s\n", e.ToolResponse.Name, e.ToolResponse.Result)
+		case event.TypeUsage:
+			fmt.Printf("\nusage: %d prompt tokens, %d completion tokens\n", e.Usage.PromptTokens, e.Usage.CompletionTokens)
+		case event.TypeFinalMessage:
+			fmt.Println("\nfinal message:", e.Message)
+		case event.TypeError:
+			panic(e.Err)
+		default:
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/maksymenkoml/lingoose/assistant"
+	"github.com/maksymenkoml/lingoose/llm/openai"
+	"github.com/maksymenkoml/lingoose/thread"
+)
+
+func main() {
+	triageLLM := openai.New().WithModel(openai.GPT4o)
+	billingLLM := openai.New().WithModel(openai.GPT4o)
+	technicalLLM := openai.New().WithModel(openai.GPT4o)
+
+	coordinator := assistant.NewCoordinator().WithAgent(
+		"triage",
+		"understands the user request and routes it to the right agent",
+		assistant.New(triageLLM).WithParameters(assistant.Parameters{
+			AssistantName:     "Triage",
+			AssistantIdentity: "a customer support agent that routes the users to the right team",
+			AssistantScope:    "by handing off the conversation to the right agent",
+		}),
+	).WithAgent(
+		"billing",
+		"answers questions about invoices, payments and refunds",
+		assistant.New(billingLLM).WithParameters(assistant.Parameters{
+			AssistantName:     "Billing",
+			AssistantIdentity: "a billing specialist",
+			AssistantScope:    "with invoices, payments and refunds",
+		}),
+	).WithAgent(
+		"technical",
+		"solves technical issues with the product",
+		assistant.New(technicalLLM).WithParameters(assistant.Parameters{
+			AssistantName:     "Technical",
+			AssistantIdentity: "a technical support engineer",
+			AssistantScope:    "with technical issues",
+		}),
+	).WithThread(
+		thread.New().AddMessages(
+			thread.NewUserMessage().AddContent(
+				thread.NewTextContent("I've been charged twice for my last invoice, can I get a refund?"),
+			),
+		),
+	)
+
+	triageLLM.WithTools(coordinator.HandoffTools("triage")...)
+	billingLLM.WithTools(coordinator.HandoffTools("billing")...)
+	technicalLLM.WithTools(coordinator.HandoffTools("technical")...)
+
+	err := coordinator.Run(context.Background())
+	if err != nil {
+		panic(err)
+	}
+
+	fmt.Println("active agent:", coordinator.ActiveAgent())
+	fmt.Println(coordinator.Thread())
+}
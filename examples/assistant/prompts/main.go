@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+
+	"github.com/maksymenkoml/lingoose/assistant"
+	"github.com/maksymenkoml/lingoose/llm/openai"
+	"github.com/maksymenkoml/lingoose/prompt"
+	"github.com/maksymenkoml/lingoose/thread"
+)
+
+//go:embed prompts
+var promptFiles embed.FS
+
+func main() {
+	promptsFS, err := fs.Sub(promptFiles, "prompts")
+	if err != nil {
+		panic(err)
+	}
+
+	prompts := prompt.NewRegistry()
+	err = prompts.LoadFS(promptsFS)
+	if err != nil {
+		panic(err)
+	}
+
+	fmt.Println("available system prompt versions:", prompts.Versions(assistant.SystemPromptName))
+
+	myAssistant := assistant.New(
+		openai.New().WithModel(openai.GPT4o),
+	).WithParameters(
+		assistant.Parameters{
+			AssistantName:     "Gopher",
+			AssistantIdentity: "a Go programming expert",
+			AssistantScope:    "with their Go questions",
+			CompanyName:       "Go Inc.",
+		},
+	).WithPrompts(prompts).WithThread(
+		thread.New().AddMessages(
+			thread.NewUserMessage().AddContent(
+				thread.NewTextContent("What is a goroutine?"),
+			),
+		),
+	)
+
+	err = myAssistant.Run(context.Background())
+	if err != nil {
+		panic(err)
+	}
+
+	fmt.Println(myAssistant.Thread())
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/maksymenkoml/lingoose/assistant"
+	"github.com/maksymenkoml/lingoose/llm/openai"
+	"github.com/maksymenkoml/lingoose/thread"
+	"github.com/maksymenkoml/lingoose/thread/store/filesystem"
+)
+
+func main() {
+	ctx := context.Background()
+
+	sessionID := "user-42"
+	if len(os.Args) > 1 {
+		sessionID = os.Args[1]
+	}
+
+	myAssistant := assistant.New(
+		openai.New().WithModel(openai.GPT4o),
+	).WithStore(
+		filesystem.New("./sessions"),
+	)
+
+	err := myAssistant.RunWithSession(
+		ctx,
+		sessionID,
+		thread.NewUserMessage().AddContent(
+			thread.NewTextContent("Hi, what did I ask you last time?"),
+		),
+	)
+	if err != nil {
+		panic(err)
+	}
+
+	fmt.Println(myAssistant.Thread())
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/maksymenkoml/lingoose/llm/anthropic"
+	"github.com/maksymenkoml/lingoose/thread"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Println("usage: document <pdf path>")
+		os.Exit(1)
+	}
+
+	fileContent, err := thread.NewFileContentFromFile(os.Args[1])
+	if err != nil {
+		panic(err)
+	}
+
+	t := thread.New().AddMessage(
+		thread.NewUserMessage().AddContent(
+			fileContent,
+		).AddContent(
+			thread.NewTextContent("Can you summarize the document?"),
+		),
+	)
+
+	err = anthropic.New().Generate(context.Background(), t)
+	if err != nil {
+		panic(err)
+	}
+
+	fmt.Println(t)
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/maksymenkoml/lingoose/llm/anthropic"
+	"github.com/maksymenkoml/lingoose/thread"
+	"github.com/maksymenkoml/lingoose/tool/python"
+)
+
+func main() {
+	newStr := func(str string) *string {
+		return &str
+	}
+	llm := anthropic.New().WithModel("claude-3-5-sonnet-latest").WithToolChoice(newStr("auto")).WithTools(
+		python.New(),
+	)
+
+	t := thread.New().AddMessage(
+		thread.NewUserMessage().AddContent(
+			thread.NewTextContent("calculate reverse string of 'ailatiditalia', don't try to guess, let's use appropriate tool"),
+		),
+	)
+
+	err := llm.Generate(context.Background(), t)
+	if err != nil {
+		panic(err)
+	}
+
+	if t.LastMessage().Role == thread.RoleTool {
+		err = llm.Generate(context.Background(), t)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	fmt.Println(t)
+}
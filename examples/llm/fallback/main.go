@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/maksymenkoml/lingoose/llm/anthropic"
+	"github.com/maksymenkoml/lingoose/llm/fallback"
+	"github.com/maksymenkoml/lingoose/llm/ollama"
+	"github.com/maksymenkoml/lingoose/llm/openai"
+	"github.com/maksymenkoml/lingoose/thread"
+)
+
+func main() {
+	llm := fallback.New(
+		openai.New().WithModel(openai.GPT4o),
+		anthropic.New(),
+		ollama.New().WithModel("llama3.1"),
+	).WithRoute(
+		fallback.HasImages(),
+		openai.New().WithModel(openai.GPT4o),
+		ollama.New().WithModel("llava"),
+	).WithTimeout(30 * time.Second)
+
+	t := thread.New().AddMessage(
+		thread.NewUserMessage().AddContent(
+			thread.NewTextContent("Hello, who are you?"),
+		),
+	)
+
+	err := llm.Generate(context.Background(), t)
+	if err != nil {
+		panic(err)
+	}
+
+	fmt.Println(t)
+}
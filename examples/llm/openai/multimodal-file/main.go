@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/maksymenkoml/lingoose/llm/openai"
+	"github.com/maksymenkoml/lingoose/thread"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Println("usage: multimodal-file <image path>")
+		os.Exit(1)
+	}
+
+	imageContent, err := thread.NewImageContentFromFile(os.Args[1])
+	if err != nil {
+		panic(err)
+	}
+
+	t := thread.New().AddMessage(
+		thread.NewUserMessage().AddContent(
+			thread.NewTextContent("Can you describe the image?"),
+		).AddContent(
+			imageContent,
+		),
+	)
+
+	err = openai.New().WithModel(openai.GPT4o).Generate(context.Background(), t)
+	if err != nil {
+		panic(err)
+	}
+
+	fmt.Println(t)
+}
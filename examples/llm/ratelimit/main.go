@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	openaiembedder "github.com/maksymenkoml/lingoose/embedder/openai"
+	"github.com/maksymenkoml/lingoose/index"
+	"github.com/maksymenkoml/lingoose/index/vectordb/jsondb"
+	"github.com/maksymenkoml/lingoose/llm/openai"
+	"github.com/maksymenkoml/lingoose/llm/ratelimit"
+	"github.com/maksymenkoml/lingoose/loader"
+	"github.com/maksymenkoml/lingoose/textsplitter"
+	"github.com/maksymenkoml/lingoose/thread"
+)
+
+func main() {
+	// one limiter shared by the embedder and the LLM keeps the process under the
+	// quota of the account
+	limiter := ratelimit.New(500, 200000)
+
+	docs, err := loader.NewPDFToTextLoader("./kb").
+		WithTextSplitter(textsplitter.NewRecursiveCharacterTextSplitter(2000, 200)).
+		Load(context.Background())
+	if err != nil {
+		panic(err)
+	}
+
+	idx := index.New(
+		jsondb.New().WithPersist("index.json"),
+		limiter.Embedder(openaiembedder.New(openaiembedder.SmallEmbedding3)),
+	)
+
+	err = idx.LoadFromDocuments(context.Background(), docs)
+	if err != nil {
+		panic(err)
+	}
+
+	llm := limiter.LLM(openai.New().WithModel(openai.GPT4o))
+
+	t := thread.New().AddMessage(
+		thread.NewUserMessage().AddContent(
+			thread.NewTextContent("Hello, who are you?"),
+		),
+	)
+
+	err = llm.Generate(context.Background(), t)
+	if err != nil {
+		panic(err)
+	}
+
+	fmt.Println(t)
+}
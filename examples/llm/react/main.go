@@ -0,0 +1,31 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/maksymenkoml/lingoose/llm/ollama"
+	"github.com/maksymenkoml/lingoose/llm/react"
+	"github.com/maksymenkoml/lingoose/thread"
+	"github.com/maksymenkoml/lingoose/tool/python"
+)
+
+func main() {
+	// llama2 has no native tool calling, tools are used through the ReAct prompt
+	llm := react.New(ollama.New().WithModel("llama2")).WithTools(
+		python.New(),
+	)
+
+	t := thread.New().AddMessage(
+		thread.NewUserMessage().AddContent(
+			thread.NewTextContent("calculate reverse string of 'ailatiditalia', don't try to guess, let's use appropriate tool"),
+		),
+	)
+
+	err := llm.Generate(context.Background(), t)
+	if err != nil {
+		panic(err)
+	}
+
+	fmt.Println(t)
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/maksymenkoml/lingoose/llm/openai"
+	"github.com/maksymenkoml/lingoose/llm/structured"
+	"github.com/maksymenkoml/lingoose/thread"
+)
+
+type Recipe struct {
+	Name        string   `json:"name" jsonschema:"description=the name of the recipe"`
+	Servings    int      `json:"servings" jsonschema:"minimum=1"`
+	Ingredients []string `json:"ingredients" jsonschema:"minItems=1"`
+	Difficulty  string   `json:"difficulty" jsonschema:"enum=easy,enum=medium,enum=hard"`
+}
+
+func main() {
+	t := thread.New().AddMessage(
+		thread.NewUserMessage().AddContent(
+			thread.NewTextContent("Give me the recipe of the Italian carbonara"),
+		),
+	)
+
+	recipe, err := structured.GenerateStructured[Recipe](
+		context.Background(),
+		openai.New().WithModel(openai.GPT4o),
+		t,
+	)
+	if err != nil {
+		panic(err)
+	}
+
+	fmt.Printf("%+v\n", recipe)
+}
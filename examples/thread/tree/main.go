@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/maksymenkoml/lingoose/llm/openai"
+	"github.com/maksymenkoml/lingoose/thread"
+)
+
+func generate(llm *openai.OpenAI, tree *thread.Tree) {
+	t := tree.Thread()
+	err := llm.Generate(context.Background(), t)
+	if err != nil {
+		panic(err)
+	}
+
+	err = tree.Update(t)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func main() {
+	llm := openai.New().WithModel(openai.GPT4o).WithTemperature(1)
+
+	tree := thread.NewTree()
+	question := tree.AddMessage(thread.NewUserMessage().AddContent(
+		thread.NewTextContent("Suggest a name for a pet gopher"),
+	))
+	generate(llm, tree)
+
+	// regenerate the reply, keeping the first one as an alternative
+	reply := tree.Head()
+	err := tree.Fork(reply.ID)
+	if err != nil {
+		panic(err)
+	}
+	generate(llm, tree)
+
+	siblings, err := tree.Siblings(reply.ID)
+	if err != nil {
+		panic(err)
+	}
+	for i, sibling := range siblings {
+		fmt.Printf("reply %d: %s\n", i+1, sibling.Message.Contents[0].AsString())
+	}
+
+	// edit the question and answer the new one
+	_, err = tree.Edit(question.ID, thread.NewUserMessage().AddContent(
+		thread.NewTextContent("Suggest a name for a pet rabbit"),
+	))
+	if err != nil {
+		panic(err)
+	}
+	generate(llm, tree)
+
+	fmt.Println(tree.Thread())
+
+	// switch back to the original question and its last reply
+	err = tree.Checkout(question.ID)
+	if err != nil {
+		panic(err)
+	}
+
+	fmt.Println(tree.Thread())
+}
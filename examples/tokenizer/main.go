@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/maksymenkoml/lingoose/llm/openai"
+	"github.com/maksymenkoml/lingoose/llm/ratelimit"
+	"github.com/maksymenkoml/lingoose/textsplitter"
+	"github.com/maksymenkoml/lingoose/thread"
+	"github.com/maksymenkoml/lingoose/tokenizer"
+	"github.com/maksymenkoml/lingoose/usage"
+)
+
+// download the rank file once, e.g. from
+// https://openaipublic.blob.core.windows.net/encodings/o200k_base.tiktoken
+const rankFile = "o200k_base.tiktoken"
+
+func main() {
+	encoding, err := tokenizer.Load(tokenizer.O200kBase, rankFile)
+	if err != nil {
+		panic(err)
+	}
+
+	text := "Lingoose is a Go framework for building awesome AI/LLM applications."
+
+	// chunks of at most 16 tokens
+	chunks := textsplitter.NewRecursiveCharacterTextSplitter(16, 4).
+		WithLengthFunction(encoding.Count).
+		SplitText(text)
+	for _, chunk := range chunks {
+		fmt.Printf("%d tokens: %q\n", encoding.Count(chunk), chunk)
+	}
+
+	t := thread.New().AddMessage(
+		thread.NewUserMessage().AddContent(
+			thread.NewTextContent(text + " Summarize it in one sentence."),
+		),
+	)
+
+	// the cost of the prompt, before sending it
+	promptTokens := 0
+	for _, message := range t.Messages {
+		promptTokens += encoding.CountMessage(message)
+	}
+	cost := usage.DefaultPricing().Cost(usage.Usage{Model: string(openai.GPT4o), PromptTokens: promptTokens})
+	fmt.Printf("prompt: %d tokens, $%f\n", promptTokens, cost)
+
+	llm := ratelimit.New(500, 30000).
+		LLM(openai.New().
+			WithModel(openai.GPT4o).
+			WithWindow(thread.WindowTokenBudget(4000, encoding.CountMessage))).
+		WithTokenCounter(encoding.CountMessage)
+
+	err = llm.Generate(context.Background(), t)
+	if err != nil {
+		panic(err)
+	}
+
+	fmt.Println(t)
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/maksymenkoml/lingoose/assistant"
+	"github.com/maksymenkoml/lingoose/llm/openai"
+	"github.com/maksymenkoml/lingoose/thread"
+	"github.com/maksymenkoml/lingoose/tool"
+	"github.com/maksymenkoml/lingoose/tool/shell"
+)
+
+type approvalRequest struct {
+	toolCall thread.ToolCallData
+	response chan tool.Approval
+}
+
+func main() {
+	// approval requests are sent to a channel, e.g. consumed by a web UI
+	requests := make(chan approvalRequest)
+	go approve(requests)
+
+	registry := tool.NewRegistry().WithToolApproval(
+		"bash",
+		func(ctx context.Context, toolCall thread.ToolCallData) (tool.Approval, error) {
+			request := approvalRequest{toolCall: toolCall, response: make(chan tool.Approval, 1)}
+			select {
+			case requests <- request:
+			case <-ctx.Done():
+				return tool.Approval{}, ctx.Err()
+			}
+
+			select {
+			case approval := <-request.response:
+				return approval, nil
+			case <-ctx.Done():
+				return tool.Approval{}, ctx.Err()
+			}
+		},
+	)
+
+	err := registry.AddTools(shell.New().WithAskForConfirm(false))
+	if err != nil {
+		panic(err)
+	}
+
+	myAssistant := assistant.New(
+		openai.New().WithModel(openai.GPT4o).WithToolRegistry(registry),
+	).WithThread(
+		thread.New().AddMessages(
+			thread.NewUserMessage().AddContent(
+				thread.NewTextContent("delete all the files in the current directory"),
+			),
+		),
+	)
+
+	err = myAssistant.Run(context.Background())
+	if err != nil {
+		panic(err)
+	}
+
+	fmt.Println(myAssistant.Thread())
+}
+
+func approve(requests <-chan approvalRequest) {
+	for request := range requests {
+		fmt.Printf("denying %s(%s)\n", request.toolCall.Name, request.toolCall.Arguments)
+		request.response <- tool.Deny("the user does not allow destructive commands")
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/maksymenkoml/lingoose/tool/duckduckgo"
@@ -11,5 +12,5 @@ func main() {
 	t := duckduckgo.New().WithMaxResults(5)
 	f := t.Fn().(duckduckgo.FnPrototype)
 
-	fmt.Println(f(duckduckgo.Input{Query: "Simone Vellei"}))
+	fmt.Println(f(context.Background(), duckduckgo.Input{Query: "Simone Vellei"}))
 }
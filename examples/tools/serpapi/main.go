@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/maksymenkoml/lingoose/tool/serpapi"
@@ -11,5 +12,5 @@ func main() {
 	t := serpapi.New()
 	f := t.Fn().(serpapi.FnPrototype)
 
-	fmt.Println(f(serpapi.Input{Query: "Simone Vellei"}))
+	fmt.Println(f(context.Background(), serpapi.Input{Query: "Simone Vellei"}))
 }
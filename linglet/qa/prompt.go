@@ -1,5 +1,9 @@
 package qa
 
+// RefinementPromptName is the name of the prompt refining the question of the user. The
+// template receives the prompt.
+const RefinementPromptName = "qa/refinement"
+
 //nolint:lll
 const (
 	refinementPrompt = `
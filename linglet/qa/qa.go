@@ -5,6 +5,7 @@ import (
 
 	"github.com/maksymenkoml/lingoose/assistant"
 	"github.com/maksymenkoml/lingoose/index"
+	"github.com/maksymenkoml/lingoose/prompt"
 	"github.com/maksymenkoml/lingoose/rag"
 	"github.com/maksymenkoml/lingoose/thread"
 	"github.com/maksymenkoml/lingoose/types"
@@ -22,6 +23,7 @@ type QA struct {
 	llm            LLM
 	index          *index.Index
 	subDocumentRAG *rag.SubDocumentRAG
+	prompts        *prompt.Registry
 }
 
 func New(
@@ -37,21 +39,31 @@ func New(
 	}
 }
 
-func (qa *QA) refinePrompt(ctx context.Context, prompt string) (string, error) {
+// WithPrompts sets the registry used to override the built-in prompts of the linglet,
+// of its RAG and of its assistant.
+func (qa *QA) WithPrompts(prompts *prompt.Registry) *QA {
+	qa.prompts = prompts
+	qa.subDocumentRAG.WithPrompts(prompts)
+	return qa
+}
+
+func (qa *QA) refinePrompt(ctx context.Context, userPrompt string) (string, error) {
+	text, err := prompt.Format(qa.prompts, RefinementPromptName, refinementPrompt, types.M{
+		"prompt": userPrompt,
+	})
+	if err != nil {
+		return userPrompt, err
+	}
+
 	t := thread.New().AddMessage(
 		thread.NewAssistantMessage().AddContent(
-			thread.NewTextContent(refinementPrompt).
-				Format(
-					types.M{
-						"prompt": prompt,
-					},
-				),
+			thread.NewTextContent(text),
 		),
 	)
 
-	err := qa.llm.Generate(ctx, t)
+	err = qa.llm.Generate(ctx, t)
 	if err != nil {
-		return prompt, err
+		return userPrompt, err
 	}
 
 	return t.LastMessage().Contents[0].AsString(), nil
@@ -61,8 +73,8 @@ func (qa *QA) AddSource(ctx context.Context, source string) error {
 	return qa.subDocumentRAG.AddSources(ctx, source)
 }
 
-func (qa *QA) Run(ctx context.Context, prompt string) (string, error) {
-	refinedPromt, err := qa.refinePrompt(ctx, prompt)
+func (qa *QA) Run(ctx context.Context, userPrompt string) (string, error) {
+	refinedPromt, err := qa.refinePrompt(ctx, userPrompt)
 	if err != nil {
 		return "", err
 	}
@@ -77,7 +89,7 @@ func (qa *QA) Run(ctx context.Context, prompt string) (string, error) {
 			CompanyName:        "",
 			CompanyDescription: "",
 		},
-	).WithRAG(qa.subDocumentRAG).WithThread(
+	).WithPrompts(qa.prompts).WithRAG(qa.subDocumentRAG).WithThread(
 		thread.New().AddMessages(
 			thread.NewUserMessage().AddContent(
 				thread.NewTextContent(refinedPromt),
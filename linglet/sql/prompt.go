@@ -1,5 +1,21 @@
 package sql
 
+const (
+	// SQLiteSystemPromptName and PostgreSQLSystemPromptName are the names of the system
+	// prompts in the prompt registry. The templates receive top_k.
+	SQLiteSystemPromptName     = "sql/system/sqlite"
+	PostgreSQLSystemPromptName = "sql/system/postgresql"
+	// QueryPromptName is the name of the prompt generating the SQL query. The template
+	// receives the schema and the question.
+	QueryPromptName = "sql/query"
+	// RefinePromptName is the name of the prompt fixing an invalid SQL query. The
+	// template receives sql_query and sql_error.
+	RefinePromptName = "sql/refine"
+	// FinalPromptName is the name of the prompt answering the question. The template
+	// receives the question and sql_result.
+	FinalPromptName = "sql/final"
+)
+
 const (
 	sqlPromptTemplate = `	
 Use the following table schema info to create your SQL query:
@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	"github.com/maksymenkoml/lingoose/assistant"
+	"github.com/maksymenkoml/lingoose/prompt"
 	"github.com/maksymenkoml/lingoose/thread"
 	"github.com/maksymenkoml/lingoose/types"
 )
@@ -26,6 +27,7 @@ type SQL struct {
 	topk       int
 	assistant  *assistant.Assistant
 	callbackFn CallbackFn
+	prompts    *prompt.Registry
 }
 
 type Result struct {
@@ -51,6 +53,14 @@ func (s *SQL) WithCallback(callbackFn CallbackFn) *SQL {
 	return s
 }
 
+// WithPrompts sets the registry used to override the built-in prompts of the linglet
+// and of its assistant.
+func (s *SQL) WithPrompts(prompts *prompt.Registry) *SQL {
+	s.prompts = prompts
+	s.assistant.WithPrompts(prompts)
+	return s
+}
+
 func (s *SQL) schema() (*string, error) {
 	driverType := fmt.Sprintf("%T", s.db.Driver())
 	if strings.Contains(driverType, "sqlite") {
@@ -62,17 +72,30 @@ func (s *SQL) schema() (*string, error) {
 	return nil, fmt.Errorf("unsupported database driver %s", driverType)
 }
 
-func (s *SQL) systemPrompt() (*string, error) {
+func (s *SQL) systemPrompt() (*thread.Content, error) {
 	driverType := fmt.Sprintf("%T", s.db.Driver())
+	input := types.M{
+		"top_k": s.topk,
+	}
+
 	if strings.Contains(driverType, "sqlite") {
-		return &sqliteSystemPromptTemplate, nil
+		return s.formatPrompt(SQLiteSystemPromptName, sqliteSystemPromptTemplate, input)
 	} else if strings.Contains(driverType, "pq.Driver") {
-		return &psqlSystemPromptTemplate, nil
+		return s.formatPrompt(PostgreSQLSystemPromptName, psqlSystemPromptTemplate, input)
 	}
 
 	return nil, fmt.Errorf("unsupported database driver %s", driverType)
 }
 
+func (s *SQL) formatPrompt(name, fallback string, input types.M) (*thread.Content, error) {
+	text, err := prompt.Format(s.prompts, name, fallback, input)
+	if err != nil {
+		return nil, err
+	}
+
+	return thread.NewTextContent(text), nil
+}
+
 func (s *SQL) Run(ctx context.Context, question string) (*Result, error) {
 	sqlQuery, err := s.generateSQLQuery(ctx, question)
 	if err != nil {
@@ -130,24 +153,18 @@ func (s *SQL) generateSQLQuery(ctx context.Context, question string) (*string, e
 	if err != nil {
 		return nil, err
 	}
+	queryPrompt, err := s.formatPrompt(QueryPromptName, sqlPromptTemplate, types.M{
+		"schema":   schema,
+		"question": question,
+	})
+	if err != nil {
+		return nil, err
+	}
 
 	s.assistant.Thread().ClearMessages().AddMessage(
-		thread.NewSystemMessage().AddContent(
-			thread.NewTextContent(*systemPrompt).Format(
-				types.M{
-					"top_k": s.topk,
-				},
-			),
-		),
+		thread.NewSystemMessage().AddContent(systemPrompt),
 	).AddMessage(
-		thread.NewUserMessage().AddContent(
-			thread.NewTextContent(sqlPromptTemplate).Format(
-				types.M{
-					"schema":   schema,
-					"question": question,
-				},
-			),
-		),
+		thread.NewUserMessage().AddContent(queryPrompt),
 	)
 
 	err = s.assistant.Run(ctx)
@@ -167,37 +184,31 @@ func (s *SQL) generateRefinedSQLQuery(ctx context.Context, question, sqlQuery st
 	if err != nil {
 		return nil, err
 	}
+	refinePrompt, err := s.formatPrompt(RefinePromptName, sqlRefinePromptTemplate, types.M{
+		"sql_query": sqlQuery,
+		"sql_error": sqlError.Error(),
+	})
+	if err != nil {
+		return nil, err
+	}
 	schema, err := s.schema()
 	if err != nil {
 		return nil, err
 	}
+	queryPrompt, err := s.formatPrompt(QueryPromptName, sqlPromptTemplate, types.M{
+		"schema":   schema,
+		"question": question,
+	})
+	if err != nil {
+		return nil, err
+	}
 
 	s.assistant.Thread().ClearMessages().AddMessage(
-		thread.NewSystemMessage().AddContent(
-			thread.NewTextContent(*systemPrompt).Format(
-				types.M{
-					"top_k": s.topk,
-				},
-			),
-		),
+		thread.NewSystemMessage().AddContent(systemPrompt),
 	).AddMessage(
-		thread.NewUserMessage().AddContent(
-			thread.NewTextContent(sqlPromptTemplate).Format(
-				types.M{
-					"schema":   schema,
-					"question": question,
-				},
-			),
-		),
+		thread.NewUserMessage().AddContent(queryPrompt),
 	).AddMessage(
-		thread.NewUserMessage().AddContent(
-			thread.NewTextContent(sqlRefinePromptTemplate).Format(
-				types.M{
-					"sql_query": sqlQuery,
-					"sql_error": sqlError.Error(),
-				},
-			),
-		),
+		thread.NewUserMessage().AddContent(refinePrompt),
 	)
 
 	err = s.assistant.Run(ctx)
@@ -213,18 +224,19 @@ func (s *SQL) generateRefinedSQLQuery(ctx context.Context, question, sqlQuery st
 }
 
 func (s *SQL) generateAnswer(ctx context.Context, sqlResult, question string) (*string, error) {
+	finalPrompt, err := s.formatPrompt(FinalPromptName, sqlFinalPromptTemplate, types.M{
+		"question":   question,
+		"sql_result": sqlResult,
+	})
+	if err != nil {
+		return nil, err
+	}
+
 	s.assistant.Thread().ClearMessages().AddMessage(
-		thread.NewUserMessage().AddContent(
-			thread.NewTextContent(sqlFinalPromptTemplate).Format(
-				types.M{
-					"question":   question,
-					"sql_result": sqlResult,
-				},
-			),
-		),
+		thread.NewUserMessage().AddContent(finalPrompt),
 	)
 
-	err := s.assistant.Run(ctx)
+	err = s.assistant.Run(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -1,5 +1,14 @@
 package summarize
 
+const (
+	// SummaryPromptName is the name of the prompt summarizing the first document. The
+	// template receives the text.
+	SummaryPromptName = "summarize/summary"
+	// RefinePromptName is the name of the prompt refining the summary with the next
+	// documents. The template receives the text and the current summary as output.
+	RefinePromptName = "summarize/refine"
+)
+
 const (
 	summaryPrompt = "Write a concise summary of the following:\n\n{{.text}}"
 	refinePrompt  = `Your job is to produce a final summary.
@@ -5,6 +5,7 @@ import (
 
 	"github.com/maksymenkoml/lingoose/assistant"
 	"github.com/maksymenkoml/lingoose/document"
+	"github.com/maksymenkoml/lingoose/prompt"
 	"github.com/maksymenkoml/lingoose/thread"
 	"github.com/maksymenkoml/lingoose/types"
 )
@@ -23,6 +24,7 @@ type Summarize struct {
 	assistant  *assistant.Assistant
 	loader     Loader
 	callbackFn CallbackFn
+	prompts    *prompt.Registry
 }
 
 func New(llm LLM, loader Loader) *Summarize {
@@ -37,6 +39,14 @@ func (s *Summarize) WithCallback(callbackFn CallbackFn) *Summarize {
 	return s
 }
 
+// WithPrompts sets the registry used to override the built-in prompts of the linglet
+// and of its assistant.
+func (s *Summarize) WithPrompts(prompts *prompt.Registry) *Summarize {
+	s.prompts = prompts
+	s.assistant.WithPrompts(prompts)
+	return s
+}
+
 func (s *Summarize) Run(ctx context.Context) (*string, error) {
 	documents, err := s.loader.Load(ctx)
 	if err != nil {
@@ -51,21 +61,22 @@ func (s *Summarize) Run(ctx context.Context) (*string, error) {
 	}
 
 	for i, document := range documents {
-		prompt := refinePrompt
+		promptName, promptTemplate := RefinePromptName, refinePrompt
 		if i == 0 {
-			prompt = summaryPrompt
+			promptName, promptTemplate = SummaryPromptName, summaryPrompt
+		}
+
+		text, promptErr := prompt.Format(s.prompts, promptName, promptTemplate, types.M{
+			"text":   document.Content,
+			"output": summary,
+		})
+		if promptErr != nil {
+			return nil, promptErr
 		}
 
 		s.assistant.Thread().ClearMessages().AddMessage(
 			thread.NewAssistantMessage().AddContent(
-				thread.NewTextContent(
-					prompt,
-				).Format(
-					types.M{
-						"text":   document.Content,
-						"output": summary,
-					},
-				),
+				thread.NewTextContent(text),
 			),
 		)
 
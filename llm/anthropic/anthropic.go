@@ -11,17 +11,23 @@ import (
 
 	"github.com/henomis/restclientgo"
 
+	"github.com/maksymenkoml/lingoose/event"
+	"github.com/maksymenkoml/lingoose/llm/apierror"
 	"github.com/maksymenkoml/lingoose/llm/cache"
 	llmobserver "github.com/maksymenkoml/lingoose/llm/observer"
+	"github.com/maksymenkoml/lingoose/llm/retry"
 	"github.com/maksymenkoml/lingoose/observer"
 	"github.com/maksymenkoml/lingoose/thread"
+	"github.com/maksymenkoml/lingoose/tool"
 	"github.com/maksymenkoml/lingoose/types"
+	"github.com/maksymenkoml/lingoose/usage"
 )
 
 const (
 	defaultModel           = "claude-3-opus-20240229"
 	eventStreamContentType = "text/event-stream"
 	jsonContentType        = "application/json"
+	retryAfterHeader       = "Retry-After"
 	defaultEndpoint        = "https://api.anthropic.com/v1"
 )
 
@@ -33,6 +39,7 @@ var threadRoleToAnthropicRole = map[thread.Role]string{
 	thread.RoleSystem:    "system",
 	thread.RoleUser:      "user",
 	thread.RoleAssistant: "assistant",
+	thread.RoleTool:      "user",
 }
 
 const (
@@ -52,6 +59,11 @@ type Antropic struct {
 	apiVersion       string
 	apiKey           string
 	maxTokens        int
+	window           thread.WindowFn
+	tools            *tool.Registry
+	toolsErr         error
+	toolChoice       *string
+	retry            *retry.Policy
 	name             string
 }
 
@@ -70,10 +82,19 @@ func New() *Antropic {
 		apiVersion: defaultAPIVersion,
 		apiKey:     apiKey,
 		maxTokens:  defaultMaxTokens,
+		tools:      tool.NewRegistry(),
+		retry:      retry.New(),
 		name:       "anthropic",
 	}
 }
 
+// WithRetry sets the policy retrying the requests failing with a transient error. A nil
+// policy disables the retries.
+func (o *Antropic) WithRetry(policy *retry.Policy) *Antropic {
+	o.retry = policy
+	return o
+}
+
 func (o *Antropic) WithModel(model string) *Antropic {
 	o.model = model
 	return o
@@ -99,6 +120,50 @@ func (o *Antropic) WithMaxTokens(maxTokens int) *Antropic {
 	return o
 }
 
+// WithWindow sets the window function used to select the thread messages sent to the model.
+func (o *Antropic) WithWindow(window thread.WindowFn) *Antropic {
+	o.window = window
+	return o
+}
+
+// WithTools adds the tools to the Anthropic instance. If a tool is invalid, Generate
+// fails with its error.
+func (o *Antropic) WithTools(tools ...tool.Tool) *Antropic {
+	err := o.tools.AddTools(tools...)
+	if err != nil && o.toolsErr == nil {
+		o.toolsErr = err
+	}
+
+	return o
+}
+
+// WithToolRegistry sets the registry holding the tools available to the Anthropic instance.
+// A nil registry is an empty one.
+func (o *Antropic) WithToolRegistry(registry *tool.Registry) *Antropic {
+	if registry == nil {
+		registry = tool.NewRegistry()
+	}
+
+	o.tools = registry
+	return o
+}
+
+// WithToolChoice sets the tool choice: "auto", "any" or the name of a tool.
+// If not set the model is not allowed to use tools.
+func (o *Antropic) WithToolChoice(toolChoice *string) *Antropic {
+	o.toolChoice = toolChoice
+	return o
+}
+
+func (o *Antropic) BindFunction(
+	fn interface{},
+	name string,
+	description string,
+	functionParameterOptions ...tool.FunctionParameterOption,
+) error {
+	return o.tools.Bind(fn, name, description, functionParameterOptions...)
+}
+
 func (o *Antropic) getCache(ctx context.Context, t *thread.Thread) (*cache.Result, error) {
 	messages := t.UserQuery()
 	cacheQuery := strings.Join(messages, "\n")
@@ -140,8 +205,23 @@ func (o *Antropic) setCache(ctx context.Context, t *thread.Thread, cacheResult *
 }
 
 func (o *Antropic) Generate(ctx context.Context, t *thread.Thread) error {
+	_, err := o.generateChat(ctx, t)
+	return err
+}
+
+// GenerateWithUsage generates the next messages of the thread and returns the tokens
+// used, in streaming mode too. The usage is nil when the answer comes from the cache.
+func (o *Antropic) GenerateWithUsage(ctx context.Context, t *thread.Thread) (*usage.Usage, error) {
+	return o.generateChat(ctx, t)
+}
+
+func (o *Antropic) generateChat(ctx context.Context, t *thread.Thread) (*usage.Usage, error) {
 	if t == nil {
-		return nil
+		return nil, nil
+	}
+
+	if o.toolsErr != nil {
+		return nil, fmt.Errorf("%w: %w", ErrAnthropicChat, o.toolsErr)
 	}
 
 	var err error
@@ -149,73 +229,144 @@ func (o *Antropic) Generate(ctx context.Context, t *thread.Thread) error {
 	if o.cache != nil {
 		cacheResult, err = o.getCache(ctx, t)
 		if err == nil {
-			return nil
+			return nil, nil
 		} else if !errors.Is(err, cache.ErrCacheMiss) {
-			return fmt.Errorf("%w: %w", ErrAnthropicChat, err)
+			return nil, fmt.Errorf("%w: %w", ErrAnthropicChat, err)
 		}
 	}
 
-	chatRequest := o.buildChatCompletionRequest(t)
+	chatRequest, err := o.buildChatCompletionRequest(ctx, t.Window(o.window))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrAnthropicChat, err)
+	}
 
 	generation, err := o.startObserveGeneration(ctx, t)
 	if err != nil {
-		return fmt.Errorf("%w: %w", ErrAnthropicChat, err)
+		return nil, fmt.Errorf("%w: %w", ErrAnthropicChat, err)
 	}
 
+	nMessageBeforeGeneration := len(t.Messages)
+
+	var u usage.Usage
 	if o.streamCallbackFn != nil {
-		err = o.stream(ctx, t, chatRequest)
+		u, err = o.stream(ctx, t, chatRequest)
 	} else {
-		err = o.generate(ctx, t, chatRequest)
+		u, err = o.generate(ctx, t, chatRequest)
 	}
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	err = o.stopObserveGeneration(ctx, generation, []*thread.Message{t.LastMessage()})
+	usage.Record(ctx, u)
+
+	err = o.stopObserveGeneration(ctx, generation, t.Messages[nMessageBeforeGeneration:])
 	if err != nil {
-		return fmt.Errorf("%w: %w", ErrAnthropicChat, err)
+		return nil, fmt.Errorf("%w: %w", ErrAnthropicChat, err)
 	}
 
 	if o.cache != nil {
 		err = o.setCache(ctx, t, cacheResult)
 		if err != nil {
-			return fmt.Errorf("%w: %w", ErrAnthropicChat, err)
+			return nil, fmt.Errorf("%w: %w", ErrAnthropicChat, err)
 		}
 	}
 
-	return nil
+	return &u, nil
 }
 
-func (o *Antropic) generate(ctx context.Context, t *thread.Thread, chatRequest *request) error {
+func (o *Antropic) generate(ctx context.Context, t *thread.Thread, chatRequest *request) (usage.Usage, error) {
 	var resp response
 
-	err := o.restClient.Post(
-		ctx,
-		chatRequest,
-		&resp,
-	)
+	err := o.retry.Do(ctx, func(ctx context.Context) error {
+		resp = response{}
+		return o.post(ctx, chatRequest, &resp)
+	})
 	if err != nil {
-		return fmt.Errorf("%w: %w", ErrAnthropicChat, err)
+		return usage.Usage{}, fmt.Errorf("%w: %w", ErrAnthropicChat, err)
 	}
 
 	m := thread.NewAssistantMessage()
+	var toolUseContents []content
 
 	for _, content := range resp.Content {
 		if content.Type == messageTypeText && content.Text != nil {
 			m.AddContent(
 				thread.NewTextContent(*content.Text),
 			)
+		} else if content.Type == messageTypeToolUse {
+			toolUseContents = append(toolUseContents, content)
 		}
 	}
 
-	t.AddMessage(m)
+	t.AddMessages(o.responseMessages(ctx, m, toolUseContents)...)
+
+	return o.usage(resp.Model, resp.Usage), nil
+}
+
+// responseMessages returns the assistant text message, followed by the tool call
+// message and the tool responses when the model requested tool calls.
+func (o *Antropic) responseMessages(
+	ctx context.Context,
+	textMessage *thread.Message,
+	toolUseContents []content,
+) []*thread.Message {
+	toolCallMessage := toolUseContentsToToolCallMessage(toolUseContents)
+	if toolCallMessage == nil {
+		return []*thread.Message{textMessage}
+	}
+
+	var messages []*thread.Message
+	if len(textMessage.Contents) > 0 {
+		messages = append(messages, textMessage)
+	}
+
+	messages = append(messages, toolCallMessage)
+	messages = append(messages, o.tools.CallTools(ctx, toolCallMessage.Contents[0].AsToolCallData())...)
+
+	return messages
+}
+
+// usage converts the usage of a response. The input tokens reported by Anthropic do
+// not include the cached ones.
+func (o *Antropic) usage(model string, u tokenUsage) usage.Usage {
+	if model == "" {
+		model = o.model
+	}
+
+	return usage.Usage{
+		Provider:         o.name,
+		Model:            model,
+		PromptTokens:     u.InputTokens + u.CacheCreationInputTokens + u.CacheReadInputTokens,
+		CompletionTokens: u.OutputTokens,
+		CachedTokens:     u.CacheReadInputTokens,
+	}
+}
+
+// post sends the request and returns the error response as an apierror.Error.
+func (o *Antropic) post(ctx context.Context, chatRequest *request, resp *response) error {
+	err := o.restClient.Post(
+		ctx,
+		chatRequest,
+		resp,
+	)
+	if err != nil {
+		return err
+	}
+
+	if resp.HTTPStatusCode >= http.StatusBadRequest {
+		return apierror.New(resp.HTTPStatusCode, resp.RawBody).WithRetryAfter(resp.retryAfter)
+	}
 
 	return nil
 }
 
-func (o *Antropic) stream(ctx context.Context, t *thread.Thread, chatRequest *request) error {
+func (o *Antropic) stream(ctx context.Context, t *thread.Thread, chatRequest *request) (usage.Usage, error) {
 	var resp response
 	var assistantMessage string
+	var toolUseContents []content
+	var model string
+	var streamUsage tokenUsage
+	var delivered bool
 
 	resp.SetAcceptContentType(eventStreamContentType)
 	resp.SetStreamCallback(
@@ -227,15 +378,41 @@ func (o *Antropic) stream(ctx context.Context, t *thread.Thread, chatRequest *re
 
 			dataAsString = strings.Replace(dataAsString, "data: ", "", -1)
 
-			var e event
+			var e streamEvent
 			_ = json.Unmarshal([]byte(dataAsString), &e)
 
-			if e.Type == "content_block_delta" {
-				if e.Delta != nil {
+			switch e.Type {
+			case "message_start":
+				if e.Message != nil {
+					model = e.Message.Model
+					streamUsage = e.Message.Usage
+				}
+			case "message_delta":
+				if e.Usage != nil {
+					streamUsage.OutputTokens = e.Usage.OutputTokens
+				}
+			case "content_block_start":
+				if e.ContentBlock != nil && e.ContentBlock.Type == messageTypeToolUse {
+					e.ContentBlock.Input = nil
+					toolUseContents = append(toolUseContents, *e.ContentBlock)
+				}
+			case "content_block_delta":
+				if e.Delta == nil {
+					break
+				}
+
+				if e.Delta.Type == deltaTypeInputJSON {
+					if len(toolUseContents) > 0 {
+						last := &toolUseContents[len(toolUseContents)-1]
+						last.Input = append(last.Input, e.Delta.PartialJSON...)
+					}
+				} else {
 					assistantMessage += e.Delta.Text
+					delivered = true
 					o.streamCallbackFn(e.Delta.Text)
+					event.Emit(ctx, event.NewTextDelta(e.Delta.Text))
 				}
-			} else if e.Type == "message_stop" {
+			case "message_stop":
 				o.streamCallbackFn(EOS)
 			}
 
@@ -245,24 +422,31 @@ func (o *Antropic) stream(ctx context.Context, t *thread.Thread, chatRequest *re
 
 	chatRequest.Stream = true
 
-	err := o.restClient.Post(
-		ctx,
-		chatRequest,
-		&resp,
-	)
+	err := o.retry.Do(ctx, func(ctx context.Context) error {
+		// a retried stream starts over, unless its deltas have already been delivered
+		assistantMessage = ""
+		toolUseContents = nil
+		streamUsage = tokenUsage{}
+
+		err := o.post(ctx, chatRequest, &resp)
+		if err != nil && delivered {
+			return retry.Permanent(err)
+		}
+
+		return err
+	})
 	if err != nil {
-		return fmt.Errorf("%w: %w", ErrAnthropicChat, err)
+		return usage.Usage{}, fmt.Errorf("%w: %w", ErrAnthropicChat, err)
 	}
 
-	if resp.HTTPStatusCode >= http.StatusBadRequest {
-		return fmt.Errorf("%w: %s", ErrAnthropicChat, resp.RawBody)
+	m := thread.NewAssistantMessage()
+	if assistantMessage != "" || len(toolUseContents) == 0 {
+		m.AddContent(thread.NewTextContent(assistantMessage))
 	}
 
-	t.AddMessage(thread.NewAssistantMessage().AddContent(
-		thread.NewTextContent(assistantMessage),
-	))
+	t.AddMessages(o.responseMessages(ctx, m, toolUseContents)...)
 
-	return nil
+	return o.usage(model, streamUsage), nil
 }
 
 func (o *Antropic) startObserveGeneration(ctx context.Context, t *thread.Thread) (*observer.Generation, error) {
@@ -0,0 +1,249 @@
+package anthropic
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/maksymenkoml/lingoose/thread"
+	"github.com/maksymenkoml/lingoose/tool"
+	"github.com/maksymenkoml/lingoose/usage"
+)
+
+func TestAntropic_GenerateWithUsage(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req request
+		_ = json.NewDecoder(r.Body).Decode(&req)
+
+		if !req.Stream {
+			w.Header().Set("Content-Type", jsonContentType)
+			_, _ = w.Write([]byte(`{"type":"message","role":"assistant","model":"claude-3-5-haiku-20241022",` +
+				`"content":[{"type":"text","text":"hello"}],` +
+				`"usage":{"input_tokens":10,"output_tokens":2,"cache_read_input_tokens":5}}`))
+			return
+		}
+
+		w.Header().Set("Content-Type", eventStreamContentType)
+		_, _ = w.Write([]byte(
+			`data: {"type":"message_start","message":{"model":"claude-3-5-haiku-20241022",` +
+				`"usage":{"input_tokens":10,"output_tokens":1,"cache_read_input_tokens":5}}}` + "\n\n" +
+				`data: {"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}` + "\n\n" +
+				`data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"hel"}}` + "\n\n" +
+				`data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"lo"}}` + "\n\n" +
+				`data: {"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":2}}` + "\n\n" +
+				`data: {"type":"message_stop"}` + "\n\n",
+		))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name string
+		llm  *Antropic
+	}{
+		{name: "generate", llm: New()},
+		{name: "stream", llm: New().WithStream(func(string) {})},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.llm.restClient.SetEndpoint(server.URL)
+
+			tracker := usage.NewTracker()
+			ctx := usage.ContextWithTracker(context.Background(), tracker)
+
+			th := thread.New().AddMessage(thread.NewUserMessage().AddContent(thread.NewTextContent("hi")))
+			u, err := tt.llm.GenerateWithUsage(ctx, th)
+			if err != nil {
+				t.Fatalf("GenerateWithUsage() error = %v", err)
+			}
+
+			want := usage.Usage{
+				Provider:         "anthropic",
+				Model:            "claude-3-5-haiku-20241022",
+				PromptTokens:     15,
+				CompletionTokens: 2,
+				CachedTokens:     5,
+			}
+			if u == nil || *u != want {
+				t.Errorf("GenerateWithUsage() usage = %+v, want %+v", u, want)
+			}
+			if got := tracker.Total(); got.TotalTokens() != want.TotalTokens() || got.Cost == 0 {
+				t.Errorf("recorded usage = %+v, want %+v priced", got, want)
+			}
+			if got := th.LastMessage().Contents[0].AsString(); got != "hello" {
+				t.Errorf("reply = %q, want hello", got)
+			}
+		})
+	}
+}
+
+type sumInput struct {
+	A int `json:"a"`
+	B int `json:"b"`
+}
+
+func TestAntropic_Generate_Tools(t *testing.T) {
+	var req request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req = request{}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+
+		if !req.Stream {
+			w.Header().Set("Content-Type", jsonContentType)
+			_, _ = w.Write([]byte(`{"type":"message","role":"assistant","model":"claude-3-5-haiku-20241022",` +
+				`"content":[{"type":"text","text":"Let me sum."},` +
+				`{"type":"tool_use","id":"toolu_3","name":"sum","input":{"a":5,"b":6}}],` +
+				`"usage":{"input_tokens":10,"output_tokens":2}}`))
+			return
+		}
+
+		// the input of a tool use block is streamed as partial JSON
+		w.Header().Set("Content-Type", eventStreamContentType)
+		_, _ = w.Write([]byte(
+			`data: {"type":"message_start","message":{"model":"claude-3-5-haiku-20241022"}}` + "\n\n" +
+				`data: {"type":"content_block_start","index":0,` +
+				`"content_block":{"type":"tool_use","id":"toolu_3","name":"sum","input":{}}}` + "\n\n" +
+				`data: {"type":"content_block_delta","index":0,"delta":{"type":"input_json_delta","partial_json":""}}` + "\n\n" +
+				`data: {"type":"content_block_delta","index":0,"delta":{"type":"input_json_delta","partial_json":"{\"a\": 5"}}` + "\n\n" +
+				`data: {"type":"content_block_delta","index":0,"delta":{"type":"input_json_delta","partial_json":", \"b\": 6}"}}` + "\n\n" +
+				`data: {"type":"content_block_stop","index":0}` + "\n\n" +
+				`data: {"type":"message_delta","delta":{"stop_reason":"tool_use"},"usage":{"output_tokens":2}}` + "\n\n" +
+				`data: {"type":"message_stop"}` + "\n\n",
+		))
+	}))
+	defer server.Close()
+
+	registry := tool.NewRegistry()
+	err := registry.Bind(func(i sumInput) int { return i.A + i.B }, "sum", "sum two numbers")
+	if err != nil {
+		t.Fatalf("Registry.Bind() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		llm  *Antropic
+	}{
+		{name: "generate", llm: New()},
+		{name: "stream", llm: New().WithStream(func(string) {})},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auto := "auto"
+			tt.llm.WithToolRegistry(registry).WithToolChoice(&auto).restClient.SetEndpoint(server.URL)
+
+			// the previous tool use step answered two parallel tool calls
+			th := thread.New().AddMessage(
+				thread.NewUserMessage().AddContent(thread.NewTextContent("Sum 1 and 2, then 3 and 4.")),
+			).AddMessage(
+				thread.NewAssistantMessage().AddContent(thread.NewToolCallContent([]thread.ToolCallData{
+					{ID: "toolu_1", Name: "sum", Arguments: `{"a":1,"b":2}`},
+					{ID: "toolu_2", Name: "sum", Arguments: `{"a":3,"b":4}`},
+				})),
+			).AddMessage(
+				tool.NewToolResponseMessage(thread.ToolCallData{ID: "toolu_1", Name: "sum"}, "3"),
+			).AddMessage(
+				tool.NewToolResponseMessage(thread.ToolCallData{ID: "toolu_2", Name: "sum"}, "7"),
+			).AddMessage(
+				thread.NewUserMessage().AddContent(thread.NewTextContent("Now sum 5 and 6.")),
+			)
+
+			err := tt.llm.Generate(context.Background(), th)
+			if err != nil {
+				t.Fatalf("Generate() error = %v", err)
+			}
+
+			// the tool responses and the following user message are merged in a single turn
+			if len(req.Messages) != 3 {
+				t.Fatalf("request messages = %+v, want 3 turns", req.Messages)
+			}
+
+			toolUse := req.Messages[1]
+			if toolUse.Role != "assistant" || len(toolUse.Content) != 2 {
+				t.Fatalf("tool use turn = %+v, want 2 tool use blocks", toolUse)
+			}
+			for i, id := range []string{"toolu_1", "toolu_2"} {
+				c := toolUse.Content[i]
+				if c.Type != messageTypeToolUse || c.ID != id || c.Name != "sum" {
+					t.Errorf("tool use block = %+v, want %s", c, id)
+				}
+			}
+
+			toolResult := req.Messages[2]
+			if toolResult.Role != "user" || len(toolResult.Content) != 3 {
+				t.Fatalf("tool result turn = %+v, want 2 tool results and a text", toolResult)
+			}
+			for i, want := range []struct{ id, result string }{{"toolu_1", "3"}, {"toolu_2", "7"}} {
+				c := toolResult.Content[i]
+				if c.Type != messageTypeToolResult || c.ToolUseID != want.id || c.Content == nil || *c.Content != want.result {
+					t.Errorf("tool result block = %+v, want %s = %s", c, want.id, want.result)
+				}
+			}
+			if c := toolResult.Content[2]; c.Type != messageTypeText || c.Text == nil || *c.Text != "Now sum 5 and 6." {
+				t.Errorf("text block = %+v, want the user message", c)
+			}
+
+			// the tool call is added to the thread with its accumulated input and executed
+			toolCalls := th.Messages[len(th.Messages)-2].Contents[0].AsToolCallData()
+			if len(toolCalls) != 1 || toolCalls[0].ID != "toolu_3" {
+				t.Fatalf("tool calls = %+v, want toolu_3", toolCalls)
+			}
+			var arguments sumInput
+			if err := json.Unmarshal([]byte(toolCalls[0].Arguments), &arguments); err != nil || arguments != (sumInput{5, 6}) {
+				t.Errorf("tool call arguments = %s, want a=5 b=6", toolCalls[0].Arguments)
+			}
+
+			got := th.LastMessage()
+			if got.Role != thread.RoleTool || got.Contents[0].AsToolResponseData().Result != "11" {
+				t.Errorf("tool response = %+v, want 11", got.Contents[0].Data)
+			}
+		})
+	}
+}
+
+// invalidTool has no function to call.
+type invalidTool struct{}
+
+func (invalidTool) Name() string        { return "invalid" }
+func (invalidTool) Description() string { return "not a function" }
+func (invalidTool) Fn() any             { return "not a function" }
+
+func TestAntropic_Generate_ToolRegistry(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", jsonContentType)
+		_, _ = w.Write([]byte(`{"type":"message","role":"assistant","model":"claude-3-5-haiku-20241022",` +
+			`"content":[{"type":"text","text":"hello"}],"usage":{"input_tokens":10,"output_tokens":2}}`))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		llm     *Antropic
+		wantErr bool
+	}{
+		{name: "invalid tool", llm: New().WithTools(invalidTool{}), wantErr: true},
+		{name: "nil registry", llm: New().WithToolRegistry(nil)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls = 0
+			tt.llm.restClient.SetEndpoint(server.URL)
+
+			th := thread.New().AddMessage(thread.NewUserMessage().AddContent(thread.NewTextContent("hi")))
+			err := tt.llm.Generate(context.Background(), th)
+			if tt.wantErr {
+				if !errors.Is(err, ErrAnthropicChat) || calls != 0 {
+					t.Errorf("Generate() error = %v, calls = %d, want the tool error before any call", err, calls)
+				}
+				return
+			}
+
+			if err != nil || th.LastMessage().Contents[0].AsString() != "hello" {
+				t.Errorf("Generate() error = %v, want the reply", err)
+			}
+		})
+	}
+}
@@ -4,25 +4,41 @@ import (
 	"bytes"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
 	"os"
 	"strings"
 
 	"github.com/henomis/restclientgo"
+
+	"github.com/maksymenkoml/lingoose/thread"
 )
 
 type request struct {
-	Model         string    `json:"model"`
-	Messages      []message `json:"messages"`
-	System        string    `json:"system"`
-	MaxTokens     int       `json:"max_tokens"`
-	Metadata      metadata  `json:"metadata"`
-	StopSequences []string  `json:"stop_sequences"`
-	Stream        bool      `json:"stream"`
-	Temperature   float64   `json:"temperature"`
-	TopP          float64   `json:"top_p"`
-	TopK          int       `json:"top_k"`
+	Model         string           `json:"model"`
+	Messages      []message        `json:"messages"`
+	System        string           `json:"system"`
+	MaxTokens     int              `json:"max_tokens"`
+	Metadata      metadata         `json:"metadata"`
+	StopSequences []string         `json:"stop_sequences"`
+	Stream        bool             `json:"stream"`
+	Temperature   float64          `json:"temperature"`
+	TopP          float64          `json:"top_p"`
+	TopK          int              `json:"top_k"`
+	Tools         []toolDefinition `json:"tools,omitempty"`
+	ToolChoice    *toolChoice      `json:"tool_choice,omitempty"`
+}
+
+type toolDefinition struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	InputSchema map[string]any `json:"input_schema"`
+}
+
+type toolChoice struct {
+	Type string `json:"type"`
+	Name string `json:"name,omitempty"`
 }
 
 type metadata struct {
@@ -47,18 +63,19 @@ func (r *request) ContentType() string {
 }
 
 type response struct {
-	HTTPStatusCode    int       `json:"-"`
-	acceptContentType string    `json:"-"`
-	ID                string    `json:"id"`
-	Type              string    `json:"type"`
-	Error             aerror    `json:"error"`
-	Role              string    `json:"role"`
-	Content           []content `json:"content"`
-	Model             string    `json:"model"`
-	StopReason        *string   `json:"stop_reason"`
-	StopSequence      *string   `json:"stop_sequence"`
-	Usage             usage     `json:"usage"`
+	HTTPStatusCode    int        `json:"-"`
+	acceptContentType string     `json:"-"`
+	ID                string     `json:"id"`
+	Type              string     `json:"type"`
+	Error             aerror     `json:"error"`
+	Role              string     `json:"role"`
+	Content           []content  `json:"content"`
+	Model             string     `json:"model"`
+	StopReason        *string    `json:"stop_reason"`
+	StopSequence      *string    `json:"stop_sequence"`
+	Usage             tokenUsage `json:"usage"`
 	streamCallbackFn  restclientgo.StreamCallback
+	retryAfter        string
 	RawBody           []byte `json:"-"`
 }
 
@@ -68,9 +85,15 @@ type aerror struct {
 }
 
 type content struct {
-	Type   contentType    `json:"type"`
-	Text   *string        `json:"text,omitempty"`
-	Source *contentSource `json:"source,omitempty"`
+	Type      contentType     `json:"type"`
+	Text      *string         `json:"text,omitempty"`
+	Source    *contentSource  `json:"source,omitempty"`
+	ID        string          `json:"id,omitempty"`
+	Name      string          `json:"name,omitempty"`
+	Input     json.RawMessage `json:"input,omitempty"`
+	ToolUseID string          `json:"tool_use_id,omitempty"`
+	Content   *string         `json:"content,omitempty"`
+	Title     string          `json:"title,omitempty"`
 }
 
 type contentSource struct {
@@ -79,9 +102,11 @@ type contentSource struct {
 	Data      string `json:"data"`
 }
 
-type usage struct {
-	InputTokens  int `json:"input_tokens"`
-	OutputTokens int `json:"output_tokens"`
+type tokenUsage struct {
+	InputTokens              int `json:"input_tokens"`
+	OutputTokens             int `json:"output_tokens"`
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+	CacheReadInputTokens     int `json:"cache_read_input_tokens"`
 }
 
 func (r *response) SetAcceptContentType(contentType string) {
@@ -109,7 +134,10 @@ func (r *response) SetStatusCode(code int) error {
 	return nil
 }
 
-func (r *response) SetHeaders(_ restclientgo.Headers) error { return nil }
+func (r *response) SetHeaders(headers restclientgo.Headers) error {
+	r.retryAfter = http.Header(headers).Get(retryAfterHeader)
+	return nil
+}
 
 func (r *response) SetStreamCallback(fn restclientgo.StreamCallback) {
 	r.streamCallbackFn = fn
@@ -127,19 +155,52 @@ type message struct {
 type contentType string
 
 const (
-	messageTypeText  contentType = "text"
-	messageTypeImage contentType = "image"
+	messageTypeText       contentType = "text"
+	messageTypeImage      contentType = "image"
+	messageTypeToolUse    contentType = "tool_use"
+	messageTypeToolResult contentType = "tool_result"
+	messageTypeDocument   contentType = "document"
 )
 
-type event struct {
-	Type  string `json:"type"`
-	Index *int   `json:"index,omitempty"`
-	Delta *delta `json:"delta,omitempty"`
+type streamEvent struct {
+	Type         string         `json:"type"`
+	Index        *int           `json:"index,omitempty"`
+	Delta        *delta         `json:"delta,omitempty"`
+	ContentBlock *content       `json:"content_block,omitempty"`
+	Message      *streamMessage `json:"message,omitempty"`
+	Usage        *tokenUsage    `json:"usage,omitempty"`
+}
+
+// streamMessage is the message of the message_start event. Its usage holds the input
+// tokens, the output tokens are reported by the message_delta events.
+type streamMessage struct {
+	Model string     `json:"model"`
+	Usage tokenUsage `json:"usage"`
 }
 
 type delta struct {
-	Type string `json:"type"`
-	Text string `json:"text"`
+	Type        string `json:"type"`
+	Text        string `json:"text"`
+	PartialJSON string `json:"partial_json"`
+}
+
+const (
+	deltaTypeInputJSON = "input_json_delta"
+)
+
+// getContentImageDataAsBase64 returns the base64 data and the MIME type of an image
+// content, either embedded or referenced by URL or file path.
+func getContentImageDataAsBase64(c *thread.Content) (string, string, error) {
+	if imageData := c.AsImageData(); imageData != nil {
+		return imageData.Base64(), imageData.MIMEType, nil
+	}
+
+	imageURL, ok := c.Data.(string)
+	if !ok {
+		return "", "", errors.New("invalid image content")
+	}
+
+	return getImageDataAsBase64(imageURL)
 }
 
 func getImageDataAsBase64(imageURL string) (string, string, error) {
@@ -1,28 +1,80 @@
 package anthropic
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime"
+
+	"github.com/maksymenkoml/lingoose/loader"
 	"github.com/maksymenkoml/lingoose/thread"
 )
 
-func (o *Antropic) buildChatCompletionRequest(t *thread.Thread) *request {
-	messages, systemPrompt := threadToChatMessages(t)
+const (
+	emptyToolInput     = "{}"
+	pdfMediaType       = "application/pdf"
+	plainTextMediaType = "text/plain"
+)
 
-	return &request{
+func (o *Antropic) buildChatCompletionRequest(ctx context.Context, t *thread.Thread) (*request, error) {
+	messages, systemPrompt, err := threadToChatMessages(ctx, t)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &request{
 		Model:       o.model,
 		Messages:    messages,
 		System:      systemPrompt,
 		MaxTokens:   o.maxTokens,
 		Temperature: o.temperature,
 	}
+
+	if o.tools.Len() > 0 {
+		r.Tools = o.getChatCompletionRequestTools()
+		r.ToolChoice = o.getChatCompletionRequestToolChoice()
+	}
+
+	return r, nil
+}
+
+func (o *Antropic) getChatCompletionRequestTools() []toolDefinition {
+	tools := []toolDefinition{}
+
+	for _, function := range o.tools.Functions() {
+		tools = append(tools, toolDefinition{
+			Name:        function.Name,
+			Description: function.Description,
+			InputSchema: function.Parameters,
+		})
+	}
+
+	return tools
+}
+
+func (o *Antropic) getChatCompletionRequestToolChoice() *toolChoice {
+	if o.toolChoice == nil {
+		return &toolChoice{Type: "none"}
+	}
+
+	if *o.toolChoice == "auto" || *o.toolChoice == "any" {
+		return &toolChoice{Type: *o.toolChoice}
+	}
+
+	return &toolChoice{
+		Type: "tool",
+		Name: *o.toolChoice,
+	}
 }
 
-//nolint:gocognit
-func threadToChatMessages(t *thread.Thread) ([]message, string) {
+// threadToChatMessages returns the messages and the system prompt of the thread. It
+// fails if the text of a file cannot be extracted, or with thread.ErrUnsupportedContent
+// if a message holds audio.
+func threadToChatMessages(ctx context.Context, t *thread.Thread) ([]message, string, error) {
 	var systemPrompt string
 	var chatMessages []message
 	for _, m := range t.Messages {
-		switch m.Role {
-		case thread.RoleSystem:
+		if m.Role == thread.RoleSystem {
 			for _, content := range m.Contents {
 				contentData, ok := content.Data.(string)
 				if !ok {
@@ -31,50 +83,171 @@ func threadToChatMessages(t *thread.Thread) ([]message, string) {
 
 				systemPrompt += contentData
 			}
-		case thread.RoleUser, thread.RoleAssistant:
-			chatMessage := message{
-				Role: threadRoleToAnthropicRole[m.Role],
-			}
-			for _, c := range m.Contents {
-				contentData, ok := c.Data.(string)
-				if !ok {
-					continue
-				}
+			continue
+		}
 
-				if c.Type == thread.ContentTypeText {
-					chatMessage.Content = append(
-						chatMessage.Content,
-						content{
-							Type: messageTypeText,
-							Text: &contentData,
-						},
-					)
-				} else if c.Type == thread.ContentTypeImage {
-					imageData, mimeType, err := getImageDataAsBase64(contentData)
-					if err != nil {
-						continue
-					}
-
-					chatMessage.Content = append(
-						chatMessage.Content,
-						content{
-							Type: messageTypeImage,
-							Source: &contentSource{
-								Type:      "base64",
-								Data:      imageData,
-								MediaType: mimeType,
-							},
-						},
-					)
-				} else {
-					continue
-				}
+		chatMessage := message{
+			Role: threadRoleToAnthropicRole[m.Role],
+		}
+		for _, c := range m.Contents {
+			contents, err := threadContentToChatMessageContents(ctx, c)
+			if err != nil {
+				return nil, "", err
 			}
-			chatMessages = append(chatMessages, chatMessage)
-		case thread.RoleTool:
+			chatMessage.Content = append(chatMessage.Content, contents...)
+		}
+
+		if len(chatMessage.Content) == 0 {
 			continue
 		}
+
+		// Anthropic expects all the blocks of a turn in a single message, e.g. every
+		// tool result answering the tool calls of the previous assistant turn.
+		if len(chatMessages) > 0 && chatMessages[len(chatMessages)-1].Role == chatMessage.Role {
+			chatMessages[len(chatMessages)-1].Content = append(
+				chatMessages[len(chatMessages)-1].Content,
+				chatMessage.Content...,
+			)
+			continue
+		}
+
+		chatMessages = append(chatMessages, chatMessage)
+	}
+
+	return chatMessages, systemPrompt, nil
+}
+
+func threadContentToChatMessageContents(ctx context.Context, c *thread.Content) ([]content, error) {
+	switch c.Type {
+	case thread.ContentTypeText:
+		contentData, ok := c.Data.(string)
+		if !ok {
+			return nil, nil
+		}
+
+		return []content{
+			{
+				Type: messageTypeText,
+				Text: &contentData,
+			},
+		}, nil
+	case thread.ContentTypeImage:
+		imageData, mimeType, err := getContentImageDataAsBase64(c)
+		if err != nil {
+			return nil, fmt.Errorf("image: %w", err)
+		}
+
+		return []content{
+			{
+				Type: messageTypeImage,
+				Source: &contentSource{
+					Type:      "base64",
+					Data:      imageData,
+					MediaType: mimeType,
+				},
+			},
+		}, nil
+	case thread.ContentTypeToolCall:
+		var contents []content
+		for _, toolCallData := range c.AsToolCallData() {
+			input := toolCallData.Arguments
+			if !json.Valid([]byte(input)) {
+				input = emptyToolInput
+			}
+
+			contents = append(contents, content{
+				Type:  messageTypeToolUse,
+				ID:    toolCallData.ID,
+				Name:  toolCallData.Name,
+				Input: json.RawMessage(input),
+			})
+		}
+
+		return contents, nil
+	case thread.ContentTypeToolResponse:
+		toolResponseData := c.AsToolResponseData()
+		if toolResponseData == nil {
+			return nil, nil
+		}
+
+		return []content{
+			{
+				Type:      messageTypeToolResult,
+				ToolUseID: toolResponseData.ID,
+				Content:   &toolResponseData.Result,
+			},
+		}, nil
+	case thread.ContentTypeFile:
+		if c.AsFileData() == nil {
+			return nil, nil
+		}
+
+		return fileContentToDocumentContents(ctx, c)
+	case thread.ContentTypeAudio:
+		return nil, fmt.Errorf("%w: audio input", thread.ErrUnsupportedContent)
+	default:
+		return nil, nil
+	}
+}
+
+// fileContentToDocumentContents returns a document block for the file. PDF and plain
+// text files are sent natively, the text of any other document is extracted with the
+// loader, see loader.ContentFileText.
+func fileContentToDocumentContents(ctx context.Context, c *thread.Content) ([]content, error) {
+	fileData := c.AsFileData()
+	mediaType, _, _ := mime.ParseMediaType(fileData.MIMEType)
+	source := &contentSource{
+		Type:      "base64",
+		MediaType: pdfMediaType,
+		Data:      fileData.Base64(),
+	}
+
+	if mediaType != pdfMediaType {
+		text := string(fileData.Data)
+		if mediaType != plainTextMediaType {
+			var err error
+			text, err = loader.ContentFileText(ctx, c)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		source = &contentSource{
+			Type:      "text",
+			MediaType: plainTextMediaType,
+			Data:      text,
+		}
+	}
+
+	return []content{
+		{
+			Type:   messageTypeDocument,
+			Source: source,
+			Title:  fileData.Name,
+		},
+	}, nil
+}
+
+func toolUseContentsToToolCallMessage(contents []content) *thread.Message {
+	var toolCallData []thread.ToolCallData
+	for _, c := range contents {
+		arguments := string(c.Input)
+		if arguments == "" {
+			arguments = emptyToolInput
+		}
+
+		toolCallData = append(toolCallData, thread.ToolCallData{
+			ID:        c.ID,
+			Name:      c.Name,
+			Arguments: arguments,
+		})
+	}
+
+	if len(toolCallData) == 0 {
+		return nil
 	}
 
-	return chatMessages, systemPrompt
+	return thread.NewAssistantMessage().AddContent(
+		thread.NewToolCallContent(toolCallData),
+	)
 }
@@ -0,0 +1,29 @@
+package anthropic
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/maksymenkoml/lingoose/thread"
+)
+
+func Test_threadContentToChatMessageContents_Errors(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		content *thread.Content
+	}{
+		{name: "unreadable image", content: thread.NewImageContentFromURL(filepath.Join(t.TempDir(), "missing.png"))},
+		{name: "audio", content: thread.NewAudioContent([]byte("RIFF"), "audio/wav")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			contents, err := threadContentToChatMessageContents(ctx, tt.content)
+			if err == nil {
+				t.Errorf("threadContentToChatMessageContents() = %+v, want an error", contents)
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+// Package apierror classifies the errors returned by the LLM and embedder providers,
+// e.g. to tell transient failures like rate limits, server errors, timeouts and
+// connection resets from the others.
+package apierror
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// Error is an error response of the API of an LLM provider.
+type Error struct {
+	StatusCode int
+	Body       string
+	// RetryAfter is the delay requested by the Retry-After header, if any.
+	RetryAfter time.Duration
+}
+
+func New(statusCode int, body []byte) *Error {
+	return &Error{
+		StatusCode: statusCode,
+		Body:       string(body),
+	}
+}
+
+// WithRetryAfter sets the delay requested by the value of a Retry-After header, given
+// either in seconds or as an HTTP date.
+func (e *Error) WithRetryAfter(retryAfter string) *Error {
+	e.RetryAfter = ParseRetryAfter(retryAfter)
+	return e
+}
+
+func (e *Error) Error() string {
+	if e.Body != "" {
+		return e.Body
+	}
+	return http.StatusText(e.StatusCode)
+}
+
+// StatusCode returns the HTTP status code of the error response wrapped by err.
+func StatusCode(err error) (int, bool) {
+	var apiError *Error
+	if errors.As(err, &apiError) {
+		return apiError.StatusCode, true
+	}
+
+	var openAIAPIError *openai.APIError
+	if errors.As(err, &openAIAPIError) && openAIAPIError.HTTPStatusCode > 0 {
+		return openAIAPIError.HTTPStatusCode, true
+	}
+
+	var openAIRequestError *openai.RequestError
+	if errors.As(err, &openAIRequestError) && openAIRequestError.HTTPStatusCode > 0 {
+		return openAIRequestError.HTTPStatusCode, true
+	}
+
+	return 0, false
+}
+
+// RetryAfter returns the delay requested by the Retry-After header of the error
+// response wrapped by err.
+func RetryAfter(err error) (time.Duration, bool) {
+	var apiError *Error
+	if errors.As(err, &apiError) && apiError.RetryAfter > 0 {
+		return apiError.RetryAfter, true
+	}
+
+	return 0, false
+}
+
+// ParseRetryAfter parses the value of a Retry-After header, given either in seconds or
+// as an HTTP date. It returns 0 when the value is empty or invalid.
+func ParseRetryAfter(retryAfter string) time.Duration {
+	if retryAfter == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(retryAfter); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+
+	date, err := http.ParseTime(retryAfter)
+	if err != nil {
+		return 0
+	}
+
+	if delay := time.Until(date); delay > 0 {
+		return delay
+	}
+
+	return 0
+}
+
+// IsRateLimit reports whether err is a rate limit error response.
+func IsRateLimit(err error) bool {
+	statusCode, ok := StatusCode(err)
+	return ok && statusCode == http.StatusTooManyRequests
+}
+
+// IsServerError reports whether err is a 5xx error response.
+func IsServerError(err error) bool {
+	statusCode, ok := StatusCode(err)
+	return ok && statusCode >= http.StatusInternalServerError
+}
+
+// IsTimeout reports whether err is caused by a timeout, either of the request or of
+// the server.
+func IsTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	var netError net.Error
+	if errors.As(err, &netError) && netError.Timeout() {
+		return true
+	}
+
+	statusCode, ok := StatusCode(err)
+	return ok && (statusCode == http.StatusRequestTimeout || statusCode == http.StatusGatewayTimeout)
+}
+
+// IsConnectionError reports whether err is caused by a connection failure, e.g. a
+// connection reset by the server.
+func IsConnectionError(err error) bool {
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.EPIPE) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+
+	var opError *net.OpError
+	return errors.As(err, &opError)
+}
+
+// IsTransient reports whether err is a rate limit, a server error, a timeout or a
+// connection failure, that is an error that may not happen again when retrying the
+// request.
+func IsTransient(err error) bool {
+	return IsRateLimit(err) || IsServerError(err) || IsTimeout(err) || IsConnectionError(err)
+}
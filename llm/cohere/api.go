@@ -0,0 +1,97 @@
+package cohere
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/henomis/cohere-go/model"
+	"github.com/henomis/cohere-go/response"
+	"github.com/henomis/restclientgo"
+)
+
+const (
+	defaultEndpoint = "https://api.cohere.ai/v1"
+)
+
+// newRestClient returns the client of the chat endpoint. The chat requests are not sent
+// with the Cohere client, whose response drops the IDs of the tool calls.
+func newRestClient(apiKey string) *restclientgo.RestClient {
+	return restclientgo.New(defaultEndpoint).WithRequestModifier(
+		func(req *http.Request) *http.Request {
+			req.Header.Set("Authorization", "Bearer "+apiKey)
+			return req
+		},
+	)
+}
+
+// chatResponse is the response of the chat endpoint, or a streamed event. It decodes
+// the fields the Cohere client does not expose: the IDs of the tool calls and the
+// billed units, which are nil until the response holding them is decoded.
+type chatResponse struct {
+	response.Chat
+	billedUnits *billedUnits
+}
+
+// billedUnits are the tokens billed for a chat request.
+type billedUnits struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+// chatResponseFields holds the fields of a chat response not decoded by response.Chat.
+// The stream-end event wraps the final response.
+type chatResponseFields struct {
+	ToolCalls []struct {
+		ID string `json:"id"`
+	} `json:"tool_calls"`
+	Meta struct {
+		BilledUnits *billedUnits `json:"billed_units"`
+	} `json:"meta"`
+	Response *chatResponseFields `json:"response"`
+}
+
+func (r *chatResponse) Decode(body io.Reader) error {
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return err
+	}
+
+	return r.decode(data)
+}
+
+// decode decodes a response, or a streamed event, over the previous one.
+func (r *chatResponse) decode(data []byte) error {
+	err := json.Unmarshal(data, &r.Chat)
+	if err != nil {
+		return err
+	}
+
+	var fields chatResponseFields
+	err = json.Unmarshal(data, &fields)
+	if err != nil {
+		return err
+	}
+
+	setToolCallIDs(&r.NonStreamedChat, &fields)
+	if fields.Meta.BilledUnits != nil {
+		r.billedUnits = fields.Meta.BilledUnits
+	}
+
+	if fields.Response != nil {
+		setToolCallIDs(&r.StreamedChat.Response, fields.Response)
+		if fields.Response.Meta.BilledUnits != nil {
+			r.billedUnits = fields.Response.Meta.BilledUnits
+		}
+	}
+
+	return nil
+}
+
+func setToolCallIDs(chat *model.NonStreamedChat, fields *chatResponseFields) {
+	for i := range chat.ToolCalls {
+		if i < len(fields.ToolCalls) {
+			chat.ToolCalls[i].ID = fields.ToolCalls[i].ID
+		}
+	}
+}
@@ -11,13 +11,19 @@ import (
 	"github.com/henomis/cohere-go/model"
 	"github.com/henomis/cohere-go/request"
 	"github.com/henomis/cohere-go/response"
+	"github.com/henomis/restclientgo"
 
+	"github.com/maksymenkoml/lingoose/event"
 	"github.com/maksymenkoml/lingoose/legacy/chat"
+	"github.com/maksymenkoml/lingoose/llm/apierror"
 	"github.com/maksymenkoml/lingoose/llm/cache"
 	llmobserver "github.com/maksymenkoml/lingoose/llm/observer"
+	"github.com/maksymenkoml/lingoose/llm/retry"
 	"github.com/maksymenkoml/lingoose/observer"
 	"github.com/maksymenkoml/lingoose/thread"
+	"github.com/maksymenkoml/lingoose/tool"
 	"github.com/maksymenkoml/lingoose/types"
+	"github.com/maksymenkoml/lingoose/usage"
 )
 
 var (
@@ -43,6 +49,7 @@ type StreamCallbackFn func(string)
 
 type Cohere struct {
 	client           *coherego.Client
+	restClient       *restclientgo.RestClient
 	model            Model
 	temperature      float64
 	maxTokens        int
@@ -50,6 +57,11 @@ type Cohere struct {
 	stop             []string
 	cache            *cache.Cache
 	streamCallbackFn StreamCallbackFn
+	window           thread.WindowFn
+	tools            *tool.Registry
+	toolsErr         error
+	retry            *retry.Policy
+	countFn          thread.TokenCountFn
 	name             string
 	observer         llmobserver.LLMObserver
 	observerTraceID  string
@@ -66,15 +78,36 @@ func NewCompletion() *Cohere {
 }
 
 func New() *Cohere {
+	apiKey := os.Getenv("COHERE_API_KEY")
+
 	return &Cohere{
-		client:      coherego.New(os.Getenv("COHERE_API_KEY")),
+		client:      coherego.New(apiKey),
+		restClient:  newRestClient(apiKey),
 		model:       DefaultModel,
 		temperature: DefaultTemperature,
 		maxTokens:   DefaultMaxTokens,
+		tools:       tool.NewRegistry(),
+		retry:       retry.New(),
+		countFn:     thread.EstimateTokens,
 		name:        "cohere",
 	}
 }
 
+// WithRetry sets the policy retrying the requests failing with a transient error. A nil
+// policy disables the retries.
+func (c *Cohere) WithRetry(policy *retry.Policy) *Cohere {
+	c.retry = policy
+	return c
+}
+
+// WithTokenCounter sets the function counting the tokens of the messages when the
+// response does not report the billed units, e.g. tokenizer.Encoding.CountMessage. The
+// tokens are estimated by default, see thread.EstimateTokens.
+func (c *Cohere) WithTokenCounter(countFn thread.TokenCountFn) *Cohere {
+	c.countFn = countFn
+	return c
+}
+
 // WithModel sets the model to use for the LLM
 func (c *Cohere) WithModel(model Model) *Cohere {
 	c.model = model
@@ -96,6 +129,7 @@ func (c *Cohere) WithMaxTokens(maxTokens int) *Cohere {
 // WithAPIKey sets the API key to use for the LLM
 func (c *Cohere) WithAPIKey(apiKey string) *Cohere {
 	c.client = coherego.New(apiKey)
+	c.restClient = newRestClient(apiKey)
 	return c
 }
 
@@ -116,6 +150,43 @@ func (c *Cohere) WithStream(callbackFn StreamCallbackFn) *Cohere {
 	return c
 }
 
+// WithWindow sets the window function used to select the thread messages sent to the model.
+func (c *Cohere) WithWindow(window thread.WindowFn) *Cohere {
+	c.window = window
+	return c
+}
+
+// WithTools adds the tools to the Cohere instance. If a tool is invalid, Generate
+// fails with its error.
+func (c *Cohere) WithTools(tools ...tool.Tool) *Cohere {
+	err := c.tools.AddTools(tools...)
+	if err != nil && c.toolsErr == nil {
+		c.toolsErr = err
+	}
+
+	return c
+}
+
+// WithToolRegistry sets the registry holding the tools available to the Cohere instance.
+// A nil registry is an empty one.
+func (c *Cohere) WithToolRegistry(registry *tool.Registry) *Cohere {
+	if registry == nil {
+		registry = tool.NewRegistry()
+	}
+
+	c.tools = registry
+	return c
+}
+
+func (c *Cohere) BindFunction(
+	fn interface{},
+	name string,
+	description string,
+	functionParameterOptions ...tool.FunctionParameterOption,
+) error {
+	return c.tools.Bind(fn, name, description, functionParameterOptions...)
+}
+
 func (c *Cohere) WithObserver(observer llmobserver.LLMObserver, traceID string) *Cohere {
 	c.observer = observer
 	c.observerTraceID = traceID
@@ -202,8 +273,25 @@ func (c *Cohere) setCache(ctx context.Context, t *thread.Thread, cacheResult *ca
 }
 
 func (c *Cohere) Generate(ctx context.Context, t *thread.Thread) error {
+	_, err := c.generateChat(ctx, t)
+	return err
+}
+
+// GenerateWithUsage generates the next messages of the thread and returns the tokens
+// billed, in streaming mode too. When the response does not report them, they are
+// counted with the token counter, see WithTokenCounter, and the usage is marked as
+// Estimated. The usage is nil when the answer comes from the cache.
+func (c *Cohere) GenerateWithUsage(ctx context.Context, t *thread.Thread) (*usage.Usage, error) {
+	return c.generateChat(ctx, t)
+}
+
+func (c *Cohere) generateChat(ctx context.Context, t *thread.Thread) (*usage.Usage, error) {
 	if t == nil {
-		return nil
+		return nil, nil
+	}
+
+	if c.toolsErr != nil {
+		return nil, fmt.Errorf("%w: %w", ErrCohereChat, c.toolsErr)
 	}
 
 	var err error
@@ -211,86 +299,202 @@ func (c *Cohere) Generate(ctx context.Context, t *thread.Thread) error {
 	if c.cache != nil {
 		cacheResult, err = c.getCache(ctx, t)
 		if err == nil {
-			return nil
+			return nil, nil
 		} else if !errors.Is(err, cache.ErrCacheMiss) {
-			return fmt.Errorf("%w: %w", ErrCohereChat, err)
+			return nil, fmt.Errorf("%w: %w", ErrCohereChat, err)
 		}
 	}
 
-	chatRequest := c.buildChatCompletionRequest(t)
+	window := t.Window(c.window)
+	chatRequest, err := c.buildChatCompletionRequest(ctx, window)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrCohereChat, err)
+	}
 
 	generation, err := c.startObserveGeneration(ctx, t)
 	if err != nil {
-		return fmt.Errorf("%w: %w", ErrCohereChat, err)
+		return nil, fmt.Errorf("%w: %w", ErrCohereChat, err)
 	}
 
+	// the window is the thread itself without a window function
+	promptMessages := window.Messages
+	nMessageBeforeGeneration := len(t.Messages)
+
+	var billed *billedUnits
 	if c.streamCallbackFn != nil {
-		err = c.stream(ctx, t, chatRequest)
+		billed, err = c.stream(ctx, t, chatRequest)
 	} else {
-		err = c.generate(ctx, t, chatRequest)
+		billed, err = c.generate(ctx, t, chatRequest)
 	}
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	err = c.stopObserveGeneration(ctx, generation, []*thread.Message{t.LastMessage()})
+	u := c.usage(billed, promptMessages, t.Messages[nMessageBeforeGeneration:])
+	usage.Record(ctx, u)
+
+	err = c.stopObserveGeneration(ctx, generation, t.Messages[nMessageBeforeGeneration:])
 	if err != nil {
-		return fmt.Errorf("%w: %w", ErrCohereChat, err)
+		return nil, fmt.Errorf("%w: %w", ErrCohereChat, err)
 	}
 
 	if c.cache != nil {
 		err = c.setCache(ctx, t, cacheResult)
 		if err != nil {
-			return fmt.Errorf("%w: %w", ErrCohereChat, err)
+			return nil, fmt.Errorf("%w: %w", ErrCohereChat, err)
 		}
 	}
 
-	return nil
+	return &u, nil
 }
 
-func (c *Cohere) generate(ctx context.Context, t *thread.Thread, chatRequest *request.Chat) error {
-	var response response.Chat
+// usage converts the billed units of a generation, or estimates them when the response
+// does not report them.
+func (c *Cohere) usage(billed *billedUnits, promptMessages, generatedMessages []*thread.Message) usage.Usage {
+	if billed == nil {
+		return c.estimateUsage(promptMessages, generatedMessages)
+	}
 
-	err := c.client.Chat(
-		ctx,
-		chatRequest,
-		&response,
-	)
+	return usage.Usage{
+		Provider:         c.name,
+		Model:            string(c.model),
+		PromptTokens:     billed.InputTokens,
+		CompletionTokens: billed.OutputTokens,
+	}
+}
+
+// estimateUsage counts the tokens of the prompt messages and of the generated ones with
+// the token counter, when the response does not report the billed units. The tool responses added after
+// the generation are not generated by the model.
+func (c *Cohere) estimateUsage(promptMessages, generatedMessages []*thread.Message) usage.Usage {
+	u := usage.Usage{
+		Provider:  c.name,
+		Model:     string(c.model),
+		Estimated: true,
+	}
+
+	for _, message := range promptMessages {
+		u.PromptTokens += c.countFn(message)
+	}
+
+	for _, message := range generatedMessages {
+		if message.Role != thread.RoleTool {
+			u.CompletionTokens += c.countFn(message)
+		}
+	}
+
+	return u
+}
+
+func (c *Cohere) generate(ctx context.Context, t *thread.Thread, chatRequest *request.Chat) (*billedUnits, error) {
+	var resp chatResponse
+
+	err := c.retry.Do(ctx, func(ctx context.Context) error {
+		resp = chatResponse{}
+		resp.SetAcceptContentType(response.ContentTypeJSON)
+		err := c.restClient.Post(
+			ctx,
+			chatRequest,
+			&resp,
+		)
+		if err != nil {
+			return err
+		}
+
+		return responseError(&resp.Chat)
+	})
 	if err != nil {
-		return fmt.Errorf("%w: %w", ErrCohereChat, err)
+		return nil, fmt.Errorf("%w: %w", ErrCohereChat, err)
 	}
 
-	t.AddMessage(thread.NewAssistantMessage().AddContent(
-		thread.NewTextContent(response.Text),
-	))
+	t.AddMessages(c.responseMessages(ctx, resp.Text, resp.ToolCalls)...)
 
-	return nil
+	return resp.billedUnits, nil
+}
+
+// responseMessages returns the assistant text message, followed by the tool call
+// message and the tool responses when the model requested tool calls.
+func (c *Cohere) responseMessages(ctx context.Context, text string, toolCalls []model.ToolCall) []*thread.Message {
+	textMessage := thread.NewAssistantMessage().AddContent(
+		thread.NewTextContent(text),
+	)
+
+	toolCallMessage := toolCallsToToolCallMessage(toolCalls)
+	if toolCallMessage == nil {
+		return []*thread.Message{textMessage}
+	}
+
+	var messages []*thread.Message
+	if text != "" {
+		messages = append(messages, textMessage)
+	}
+
+	messages = append(messages, toolCallMessage)
+	messages = append(messages, c.tools.CallTools(ctx, toolCallMessage.Contents[0].AsToolCallData())...)
+
+	return messages
 }
 
-func (c *Cohere) stream(ctx context.Context, t *thread.Thread, chatRequest *request.Chat) error {
-	chatResponse := &response.Chat{}
+func (c *Cohere) stream(ctx context.Context, t *thread.Thread, chatRequest *request.Chat) (*billedUnits, error) {
+	var resp chatResponse
 	var assistantMessage string
+	var toolCalls []model.ToolCall
+	var delivered bool
+
+	chatRequest.Stream = true
+
+	err := c.retry.Do(ctx, func(ctx context.Context) error {
+		// a retried stream starts over, unless its deltas have already been delivered
+		resp = chatResponse{}
+		assistantMessage = ""
+		toolCalls = nil
+
+		resp.SetAcceptContentType(response.ContentTypeStreamJSON)
+		resp.SetStreamCallback(func(data []byte) error {
+			err := resp.decode(data)
+			if err != nil {
+				return err
+			}
 
-	err := c.client.ChatStream(
-		ctx,
-		chatRequest,
-		chatResponse,
-		func(r *response.Chat) {
-			if r.Text != "" {
-				c.streamCallbackFn(r.Text)
-				assistantMessage += r.Text
+			// every event is decoded into the same response, so fields of previous
+			// events must be read according to the current event type
+			switch resp.EventType {
+			case model.EventTypeTextGeneration:
+				if resp.Text != "" {
+					delivered = true
+					c.streamCallbackFn(resp.Text)
+					event.Emit(ctx, event.NewTextDelta(resp.Text))
+					assistantMessage += resp.Text
+				}
+			case model.EventTypeStreamEnd:
+				toolCalls = resp.StreamedChat.Response.ToolCalls
+			default:
 			}
-		},
-	)
+
+			return nil
+		})
+
+		err := c.restClient.Post(
+			ctx,
+			chatRequest,
+			&resp,
+		)
+		if err != nil {
+			if delivered {
+				return retry.Permanent(err)
+			}
+			return err
+		}
+
+		return responseError(&resp.Chat)
+	})
 	if err != nil {
-		return fmt.Errorf("%w: %w", ErrCohereChat, err)
+		return nil, fmt.Errorf("%w: %w", ErrCohereChat, err)
 	}
 
-	t.AddMessage(thread.NewAssistantMessage().AddContent(
-		thread.NewTextContent(assistantMessage),
-	))
+	t.AddMessages(c.responseMessages(ctx, assistantMessage, toolCalls)...)
 
-	return nil
+	return resp.billedUnits, nil
 }
 
 func (c *Cohere) startObserveGeneration(ctx context.Context, t *thread.Thread) (*observer.Generation, error) {
@@ -317,3 +521,20 @@ func (c *Cohere) stopObserveGeneration(
 		messages,
 	)
 }
+
+// responseError returns the error response as an apierror.Error. The Retry-After
+// header is not exposed by the Cohere client.
+func responseError(chatResponse *response.Chat) error {
+	if chatResponse.IsSuccess() {
+		return nil
+	}
+
+	return apierror.New(chatResponse.Code, []byte(stringValue(chatResponse.RawBody)))
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,202 @@
+package cohere
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/henomis/cohere-go/request"
+
+	"github.com/maksymenkoml/lingoose/llm/retry"
+	"github.com/maksymenkoml/lingoose/thread"
+	"github.com/maksymenkoml/lingoose/usage"
+)
+
+type sumInput struct {
+	A int `json:"a"`
+	B int `json:"b"`
+}
+
+func TestCohere_Generate_ToolCalls(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req request.Chat
+		_ = json.NewDecoder(r.Body).Decode(&req)
+
+		if !req.Stream {
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"text":"","generation_id":"gen_1",` +
+				`"tool_calls":[{"id":"sum_1","name":"sum","parameters":{"a":1,"b":2}},{"name":"sum","parameters":{"a":3,"b":4}}]}`))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/stream+json")
+		_, _ = w.Write([]byte(
+			`{"is_finished":false,"event_type":"stream-start","generation_id":"gen_1"}` + "\n" +
+				`{"is_finished":true,"event_type":"stream-end","finish_reason":"COMPLETE","response":{"text":"",` +
+				`"tool_calls":[{"id":"sum_1","name":"sum","parameters":{"a":1,"b":2}},{"name":"sum","parameters":{"a":3,"b":4}}]}}` + "\n",
+		))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name string
+		llm  *Cohere
+	}{
+		{name: "generate", llm: New()},
+		{name: "stream", llm: New().WithStream(func(string) {})},
+	}
+	for _, tt := range tests {
+		tt.llm.restClient.SetEndpoint(server.URL)
+		err := tt.llm.BindFunction(func(i sumInput) int { return i.A + i.B }, "sum", "sum two numbers")
+		if err != nil {
+			t.Fatalf("%s: BindFunction() error = %v", tt.name, err)
+		}
+
+		th := thread.New().AddMessage(thread.NewUserMessage().AddContent(thread.NewTextContent("Sum 1 and 2, then 3 and 4.")))
+		err = tt.llm.Generate(context.Background(), th)
+		if err != nil {
+			t.Fatalf("%s: Generate() error = %v", tt.name, err)
+		}
+
+		if len(th.Messages) != 4 {
+			t.Fatalf("%s: thread messages = %d, want the tool calls and 2 tool responses", tt.name, len(th.Messages))
+		}
+
+		// the ID returned by Cohere is kept, a missing one is generated
+		toolCalls := th.Messages[1].Contents[0].AsToolCallData()
+		if len(toolCalls) != 2 || toolCalls[0].ID != "sum_1" || toolCalls[1].ID == "" {
+			t.Fatalf("%s: tool calls = %+v, want the ID sum_1 and a generated one", tt.name, toolCalls)
+		}
+
+		for i, want := range []string{"3", "7"} {
+			got := th.Messages[2+i].Contents[0].AsToolResponseData()
+			if got.ID != toolCalls[i].ID || got.Result != want {
+				t.Errorf("%s: tool response = %+v, want %s = %s", tt.name, got, toolCalls[i].ID, want)
+			}
+		}
+	}
+}
+
+func TestCohere_Generate_StreamRetry(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		calls++
+		switch calls {
+		case 1:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case 2:
+			w.Header().Set("Content-Type", "application/stream+json")
+			_, _ = w.Write([]byte(`{"is_finished":false,"event_type":"text-generation","text":"hel"}` + "\n" +
+				`{"is_finished":` + "\n"))
+		default:
+			w.Header().Set("Content-Type", "application/stream+json")
+			_, _ = w.Write([]byte(`{"is_finished":false,"event_type":"text-generation","text":"hello"}` + "\n" +
+				`{"is_finished":true,"event_type":"stream-end","finish_reason":"COMPLETE","response":{"text":"hello"}}` + "\n"))
+		}
+	}))
+	defer server.Close()
+
+	var deltas []string
+	llm := New().
+		WithStream(func(delta string) { deltas = append(deltas, delta) }).
+		WithRetry(retry.New().WithBackoff(time.Millisecond, time.Millisecond).WithRetryable(func(error) bool { return true }))
+	llm.restClient.SetEndpoint(server.URL)
+
+	// the stream failing before any delta is retried, the one failing after is not
+	th := thread.New().AddMessage(thread.NewUserMessage().AddContent(thread.NewTextContent("hi")))
+	err := llm.Generate(context.Background(), th)
+	if err == nil {
+		t.Fatalf("Generate() error = nil, want the stream error")
+	}
+
+	if calls != 2 {
+		t.Errorf("calls = %d, want 2", calls)
+	}
+	if len(deltas) != 1 || deltas[0] != "hel" || th.CountMessages() != 1 {
+		t.Errorf("deltas = %q, thread = %v, want the delivered delta only", deltas, th)
+	}
+}
+
+func TestCohere_GenerateWithUsage(t *testing.T) {
+	billed := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req request.Chat
+		_ = json.NewDecoder(r.Body).Decode(&req)
+
+		meta := `"meta":{"api_version":{"version":"1"}}`
+		if billed {
+			meta = `"meta":{"api_version":{"version":"1"},"billed_units":{"input_tokens":10,"output_tokens":2}}`
+		}
+
+		if !req.Stream {
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"text":"hello",` + meta + `}`))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/stream+json")
+		_, _ = w.Write([]byte(
+			`{"is_finished":false,"event_type":"text-generation","text":"hel"}` + "\n" +
+				`{"is_finished":false,"event_type":"text-generation","text":"lo"}` + "\n" +
+				`{"is_finished":true,"event_type":"stream-end","finish_reason":"COMPLETE",` +
+				`"response":{"text":"hello",` + meta + `}}` + "\n",
+		))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		llm     *Cohere
+		billed  bool
+		counted bool
+	}{
+		{name: "generate", llm: New(), billed: true},
+		{name: "stream", llm: New().WithStream(func(string) {}), billed: true},
+		{name: "generate estimated", llm: New()},
+		{name: "stream estimated", llm: New().WithStream(func(string) {})},
+		{name: "generate counted", llm: New().WithTokenCounter(func(*thread.Message) int { return 7 }), counted: true},
+	}
+	for _, tt := range tests {
+		billed = tt.billed
+		tt.llm.restClient.SetEndpoint(server.URL)
+
+		tracker := usage.NewTracker()
+		ctx := usage.ContextWithTracker(context.Background(), tracker)
+
+		th := thread.New().AddMessage(thread.NewUserMessage().AddContent(thread.NewTextContent("hi")))
+		u, err := tt.llm.GenerateWithUsage(ctx, th)
+		if err != nil {
+			t.Fatalf("%s: GenerateWithUsage() error = %v", tt.name, err)
+		}
+
+		if got := th.LastMessage().Contents[0].AsString(); got != "hello" {
+			t.Errorf("%s: reply = %q, want hello", tt.name, got)
+		}
+		if got := tracker.Total(); got.TotalTokens() != u.TotalTokens() || got.Estimated != u.Estimated {
+			t.Errorf("%s: recorded usage = %+v, want %+v", tt.name, got, u)
+		}
+
+		if tt.counted {
+			want := usage.Usage{Provider: "cohere", Model: string(DefaultModel), PromptTokens: 7, CompletionTokens: 7, Estimated: true}
+			if u == nil || *u != want {
+				t.Errorf("%s: GenerateWithUsage() usage = %+v, want %+v", tt.name, u, want)
+			}
+			continue
+		}
+
+		if !tt.billed {
+			if !u.Estimated || u.PromptTokens == 0 || u.CompletionTokens == 0 {
+				t.Errorf("%s: GenerateWithUsage() usage = %+v, want an estimated usage", tt.name, u)
+			}
+			continue
+		}
+
+		want := usage.Usage{Provider: "cohere", Model: string(DefaultModel), PromptTokens: 10, CompletionTokens: 2}
+		if u == nil || *u != want {
+			t.Errorf("%s: GenerateWithUsage() usage = %+v, want %+v", tt.name, u, want)
+		}
+	}
+}
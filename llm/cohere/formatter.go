@@ -1,9 +1,15 @@
 package cohere
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
 	"github.com/henomis/cohere-go/model"
 	"github.com/henomis/cohere-go/request"
 
+	"github.com/maksymenkoml/lingoose/loader"
 	"github.com/maksymenkoml/lingoose/thread"
 )
 
@@ -11,51 +17,254 @@ var threadRoleToCohereRole = map[thread.Role]model.ChatMessageRole{
 	thread.RoleSystem:    model.ChatMessageRoleChatbot,
 	thread.RoleUser:      model.ChatMessageRoleUser,
 	thread.RoleAssistant: model.ChatMessageRoleChatbot,
+	thread.RoleTool:      model.ChatMessageRoleTool,
 }
 
-func (c *Cohere) buildChatCompletionRequest(t *thread.Thread) *request.Chat {
-	message, history := threadToChatMessages(t)
+var jsonSchemaTypeToCohereType = map[string]string{
+	"string":  "str",
+	"integer": "int",
+	"number":  "float",
+	"boolean": "bool",
+	"array":   "list",
+	"object":  "dict",
+}
+
+func (c *Cohere) buildChatCompletionRequest(ctx context.Context, t *thread.Thread) (*request.Chat, error) {
+	message, history, toolResults, err := threadToChatMessages(ctx, t)
+	if err != nil {
+		return nil, err
+	}
 
 	return &request.Chat{
 		Model:       c.model,
 		ChatHistory: history,
 		Message:     message,
+		Tools:       c.getChatCompletionRequestTools(),
+		ToolResults: toolResults,
+	}, nil
+}
+
+func (c *Cohere) getChatCompletionRequestTools() []model.Tool {
+	var tools []model.Tool
+
+	for _, function := range c.tools.Functions() {
+		tools = append(tools, model.Tool{
+			Name:                 function.Name,
+			Description:          function.Description,
+			ParameterDefinitions: jsonSchemaToParameterDefinitions(function.Parameters),
+		})
 	}
+
+	return tools
 }
 
-func threadToChatMessages(t *thread.Thread) (string, []model.ChatMessage) {
-	var history []model.ChatMessage
+func jsonSchemaToParameterDefinitions(schema map[string]interface{}) map[string]model.ToolParameterDefinition {
+	properties, ok := schema["properties"].(map[string]interface{})
+	if !ok || len(properties) == 0 {
+		return nil
+	}
+
+	required := make(map[string]bool)
+	if requiredProperties, isList := schema["required"].([]interface{}); isList {
+		for _, name := range requiredProperties {
+			if nameAsString, isString := name.(string); isString {
+				required[nameAsString] = true
+			}
+		}
+	}
+
+	parameterDefinitions := make(map[string]model.ToolParameterDefinition)
+	for name, property := range properties {
+		propertyAsMap, _ := property.(map[string]interface{})
+		propertyType, _ := propertyAsMap["type"].(string)
+		description, _ := propertyAsMap["description"].(string)
+
+		cohereType, found := jsonSchemaTypeToCohereType[propertyType]
+		if !found {
+			cohereType = "str"
+		}
+
+		parameterDefinitions[name] = model.ToolParameterDefinition{
+			Description: description,
+			Type:        cohereType,
+			Required:    required[name],
+		}
+	}
+
+	return parameterDefinitions
+}
+
+// threadToChatMessages returns the message to send, the chat history and, when the
+// thread ends with tool responses, the tool results of the current tool use step. It
+// fails if the text of a file cannot be extracted, or with thread.ErrUnsupportedContent
+// if a message holds audio.
+//
+//nolint:gocognit
+func threadToChatMessages(
+	ctx context.Context,
+	t *thread.Thread,
+) (string, []model.ChatMessage, []model.ToolResult, error) {
+	toolCalls := threadToolCalls(t)
+
+	// trailing tool messages are sent as tool results of the current step
+	toolResultsStart := len(t.Messages)
+	for toolResultsStart > 0 && t.Messages[toolResultsStart-1].Role == thread.RoleTool {
+		toolResultsStart--
+	}
+
 	var message string
+	var toolResults []model.ToolResult
+	historyEnd := len(t.Messages)
 
-	for _, m := range t.Messages {
+	if toolResultsStart < len(t.Messages) {
+		for _, m := range t.Messages[toolResultsStart:] {
+			toolResults = append(toolResults, threadMessageToToolResults(m, toolCalls)...)
+		}
+		historyEnd = toolResultsStart
+	} else if lastMessage := t.LastMessage(); lastMessage.Role == thread.RoleUser {
+		for _, content := range lastMessage.Contents {
+			text, err := contentToText(ctx, content)
+			if err != nil {
+				return "", nil, nil, err
+			}
+			message += text
+		}
+		historyEnd--
+	}
+
+	var history []model.ChatMessage
+	for _, m := range t.Messages[:historyEnd] {
 		chatMessage := model.ChatMessage{
 			Role: threadRoleToCohereRole[m.Role],
 		}
 
-		switch m.Role {
-		case thread.RoleUser, thread.RoleSystem, thread.RoleAssistant:
-			for _, content := range m.Contents {
-				if content.Type == thread.ContentTypeText {
-					chatMessage.Message += content.Data.(string) + "\n"
+		for _, content := range m.Contents {
+			if content.Type == thread.ContentTypeToolCall {
+				for _, toolCallData := range content.AsToolCallData() {
+					chatMessage.ToolCalls = append(chatMessage.ToolCalls, toolCallDataToToolCall(toolCallData))
 				}
+				continue
 			}
-		case thread.RoleTool:
-			continue
+
+			text, err := contentToText(ctx, content)
+			if err != nil {
+				return "", nil, nil, err
+			}
+			chatMessage.Message += text
+		}
+
+		if m.Role == thread.RoleTool {
+			chatMessage.ToolResults = threadMessageToToolResults(m, toolCalls)
 		}
 
 		history = append(history, chatMessage)
 	}
 
-	lastMessage := t.LastMessage()
-	if lastMessage.Role == thread.RoleUser {
-		for _, content := range lastMessage.Contents {
-			if content.Type == thread.ContentTypeText {
-				message += content.Data.(string) + "\n"
+	return message, history, toolResults, nil
+}
+
+// contentToText returns the text of a content sent as chat message. Documents are not
+// accepted natively by the chat API, the text extracted from files is sent instead, see
+// loader.ContentFileText. Images and tool responses have no text.
+func contentToText(ctx context.Context, c *thread.Content) (string, error) {
+	switch c.Type {
+	case thread.ContentTypeText:
+		return c.AsString() + "\n", nil
+	case thread.ContentTypeFile:
+		text, err := loader.ContentFileText(ctx, c)
+		if err != nil {
+			return "", err
+		}
+
+		return "File " + c.AsFileData().Name + ":\n" + text + "\n", nil
+	case thread.ContentTypeAudio:
+		return "", fmt.Errorf("%w: audio input", thread.ErrUnsupportedContent)
+	case thread.ContentTypeImage, thread.ContentTypeToolCall, thread.ContentTypeToolResponse:
+		return "", nil
+	}
+
+	return "", nil
+}
+
+func threadToolCalls(t *thread.Thread) map[string]thread.ToolCallData {
+	toolCalls := make(map[string]thread.ToolCallData)
+	for _, m := range t.Messages {
+		for _, content := range m.Contents {
+			for _, toolCallData := range content.AsToolCallData() {
+				toolCalls[toolCallData.ID] = toolCallData
 			}
 		}
+	}
+
+	return toolCalls
+}
+
+func threadMessageToToolResults(m *thread.Message, toolCalls map[string]thread.ToolCallData) []model.ToolResult {
+	var toolResults []model.ToolResult
+	for _, content := range m.Contents {
+		toolResponseData := content.AsToolResponseData()
+		if toolResponseData == nil {
+			continue
+		}
+
+		toolCallData, ok := toolCalls[toolResponseData.ID]
+		if !ok {
+			toolCallData = thread.ToolCallData{Name: toolResponseData.Name}
+		}
+
+		// Cohere expects tool outputs as a list of objects
+		var output interface{}
+		err := json.Unmarshal([]byte(toolResponseData.Result), &output)
+		if _, isObject := output.(map[string]interface{}); err != nil || !isObject {
+			output = map[string]interface{}{"result": toolResponseData.Result}
+		}
+
+		toolResults = append(toolResults, model.ToolResult{
+			Call:    toolCallDataToToolCall(toolCallData),
+			Outputs: []interface{}{output},
+		})
+	}
+
+	return toolResults
+}
+
+func toolCallDataToToolCall(toolCallData thread.ToolCallData) model.ToolCall {
+	parameters := make(map[string]interface{})
+	_ = json.Unmarshal([]byte(toolCallData.Arguments), &parameters)
+
+	return model.ToolCall{
+		ID:         toolCallData.ID,
+		Name:       toolCallData.Name,
+		Parameters: parameters,
+	}
+}
+
+func toolCallsToToolCallMessage(toolCalls []model.ToolCall) *thread.Message {
+	if len(toolCalls) == 0 {
+		return nil
+	}
+
+	var toolCallData []thread.ToolCallData
+	for _, toolCall := range toolCalls {
+		arguments, err := json.Marshal(toolCall.Parameters)
+		if err != nil || toolCall.Parameters == nil {
+			arguments = []byte("{}")
+		}
+
+		// the chat API in use does not always assign an ID to tool calls
+		id := toolCall.ID
+		if id == "" {
+			id = uuid.NewString()
+		}
 
-		history = history[:len(history)-1]
+		toolCallData = append(toolCallData, thread.ToolCallData{
+			ID:        id,
+			Name:      toolCall.Name,
+			Arguments: string(arguments),
+		})
 	}
 
-	return message, history
+	return thread.NewAssistantMessage().AddContent(
+		thread.NewToolCallContent(toolCallData),
+	)
 }
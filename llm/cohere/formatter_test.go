@@ -0,0 +1,122 @@
+package cohere
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/maksymenkoml/lingoose/thread"
+	"github.com/maksymenkoml/lingoose/tool"
+)
+
+func Test_threadToChatMessages(t *testing.T) {
+	ctx := context.Background()
+
+	th := thread.New().AddMessage(
+		thread.NewUserMessage().AddContent(thread.NewTextContent("Sum 1 and 2.")),
+	).AddMessage(
+		thread.NewAssistantMessage().AddContent(thread.NewToolCallContent([]thread.ToolCallData{
+			{ID: "sum_1", Name: "sum", Arguments: `{"a":1,"b":2}`},
+		})),
+	).AddMessage(
+		tool.NewToolResponseMessage(thread.ToolCallData{ID: "sum_1", Name: "sum"}, "3"),
+	).AddMessage(
+		thread.NewAssistantMessage().AddContent(thread.NewTextContent("The sum is 3.")),
+	).AddMessage(
+		thread.NewUserMessage().AddContent(thread.NewTextContent("Now sum 3 and 4.")),
+	).AddMessage(
+		thread.NewAssistantMessage().AddContent(thread.NewToolCallContent([]thread.ToolCallData{
+			{ID: "sum_2", Name: "sum", Arguments: `{"a":3,"b":4}`},
+		})),
+	).AddMessage(
+		tool.NewToolResponseMessage(thread.ToolCallData{ID: "sum_2", Name: "sum"}, `{"sum":7}`),
+	)
+
+	message, history, toolResults, err := threadToChatMessages(ctx, th)
+	if err != nil {
+		t.Fatalf("threadToChatMessages() error = %v", err)
+	}
+
+	// the trailing tool responses are the tool results of the current step
+	if message != "" {
+		t.Errorf("threadToChatMessages() message = %q, want none", message)
+	}
+	if len(toolResults) != 1 || toolResults[0].Call.Name != "sum" || toolResults[0].Call.Parameters["a"] != float64(3) {
+		t.Fatalf("threadToChatMessages() tool results = %+v, want the result of sum_2", toolResults)
+	}
+	if output, _ := toolResults[0].Outputs[0].(map[string]interface{}); output["sum"] != float64(7) {
+		t.Errorf("threadToChatMessages() tool result outputs = %+v, want the JSON object", toolResults[0].Outputs)
+	}
+
+	// the previous tool use steps are kept in the history
+	if len(history) != 6 {
+		t.Fatalf("threadToChatMessages() history = %+v, want 6 messages", history)
+	}
+	if got := history[1].ToolCalls; len(got) != 1 || got[0].Name != "sum" || got[0].Parameters["b"] != float64(2) {
+		t.Errorf("threadToChatMessages() history tool calls = %+v, want sum_1", got)
+	}
+	got := history[2]
+	if got.Role != "TOOL" || len(got.ToolResults) != 1 || got.ToolResults[0].Call.Parameters["a"] != float64(1) {
+		t.Errorf("threadToChatMessages() history tool message = %+v, want the result of sum_1", got)
+	}
+	if output, _ := got.ToolResults[0].Outputs[0].(map[string]interface{}); output["result"] != "3" {
+		t.Errorf("threadToChatMessages() history tool result outputs = %+v, want the wrapped result", got.ToolResults[0].Outputs)
+	}
+	if history[4].Message != "Now sum 3 and 4.\n" {
+		t.Errorf("threadToChatMessages() history = %+v, want the last user message", history[4])
+	}
+
+	// a thread ending with a user message sends it as message
+	th = thread.New().AddMessage(thread.NewUserMessage().AddContent(thread.NewTextContent("Hello")))
+	message, history, toolResults, err = threadToChatMessages(ctx, th)
+	if err != nil {
+		t.Fatalf("threadToChatMessages() error = %v", err)
+	}
+	if message != "Hello\n" || len(history) != 0 || len(toolResults) != 0 {
+		t.Errorf("threadToChatMessages() = %q, %+v, %+v, want the message only", message, history, toolResults)
+	}
+}
+
+func Test_threadToChatMessages_Files(t *testing.T) {
+	ctx := context.Background()
+
+	file := thread.NewFileContent("notes.txt", []byte("some notes"), "")
+	th := thread.New().AddMessage(
+		thread.NewUserMessage().AddContent(file),
+	).AddMessage(
+		thread.NewAssistantMessage().AddContent(thread.NewTextContent("Noted.")),
+	).AddMessage(
+		thread.NewUserMessage().AddContent(thread.NewTextContent("Summarize them.")),
+	)
+
+	message, history, _, err := threadToChatMessages(ctx, th)
+	if err != nil {
+		t.Fatalf("threadToChatMessages() error = %v", err)
+	}
+	if message != "Summarize them.\n" {
+		t.Errorf("threadToChatMessages() message = %q, want %q", message, "Summarize them.\n")
+	}
+	if len(history) != 2 || history[0].Message != "File notes.txt:\nsome notes\n" {
+		t.Errorf("threadToChatMessages() history = %+v, want the text of the file", history)
+	}
+
+	// the thread is not modified by the formatter
+	if got := file.AsFileData().Text; got != "" {
+		t.Errorf("content text = %q, want the content not modified", got)
+	}
+
+	// the failure of the conversion is not dropped
+	th = thread.New().AddMessage(thread.NewUserMessage().AddContent(
+		thread.NewFileContent("report.pdf", []byte("not a pdf"), ""),
+	))
+	if _, _, _, err = threadToChatMessages(ctx, th); err == nil {
+		t.Errorf("threadToChatMessages() error = nil, want the conversion error")
+	}
+
+	th = thread.New().AddMessage(thread.NewUserMessage().AddContent(
+		thread.NewAudioContent([]byte("RIFF"), "audio/wav"),
+	))
+	if _, _, _, err = threadToChatMessages(ctx, th); !errors.Is(err, thread.ErrUnsupportedContent) {
+		t.Errorf("threadToChatMessages() error = %v, want %v", err, thread.ErrUnsupportedContent)
+	}
+}
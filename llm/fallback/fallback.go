@@ -0,0 +1,165 @@
+// Package fallback composes LLMs. The threads are routed to a list of LLMs according
+// to the first matching rule, and each LLM of the list is tried in order until one of
+// them does not fail with a transient error like a rate limit, a server error or a
+// timeout.
+package fallback
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/maksymenkoml/lingoose/llm/apierror"
+	"github.com/maksymenkoml/lingoose/thread"
+)
+
+var (
+	ErrFallback = errors.New("fallback error")
+)
+
+type LLM interface {
+	Generate(context.Context, *thread.Thread) error
+}
+
+// MatchFn reports whether a thread must be routed to the LLMs of a rule.
+type MatchFn func(*thread.Thread) bool
+
+// ShouldFallbackFn reports whether the next LLM must be tried after an error.
+type ShouldFallbackFn func(error) bool
+
+type route struct {
+	matchFn MatchFn
+	llms    []LLM
+}
+
+type Fallback struct {
+	llms             []LLM
+	routes           []route
+	shouldFallbackFn ShouldFallbackFn
+	timeout          time.Duration
+}
+
+// New creates a fallback LLM trying the LLMs in order.
+func New(llms ...LLM) *Fallback {
+	return &Fallback{
+		llms:             llms,
+		shouldFallbackFn: apierror.IsTransient,
+	}
+}
+
+// WithRoute adds a routing rule. The threads matched by matchFn are generated by the
+// LLMs of the rule, tried in order, instead of the default ones. Rules are evaluated in
+// the order they are added.
+func (f *Fallback) WithRoute(matchFn MatchFn, llms ...LLM) *Fallback {
+	f.routes = append(f.routes, route{
+		matchFn: matchFn,
+		llms:    llms,
+	})
+	return f
+}
+
+// WithShouldFallback sets the function deciding whether to try the next LLM after an
+// error. By default only transient errors, see apierror.IsTransient, fall back.
+func (f *Fallback) WithShouldFallback(shouldFallbackFn ShouldFallbackFn) *Fallback {
+	f.shouldFallbackFn = shouldFallbackFn
+	return f
+}
+
+// WithTimeout sets the timeout of each attempt. A timed out attempt falls back to the
+// next LLM.
+func (f *Fallback) WithTimeout(timeout time.Duration) *Fallback {
+	f.timeout = timeout
+	return f
+}
+
+// Generate generates the next messages of the thread with the first LLM that does not
+// fail. Each attempt works on a copy of the thread, so failed attempts leave the
+// thread untouched.
+func (f *Fallback) Generate(ctx context.Context, t *thread.Thread) error {
+	if t == nil {
+		return nil
+	}
+
+	llms := f.route(t)
+	if len(llms) == 0 {
+		return fmt.Errorf("%w: no LLM available", ErrFallback)
+	}
+
+	var errs []error
+	for _, llm := range llms {
+		attempt := thread.New().AddMessages(t.Messages...)
+
+		err := f.generate(ctx, llm, attempt)
+		if err == nil {
+			t.AddMessages(attempt.Messages[len(t.Messages):]...)
+			return nil
+		}
+
+		errs = append(errs, err)
+
+		if ctx.Err() != nil || !f.shouldFallbackFn(err) {
+			break
+		}
+	}
+
+	return fmt.Errorf("%w: %w", ErrFallback, errors.Join(errs...))
+}
+
+func (f *Fallback) route(t *thread.Thread) []LLM {
+	for _, r := range f.routes {
+		if r.matchFn(t) {
+			return r.llms
+		}
+	}
+
+	return f.llms
+}
+
+func (f *Fallback) generate(ctx context.Context, llm LLM, t *thread.Thread) error {
+	if f.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, f.timeout)
+		defer cancel()
+	}
+
+	return llm.Generate(ctx, t)
+}
+
+// HasContentType matches the threads holding a content of the given type, e.g. to
+// route the threads with images to a vision model.
+func HasContentType(contentType thread.ContentType) MatchFn {
+	return func(t *thread.Thread) bool {
+		for _, message := range t.Messages {
+			for _, content := range message.Contents {
+				if content.Type == contentType {
+					return true
+				}
+			}
+		}
+
+		return false
+	}
+}
+
+// HasImages matches the threads holding an image.
+func HasImages() MatchFn {
+	return HasContentType(thread.ContentTypeImage)
+}
+
+// ExceedsTokens matches the threads longer than maxTokens, e.g. to route them to a
+// large context model. Tokens are counted with countFn, or estimated when it is nil.
+func ExceedsTokens(maxTokens int, countFn thread.TokenCountFn) MatchFn {
+	if countFn == nil {
+		countFn = thread.EstimateTokens
+	}
+
+	return func(t *thread.Thread) bool {
+		tokens := 0
+		for _, message := range t.Messages {
+			tokens += countFn(message)
+		}
+
+		return tokens > maxTokens
+	}
+}
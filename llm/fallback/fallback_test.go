@@ -0,0 +1,132 @@
+package fallback
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/maksymenkoml/lingoose/llm/apierror"
+	"github.com/maksymenkoml/lingoose/thread"
+)
+
+type fakeLLM struct {
+	name  string
+	err   error
+	delay time.Duration
+	calls int
+}
+
+func (l *fakeLLM) Generate(ctx context.Context, t *thread.Thread) error {
+	l.calls++
+
+	// failed attempts must not leak into the thread
+	t.AddMessage(thread.NewAssistantMessage().AddContent(thread.NewTextContent("partial")))
+
+	if l.delay > 0 {
+		select {
+		case <-time.After(l.delay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	if l.err != nil {
+		return l.err
+	}
+
+	t.Messages = t.Messages[:len(t.Messages)-1]
+	t.AddMessage(thread.NewAssistantMessage().AddContent(thread.NewTextContent(l.name)))
+
+	return nil
+}
+
+func newThread() *thread.Thread {
+	return thread.New().AddMessage(thread.NewUserMessage().AddContent(thread.NewTextContent("hi")))
+}
+
+func TestFallback_Generate(t *testing.T) {
+	rateLimited := &fakeLLM{name: "rate limited", err: apierror.New(http.StatusTooManyRequests, nil)}
+	slow := &fakeLLM{name: "slow", delay: time.Second}
+	working := &fakeLLM{name: "working"}
+
+	th := newThread()
+	err := New(rateLimited, slow, working).WithTimeout(10*time.Millisecond).Generate(context.Background(), th)
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	if th.CountMessages() != 2 || th.LastMessage().Contents[0].AsString() != "working" {
+		t.Errorf("thread = %v, want the working reply only", th)
+	}
+	if rateLimited.calls != 1 || slow.calls != 1 || working.calls != 1 {
+		t.Errorf("calls = %d, %d, %d, want 1 each", rateLimited.calls, slow.calls, working.calls)
+	}
+}
+
+func TestFallback_Generate_Errors(t *testing.T) {
+	badRequest := &fakeLLM{err: apierror.New(http.StatusBadRequest, []byte("invalid request"))}
+	unavailable := &fakeLLM{err: apierror.New(http.StatusServiceUnavailable, nil)}
+	working := &fakeLLM{name: "working"}
+
+	th := newThread()
+	err := New(badRequest, working).Generate(context.Background(), th)
+	if !errors.Is(err, ErrFallback) || !strings.Contains(err.Error(), "invalid request") {
+		t.Errorf("Generate() error = %v, want the bad request error", err)
+	}
+	if working.calls != 0 {
+		t.Errorf("non transient errors must not fall back")
+	}
+
+	err = New(unavailable, unavailable).Generate(context.Background(), th)
+	if code, ok := apierror.StatusCode(err); !ok || code != http.StatusServiceUnavailable {
+		t.Errorf("Generate() error = %v, want the service unavailable error", err)
+	}
+
+	if th.CountMessages() != 1 {
+		t.Errorf("failed attempts modified the thread: %v", th)
+	}
+}
+
+func TestFallback_Route(t *testing.T) {
+	text := &fakeLLM{name: "text"}
+	vision := &fakeLLM{name: "vision"}
+	large := &fakeLLM{name: "large"}
+
+	f := New(text).
+		WithRoute(HasImages(), vision).
+		WithRoute(ExceedsTokens(100, nil), large)
+
+	tests := []struct {
+		name   string
+		thread *thread.Thread
+		want   string
+	}{
+		{name: "default", thread: newThread(), want: "text"},
+		{
+			name: "image",
+			thread: thread.New().AddMessage(thread.NewUserMessage().AddContent(
+				thread.NewImageContentFromURL("https://example.com/image.png"),
+			)),
+			want: "vision",
+		},
+		{
+			name: "long",
+			thread: thread.New().AddMessage(thread.NewUserMessage().AddContent(
+				thread.NewTextContent(strings.Repeat("long thread ", 100)),
+			)),
+			want: "large",
+		},
+	}
+	for _, tt := range tests {
+		err := f.Generate(context.Background(), tt.thread)
+		if err != nil {
+			t.Fatalf("%s: Generate() error = %v", tt.name, err)
+		}
+		if got := tt.thread.LastMessage().Contents[0].AsString(); got != tt.want {
+			t.Errorf("%s: routed to %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
@@ -4,19 +4,35 @@ import (
 	"bytes"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
 	"os"
 	"strings"
 
 	"github.com/henomis/restclientgo"
+
+	"github.com/maksymenkoml/lingoose/thread"
 )
 
 type request struct {
-	Model    string    `json:"model"`
-	Messages []message `json:"messages"`
-	Stream   bool      `json:"stream"`
-	Options  options   `json:"options"`
+	Model    string           `json:"model"`
+	Messages []message        `json:"messages"`
+	Stream   bool             `json:"stream"`
+	Options  options          `json:"options"`
+	Tools    []toolDefinition `json:"tools,omitempty"`
+	Format   map[string]any   `json:"format,omitempty"`
+}
+
+type toolDefinition struct {
+	Type     string             `json:"type"`
+	Function functionDefinition `json:"function"`
+}
+
+type functionDefinition struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	Parameters  map[string]any `json:"parameters"`
 }
 
 func (r *request) Path() (string, error) {
@@ -43,13 +59,17 @@ type response[T any] struct {
 	CreatedAt         string `json:"created_at"`
 	Message           T      `json:"message"`
 	Done              bool   `json:"done"`
+	PromptEvalCount   int    `json:"prompt_eval_count"`
+	EvalCount         int    `json:"eval_count"`
 	streamCallbackFn  restclientgo.StreamCallback
+	retryAfter        string
 	RawBody           []byte `json:"-"`
 }
 
 type assistantMessage struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role      string     `json:"role"`
+	Content   string     `json:"content"`
+	ToolCalls []toolCall `json:"tool_calls,omitempty"`
 }
 
 func (r *response[T]) SetAcceptContentType(contentType string) {
@@ -77,7 +97,10 @@ func (r *response[T]) SetStatusCode(code int) error {
 	return nil
 }
 
-func (r *response[T]) SetHeaders(_ restclientgo.Headers) error { return nil }
+func (r *response[T]) SetHeaders(headers restclientgo.Headers) error {
+	r.retryAfter = http.Header(headers).Get(retryAfterHeader)
+	return nil
+}
 
 func (r *response[T]) SetStreamCallback(fn restclientgo.StreamCallback) {
 	r.streamCallbackFn = fn
@@ -88,15 +111,42 @@ func (r *response[T]) StreamCallback() restclientgo.StreamCallback {
 }
 
 type message struct {
-	Role    string   `json:"role"`
-	Content string   `json:"content,omitempty"`
-	Images  []string `json:"images,omitempty"`
+	Role      string     `json:"role"`
+	Content   string     `json:"content,omitempty"`
+	Images    []string   `json:"images,omitempty"`
+	ToolCalls []toolCall `json:"tool_calls,omitempty"`
+	ToolName  string     `json:"tool_name,omitempty"`
+}
+
+type toolCall struct {
+	ID       string           `json:"id,omitempty"`
+	Function toolCallFunction `json:"function"`
+}
+
+type toolCallFunction struct {
+	Name      string          `json:"name"`
+	Arguments json.RawMessage `json:"arguments"`
 }
 
 type options struct {
 	Temperature float64 `json:"temperature"`
 }
 
+// getContentImageDataAsBase64 returns the base64 data of an image content, either
+// embedded or referenced by URL or file path.
+func getContentImageDataAsBase64(c *thread.Content) (string, error) {
+	if imageData := c.AsImageData(); imageData != nil {
+		return imageData.Base64(), nil
+	}
+
+	imageURL, ok := c.Data.(string)
+	if !ok {
+		return "", errors.New("invalid image content")
+	}
+
+	return getImageDataAsBase64(imageURL)
+}
+
 func getImageDataAsBase64(imageURL string) (string, error) {
 	var imageData []byte
 	var err error
@@ -1,53 +1,147 @@
 package ollama
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"github.com/maksymenkoml/lingoose/loader"
 	"github.com/maksymenkoml/lingoose/thread"
 )
 
-func (o *Ollama) buildChatCompletionRequest(t *thread.Thread) *request {
+const (
+	emptyToolArguments = "{}"
+)
+
+func (o *Ollama) buildChatCompletionRequest(ctx context.Context, t *thread.Thread) (*request, error) {
+	messages, err := threadToChatMessages(ctx, t)
+	if err != nil {
+		return nil, err
+	}
+
 	return &request{
 		Model:    o.model,
-		Messages: threadToChatMessages(t),
+		Messages: messages,
 		Options: options{
 			Temperature: o.temperature,
 		},
+		Tools: o.getChatCompletionRequestTools(),
+	}, nil
+}
+
+func (o *Ollama) getChatCompletionRequestTools() []toolDefinition {
+	var tools []toolDefinition
+
+	for _, function := range o.tools.Functions() {
+		tools = append(tools, toolDefinition{
+			Type: "function",
+			Function: functionDefinition{
+				Name:        function.Name,
+				Description: function.Description,
+				Parameters:  function.Parameters,
+			},
+		})
 	}
+
+	return tools
 }
 
+// threadToChatMessages converts the thread messages, one per content. It fails if the
+// text of a file cannot be extracted, or with thread.ErrUnsupportedContent if a message
+// holds audio.
+//
 //nolint:gocognit
-func threadToChatMessages(t *thread.Thread) []message {
+func threadToChatMessages(ctx context.Context, t *thread.Thread) ([]message, error) {
 	var chatMessages []message
 	for _, m := range t.Messages {
-		switch m.Role {
-		case thread.RoleUser, thread.RoleSystem, thread.RoleAssistant:
-			for _, content := range m.Contents {
-				chatMessage := message{
-					Role: threadRoleToOllamaRole[m.Role],
-				}
+		for _, content := range m.Contents {
+			chatMessage := message{
+				Role: threadRoleToOllamaRole[m.Role],
+			}
 
+			switch content.Type {
+			case thread.ContentTypeText:
 				contentData, ok := content.Data.(string)
 				if !ok {
 					continue
 				}
-
-				if content.Type == thread.ContentTypeText {
-					chatMessage.Content = contentData
-				} else if content.Type == thread.ContentTypeImage {
-					imageData, err := getImageDataAsBase64(contentData)
-					if err != nil {
-						continue
-					}
-					chatMessage.Images = []string{imageData}
-				} else {
+				chatMessage.Content = contentData
+			case thread.ContentTypeImage:
+				imageData, err := getContentImageDataAsBase64(content)
+				if err != nil {
 					continue
 				}
+				chatMessage.Images = append(chatMessage.Images, imageData)
+			case thread.ContentTypeToolCall:
+				for _, toolCallData := range content.AsToolCallData() {
+					arguments := toolCallData.Arguments
+					if !json.Valid([]byte(arguments)) {
+						arguments = emptyToolArguments
+					}
 
-				chatMessages = append(chatMessages, chatMessage)
+					chatMessage.ToolCalls = append(chatMessage.ToolCalls, toolCall{
+						ID: toolCallData.ID,
+						Function: toolCallFunction{
+							Name:      toolCallData.Name,
+							Arguments: json.RawMessage(arguments),
+						},
+					})
+				}
+			case thread.ContentTypeToolResponse:
+				toolResponseData := content.AsToolResponseData()
+				if toolResponseData == nil {
+					continue
+				}
+				chatMessage.Content = toolResponseData.Result
+				chatMessage.ToolName = toolResponseData.Name
+			case thread.ContentTypeFile:
+				// documents are not accepted natively, their text is sent instead
+				text, err := loader.ContentFileText(ctx, content)
+				if err != nil {
+					return nil, err
+				}
+				chatMessage.Content = "File " + content.AsFileData().Name + ":\n" + text
+			case thread.ContentTypeAudio:
+				return nil, fmt.Errorf("%w: audio input", thread.ErrUnsupportedContent)
+			default:
+				continue
 			}
-		case thread.RoleTool:
-			continue
+
+			chatMessages = append(chatMessages, chatMessage)
 		}
 	}
 
-	return chatMessages
+	return chatMessages, nil
+}
+
+func toolCallsToToolCallMessage(toolCalls []toolCall) *thread.Message {
+	if len(toolCalls) == 0 {
+		return nil
+	}
+
+	var toolCallData []thread.ToolCallData
+	for _, tc := range toolCalls {
+		// older Ollama versions do not assign an ID to tool calls
+		id := tc.ID
+		if id == "" {
+			id = uuid.NewString()
+		}
+
+		arguments := string(tc.Function.Arguments)
+		if arguments == "" || arguments == "null" {
+			arguments = emptyToolArguments
+		}
+
+		toolCallData = append(toolCallData, thread.ToolCallData{
+			ID:        id,
+			Name:      tc.Function.Name,
+			Arguments: arguments,
+		})
+	}
+
+	return thread.NewAssistantMessage().AddContent(
+		thread.NewToolCallContent(toolCallData),
+	)
 }
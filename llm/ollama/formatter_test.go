@@ -0,0 +1,127 @@
+package ollama
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/maksymenkoml/lingoose/thread"
+	"github.com/maksymenkoml/lingoose/tool"
+)
+
+func Test_threadToChatMessages(t *testing.T) {
+	th := thread.New().AddMessage(
+		thread.NewUserMessage().AddContent(thread.NewTextContent("Sum 1 and 2.")),
+	).AddMessage(
+		thread.NewAssistantMessage().AddContent(thread.NewToolCallContent([]thread.ToolCallData{
+			{ID: "call_1", Name: "sum", Arguments: `{"a":1,"b":2}`},
+			{ID: "call_2", Name: "sum", Arguments: `not json`},
+		})),
+	).AddMessage(
+		tool.NewToolResponseMessage(thread.ToolCallData{ID: "call_1", Name: "sum"}, "3"),
+	)
+
+	got, err := threadToChatMessages(context.Background(), th)
+	if err != nil {
+		t.Fatalf("threadToChatMessages() error = %v", err)
+	}
+
+	want := []message{
+		{Role: "user", Content: "Sum 1 and 2."},
+		{Role: "assistant", ToolCalls: []toolCall{
+			{ID: "call_1", Function: toolCallFunction{Name: "sum", Arguments: json.RawMessage(`{"a":1,"b":2}`)}},
+			{ID: "call_2", Function: toolCallFunction{Name: "sum", Arguments: json.RawMessage(`{}`)}},
+		}},
+		{Role: "tool", Content: "3", ToolName: "sum"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("threadToChatMessages() = %+v, want %+v", got, want)
+	}
+}
+
+func Test_toolCallsToToolCallMessage(t *testing.T) {
+	if got := toolCallsToToolCallMessage(nil); got != nil {
+		t.Errorf("toolCallsToToolCallMessage(nil) = %+v, want nil", got)
+	}
+
+	m := toolCallsToToolCallMessage([]toolCall{
+		{ID: "call_1", Function: toolCallFunction{Name: "sum", Arguments: json.RawMessage(`{"a":1,"b":2}`)}},
+		{Function: toolCallFunction{Name: "now", Arguments: json.RawMessage(`null`)}},
+	})
+	if m.Role != thread.RoleAssistant {
+		t.Errorf("toolCallsToToolCallMessage() role = %s, want %s", m.Role, thread.RoleAssistant)
+	}
+
+	got := m.Contents[0].AsToolCallData()
+	if len(got) != 2 {
+		t.Fatalf("toolCallsToToolCallMessage() = %+v, want 2 tool calls", got)
+	}
+	if want := (thread.ToolCallData{ID: "call_1", Name: "sum", Arguments: `{"a":1,"b":2}`}); got[0] != want {
+		t.Errorf("toolCallsToToolCallMessage() = %+v, want %+v", got[0], want)
+	}
+
+	// older Ollama versions do not assign an ID to tool calls
+	if got[1].ID == "" || got[1].Name != "now" || got[1].Arguments != emptyToolArguments {
+		t.Errorf("toolCallsToToolCallMessage() = %+v, want an ID and empty arguments", got[1])
+	}
+}
+
+func Test_threadToChatMessages_Files(t *testing.T) {
+	ctx := context.Background()
+
+	cached := &thread.Content{
+		Type: thread.ContentTypeFile,
+		Data: thread.FileData{Name: "report.pdf", MIMEType: "application/pdf", Data: []byte("not a pdf"), Text: "report"},
+	}
+
+	tests := []struct {
+		name    string
+		content *thread.Content
+		want    string
+		wantErr error
+	}{
+		{
+			name:    "text file",
+			content: thread.NewFileContent("notes.txt", []byte("some notes"), ""),
+			want:    "File notes.txt:\nsome notes",
+		},
+		{
+			name:    "cached text",
+			content: cached,
+			want:    "File report.pdf:\nreport",
+		},
+		{
+			name:    "audio",
+			content: thread.NewAudioContent([]byte("RIFF"), "audio/wav"),
+			wantErr: thread.ErrUnsupportedContent,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			th := thread.New().AddMessage(thread.NewUserMessage().AddContent(tt.content))
+
+			got, err := threadToChatMessages(ctx, th)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("threadToChatMessages() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+
+			if len(got) != 1 || got[0].Content != tt.want {
+				t.Errorf("threadToChatMessages() = %+v, want content %q", got, tt.want)
+			}
+		})
+	}
+
+	// the failure of the conversion is not dropped
+	th := thread.New().AddMessage(thread.NewUserMessage().AddContent(
+		thread.NewFileContent("report.pdf", []byte("not a pdf"), ""),
+	))
+	if _, err := threadToChatMessages(ctx, th); err == nil {
+		t.Errorf("threadToChatMessages() error = nil, want the conversion error")
+	}
+}
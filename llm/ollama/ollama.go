@@ -10,17 +10,23 @@ import (
 
 	"github.com/henomis/restclientgo"
 
+	"github.com/maksymenkoml/lingoose/event"
+	"github.com/maksymenkoml/lingoose/llm/apierror"
 	"github.com/maksymenkoml/lingoose/llm/cache"
 	llmobserver "github.com/maksymenkoml/lingoose/llm/observer"
+	"github.com/maksymenkoml/lingoose/llm/retry"
 	"github.com/maksymenkoml/lingoose/observer"
 	"github.com/maksymenkoml/lingoose/thread"
+	"github.com/maksymenkoml/lingoose/tool"
 	"github.com/maksymenkoml/lingoose/types"
+	"github.com/maksymenkoml/lingoose/usage"
 )
 
 const (
 	defaultModel      = "llama2"
 	ndjsonContentType = "application/x-ndjson"
 	jsonContentType   = "application/json"
+	retryAfterHeader  = "Retry-After"
 	defaultEndpoint   = "http://localhost:11434/api"
 )
 
@@ -32,6 +38,7 @@ var threadRoleToOllamaRole = map[thread.Role]string{
 	thread.RoleSystem:    "system",
 	thread.RoleUser:      "user",
 	thread.RoleAssistant: "assistant",
+	thread.RoleTool:      "tool",
 }
 
 type StreamCallbackFn func(string)
@@ -42,6 +49,10 @@ type Ollama struct {
 	restClient       *restclientgo.RestClient
 	streamCallbackFn StreamCallbackFn
 	cache            *cache.Cache
+	window           thread.WindowFn
+	tools            *tool.Registry
+	toolsErr         error
+	retry            *retry.Policy
 	name             string
 }
 
@@ -49,6 +60,8 @@ func New() *Ollama {
 	return &Ollama{
 		restClient: restclientgo.New(defaultEndpoint),
 		model:      defaultModel,
+		tools:      tool.NewRegistry(),
+		retry:      retry.New(),
 		name:       "ollama",
 	}
 }
@@ -58,6 +71,13 @@ func (o *Ollama) WithEndpoint(endpoint string) *Ollama {
 	return o
 }
 
+// WithRetry sets the policy retrying the requests failing with a transient error. A nil
+// policy disables the retries.
+func (o *Ollama) WithRetry(policy *retry.Policy) *Ollama {
+	o.retry = policy
+	return o
+}
+
 func (o *Ollama) WithModel(model string) *Ollama {
 	o.model = model
 	return o
@@ -78,6 +98,43 @@ func (o *Ollama) WithTemperature(temperature float64) *Ollama {
 	return o
 }
 
+// WithWindow sets the window function used to select the thread messages sent to the model.
+func (o *Ollama) WithWindow(window thread.WindowFn) *Ollama {
+	o.window = window
+	return o
+}
+
+// WithTools adds the tools to the Ollama instance. The model must support tool calling.
+// If a tool is invalid, Generate fails with its error.
+func (o *Ollama) WithTools(tools ...tool.Tool) *Ollama {
+	err := o.tools.AddTools(tools...)
+	if err != nil && o.toolsErr == nil {
+		o.toolsErr = err
+	}
+
+	return o
+}
+
+// WithToolRegistry sets the registry holding the tools available to the Ollama instance.
+// A nil registry is an empty one.
+func (o *Ollama) WithToolRegistry(registry *tool.Registry) *Ollama {
+	if registry == nil {
+		registry = tool.NewRegistry()
+	}
+
+	o.tools = registry
+	return o
+}
+
+func (o *Ollama) BindFunction(
+	fn interface{},
+	name string,
+	description string,
+	functionParameterOptions ...tool.FunctionParameterOption,
+) error {
+	return o.tools.Bind(fn, name, description, functionParameterOptions...)
+}
+
 func (o *Ollama) getCache(ctx context.Context, t *thread.Thread) (*cache.Result, error) {
 	messages := t.UserQuery()
 	cacheQuery := strings.Join(messages, "\n")
@@ -119,8 +176,42 @@ func (o *Ollama) setCache(ctx context.Context, t *thread.Thread, cacheResult *ca
 }
 
 func (o *Ollama) Generate(ctx context.Context, t *thread.Thread) error {
+	_, err := o.generateChat(ctx, t, nil)
+	return err
+}
+
+// GenerateWithUsage generates the next messages of the thread and returns the tokens
+// used, in streaming mode too. The usage is nil when the answer comes from the cache.
+func (o *Ollama) GenerateWithUsage(ctx context.Context, t *thread.Thread) (*usage.Usage, error) {
+	return o.generateChat(ctx, t, nil)
+}
+
+// GenerateWithJSONSchema generates the next message of the thread constraining its
+// content to a JSON object matching the schema. The name is ignored by Ollama.
+func (o *Ollama) GenerateWithJSONSchema(
+	ctx context.Context,
+	t *thread.Thread,
+	name string,
+	schema map[string]interface{},
+) error {
+	_ = name
+	_, err := o.generateChat(ctx, t, schema)
+	return err
+}
+
+// generateChat generates the next messages of the thread, constraining the response
+// to the format schema when it is not nil.
+func (o *Ollama) generateChat(
+	ctx context.Context,
+	t *thread.Thread,
+	format map[string]interface{},
+) (*usage.Usage, error) {
 	if t == nil {
-		return nil
+		return nil, nil
+	}
+
+	if o.toolsErr != nil {
+		return nil, fmt.Errorf("%w: %w", ErrOllamaChat, o.toolsErr)
 	}
 
 	var err error
@@ -128,69 +219,97 @@ func (o *Ollama) Generate(ctx context.Context, t *thread.Thread) error {
 	if o.cache != nil {
 		cacheResult, err = o.getCache(ctx, t)
 		if err == nil {
-			return nil
+			return nil, nil
 		} else if !errors.Is(err, cache.ErrCacheMiss) {
-			return fmt.Errorf("%w: %w", ErrOllamaChat, err)
+			return nil, fmt.Errorf("%w: %w", ErrOllamaChat, err)
 		}
 	}
 
-	chatRequest := o.buildChatCompletionRequest(t)
+	chatRequest, err := o.buildChatCompletionRequest(ctx, t.Window(o.window))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrOllamaChat, err)
+	}
+	chatRequest.Format = format
 
 	generation, err := o.startObserveGeneration(ctx, t)
 	if err != nil {
-		return fmt.Errorf("%w: %w", ErrOllamaChat, err)
+		return nil, fmt.Errorf("%w: %w", ErrOllamaChat, err)
 	}
 
+	nMessageBeforeGeneration := len(t.Messages)
+
+	var u usage.Usage
 	if o.streamCallbackFn != nil {
-		err = o.stream(ctx, t, chatRequest)
+		u, err = o.stream(ctx, t, chatRequest)
 	} else {
-		err = o.generate(ctx, t, chatRequest)
+		u, err = o.generate(ctx, t, chatRequest)
 	}
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	err = o.stopObserveGeneration(ctx, generation, []*thread.Message{t.LastMessage()})
+	usage.Record(ctx, u)
+
+	err = o.stopObserveGeneration(ctx, generation, t.Messages[nMessageBeforeGeneration:])
 	if err != nil {
-		return fmt.Errorf("%w: %w", ErrOllamaChat, err)
+		return nil, fmt.Errorf("%w: %w", ErrOllamaChat, err)
 	}
 
 	if o.cache != nil {
 		err = o.setCache(ctx, t, cacheResult)
 		if err != nil {
-			return fmt.Errorf("%w: %w", ErrOllamaChat, err)
+			return nil, fmt.Errorf("%w: %w", ErrOllamaChat, err)
 		}
 	}
 
-	return nil
+	return &u, nil
 }
 
-func (o *Ollama) generate(ctx context.Context, t *thread.Thread, chatRequest *request) error {
+func (o *Ollama) generate(ctx context.Context, t *thread.Thread, chatRequest *request) (usage.Usage, error) {
 	var resp response[assistantMessage]
 
-	err := o.restClient.Post(
-		ctx,
-		chatRequest,
-		&resp,
-	)
+	err := o.retry.Do(ctx, func(ctx context.Context) error {
+		resp = response[assistantMessage]{}
+		return post(ctx, o.restClient, chatRequest, &resp)
+	})
 	if err != nil {
-		return fmt.Errorf("%w: %w", ErrOllamaChat, err)
+		return usage.Usage{}, fmt.Errorf("%w: %w", ErrOllamaChat, err)
 	}
 
-	if resp.HTTPStatusCode >= http.StatusBadRequest {
-		return fmt.Errorf("%w: %s", ErrOllamaChat, resp.RawBody)
+	t.AddMessages(o.responseMessages(ctx, resp.Message.Content, resp.Message.ToolCalls)...)
+
+	return o.usage(resp.PromptEvalCount, resp.EvalCount), nil
+}
+
+// responseMessages returns the assistant text message, followed by the tool call
+// message and the tool responses when the model requested tool calls.
+func (o *Ollama) responseMessages(ctx context.Context, content string, toolCalls []toolCall) []*thread.Message {
+	textMessage := thread.NewAssistantMessage().AddContent(
+		thread.NewTextContent(content),
+	)
+
+	toolCallMessage := toolCallsToToolCallMessage(toolCalls)
+	if toolCallMessage == nil {
+		return []*thread.Message{textMessage}
 	}
 
-	t.AddMessage(thread.NewAssistantMessage().AddContent(
-		thread.NewTextContent(resp.Message.Content),
-	))
+	var messages []*thread.Message
+	if content != "" {
+		messages = append(messages, textMessage)
+	}
 
-	return nil
+	messages = append(messages, toolCallMessage)
+	messages = append(messages, o.tools.CallTools(ctx, toolCallMessage.Contents[0].AsToolCallData())...)
+
+	return messages
 }
 
-func (o *Ollama) stream(ctx context.Context, t *thread.Thread, chatRequest *request) error {
+func (o *Ollama) stream(ctx context.Context, t *thread.Thread, chatRequest *request) (usage.Usage, error) {
 	var resp response[message]
 	var assistantMessage string
+	var toolCalls []toolCall
+	var promptEvalCount, evalCount int
+	var delivered bool
 
 	resp.SetAcceptContentType(ndjsonContentType)
 	resp.SetStreamCallback(
@@ -202,8 +321,19 @@ func (o *Ollama) stream(ctx context.Context, t *thread.Thread, chatRequest *requ
 				return err
 			}
 
+			// the last response holds the tokens evaluated by the whole generation
+			if streamResponse.Done {
+				promptEvalCount = streamResponse.PromptEvalCount
+				evalCount = streamResponse.EvalCount
+			}
+
 			assistantMessage += streamResponse.Message.Content
+			toolCalls = append(toolCalls, streamResponse.Message.ToolCalls...)
+			delivered = true
 			o.streamCallbackFn(streamResponse.Message.Content)
+			if streamResponse.Message.Content != "" {
+				event.Emit(ctx, event.NewTextDelta(streamResponse.Message.Content))
+			}
 
 			return nil
 		},
@@ -211,23 +341,59 @@ func (o *Ollama) stream(ctx context.Context, t *thread.Thread, chatRequest *requ
 
 	chatRequest.Stream = true
 
-	err := o.restClient.Post(
+	err := o.retry.Do(ctx, func(ctx context.Context) error {
+		// a retried stream starts over, unless its deltas have already been delivered
+		assistantMessage = ""
+		toolCalls = nil
+		promptEvalCount, evalCount = 0, 0
+
+		err := post(ctx, o.restClient, chatRequest, &resp)
+		if err != nil && delivered {
+			return retry.Permanent(err)
+		}
+
+		return err
+	})
+	if err != nil {
+		return usage.Usage{}, fmt.Errorf("%w: %w", ErrOllamaChat, err)
+	}
+
+	t.AddMessages(o.responseMessages(ctx, assistantMessage, toolCalls)...)
+
+	return o.usage(promptEvalCount, evalCount), nil
+}
+
+// usage returns the usage of a generation from the prompt and the completion tokens
+// evaluated by Ollama.
+func (o *Ollama) usage(promptEvalCount, evalCount int) usage.Usage {
+	return usage.Usage{
+		Provider:         o.name,
+		Model:            o.model,
+		PromptTokens:     promptEvalCount,
+		CompletionTokens: evalCount,
+	}
+}
+
+// post sends the request and returns the error response as an apierror.Error.
+func post[T any](
+	ctx context.Context,
+	restClient *restclientgo.RestClient,
+	chatRequest *request,
+	resp *response[T],
+) error {
+	err := restClient.Post(
 		ctx,
 		chatRequest,
-		&resp,
+		resp,
 	)
 	if err != nil {
-		return fmt.Errorf("%w: %w", ErrOllamaChat, err)
+		return err
 	}
 
 	if resp.HTTPStatusCode >= http.StatusBadRequest {
-		return fmt.Errorf("%w: %s", ErrOllamaChat, resp.RawBody)
+		return apierror.New(resp.HTTPStatusCode, resp.RawBody).WithRetryAfter(resp.retryAfter)
 	}
 
-	t.AddMessage(thread.NewAssistantMessage().AddContent(
-		thread.NewTextContent(assistantMessage),
-	))
-
 	return nil
 }
 
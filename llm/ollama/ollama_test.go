@@ -0,0 +1,158 @@
+package ollama
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/maksymenkoml/lingoose/llm/apierror"
+	"github.com/maksymenkoml/lingoose/llm/retry"
+	"github.com/maksymenkoml/lingoose/thread"
+	"github.com/maksymenkoml/lingoose/usage"
+)
+
+func TestOllama_Generate_Retry(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		calls++
+		switch calls {
+		case 1:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case 2:
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+		default:
+			w.Header().Set("Content-Type", jsonContentType)
+			_, _ = w.Write([]byte(`{"message":{"role":"assistant","content":"hello"},"done":true}`))
+		}
+	}))
+	defer server.Close()
+
+	llm := New().
+		WithEndpoint(server.URL).
+		WithRetry(retry.New().WithBackoff(time.Millisecond, time.Millisecond))
+
+	th := thread.New().AddMessage(thread.NewUserMessage().AddContent(thread.NewTextContent("hi")))
+	err := llm.Generate(context.Background(), th)
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	if calls != 3 {
+		t.Errorf("calls = %d, want 3", calls)
+	}
+	if th.CountMessages() != 2 || th.LastMessage().Contents[0].AsString() != "hello" {
+		t.Errorf("thread = %v, want the reply only", th)
+	}
+}
+
+func TestOllama_Generate_NoRetry(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	llm := New().WithEndpoint(server.URL).WithRetry(nil)
+
+	th := thread.New().AddMessage(thread.NewUserMessage().AddContent(thread.NewTextContent("hi")))
+	err := llm.Generate(context.Background(), th)
+	if code, ok := apierror.StatusCode(err); !ok || code != http.StatusServiceUnavailable {
+		t.Errorf("Generate() error = %v, want the service unavailable error", err)
+	}
+	if calls != 1 || th.CountMessages() != 1 {
+		t.Errorf("calls = %d, thread = %v, want a single failed call", calls, th)
+	}
+}
+
+func TestOllama_Generate_StreamRetry(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		calls++
+		switch calls {
+		case 1:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case 2:
+			w.Header().Set("Content-Type", ndjsonContentType)
+			_, _ = w.Write([]byte(`{"message":{"role":"assistant","content":"hel"},"done":false}` + "\n" +
+				`{"message":` + "\n"))
+		default:
+			w.Header().Set("Content-Type", ndjsonContentType)
+			_, _ = w.Write([]byte(`{"message":{"role":"assistant","content":"hello"},"done":true}` + "\n"))
+		}
+	}))
+	defer server.Close()
+
+	var deltas []string
+	llm := New().
+		WithEndpoint(server.URL).
+		WithStream(func(delta string) { deltas = append(deltas, delta) }).
+		WithRetry(retry.New().WithBackoff(time.Millisecond, time.Millisecond).WithRetryable(func(error) bool { return true }))
+
+	// the stream failing before any delta is retried, the one failing after is not
+	th := thread.New().AddMessage(thread.NewUserMessage().AddContent(thread.NewTextContent("hi")))
+	err := llm.Generate(context.Background(), th)
+	if err == nil {
+		t.Fatalf("Generate() error = nil, want the stream error")
+	}
+
+	if calls != 2 {
+		t.Errorf("calls = %d, want 2", calls)
+	}
+	if len(deltas) != 1 || deltas[0] != "hel" || th.CountMessages() != 1 {
+		t.Errorf("deltas = %q, thread = %v, want the delivered delta only", deltas, th)
+	}
+}
+
+func TestOllama_GenerateWithUsage(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req request
+		_ = json.NewDecoder(r.Body).Decode(&req)
+
+		if !req.Stream {
+			w.Header().Set("Content-Type", jsonContentType)
+			_, _ = w.Write([]byte(`{"message":{"role":"assistant","content":"hello"},"done":true,` +
+				`"prompt_eval_count":10,"eval_count":2}`))
+			return
+		}
+
+		w.Header().Set("Content-Type", ndjsonContentType)
+		_, _ = w.Write([]byte(`{"message":{"role":"assistant","content":"hel"},"done":false}` + "\n" +
+			`{"message":{"role":"assistant","content":"lo"},"done":false}` + "\n" +
+			`{"message":{"role":"assistant","content":""},"done":true,"prompt_eval_count":10,"eval_count":2}` + "\n"))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name string
+		llm  *Ollama
+	}{
+		{name: "generate", llm: New().WithEndpoint(server.URL)},
+		{name: "stream", llm: New().WithEndpoint(server.URL).WithStream(func(string) {})},
+	}
+	for _, tt := range tests {
+		tracker := usage.NewTracker()
+		ctx := usage.ContextWithTracker(context.Background(), tracker)
+
+		th := thread.New().AddMessage(thread.NewUserMessage().AddContent(thread.NewTextContent("hi")))
+		u, err := tt.llm.GenerateWithUsage(ctx, th)
+		if err != nil {
+			t.Fatalf("%s: GenerateWithUsage() error = %v", tt.name, err)
+		}
+
+		want := usage.Usage{Provider: "ollama", Model: defaultModel, PromptTokens: 10, CompletionTokens: 2}
+		if u == nil || *u != want {
+			t.Errorf("%s: GenerateWithUsage() usage = %+v, want %+v", tt.name, u, want)
+		}
+		if got := tracker.Total(); got != want {
+			t.Errorf("%s: recorded usage = %+v, want %+v", tt.name, got, want)
+		}
+		if got := th.LastMessage().Contents[0].AsString(); got != "hello" {
+			t.Errorf("%s: reply = %q, want hello", tt.name, got)
+		}
+	}
+}
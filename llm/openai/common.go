@@ -1,7 +1,9 @@
 package openai
 
 import (
+	"encoding/json"
 	"fmt"
+	"sort"
 
 	"github.com/sashabaranov/go-openai"
 
@@ -65,3 +67,62 @@ const (
 	ResponseFormatJSONObject ResponseFormat = openai.ChatCompletionResponseFormatTypeJSONObject
 	ResponseFormatText       ResponseFormat = openai.ChatCompletionResponseFormatTypeText
 )
+
+// jsonSchema is a JSON schema that can be used as response format schema.
+type jsonSchema map[string]interface{}
+
+func (s jsonSchema) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]interface{}(s))
+}
+
+// strictJSONSchema returns a copy of the schema accepted by the strict mode of the
+// json_schema response format: every object forbids additional properties and requires
+// all its properties. The optional pointer fields accept null, see tool.JSONSchema.
+func strictJSONSchema(schema map[string]interface{}) map[string]interface{} {
+	strict := make(map[string]interface{}, len(schema))
+	for key, value := range schema {
+		strict[key] = value
+	}
+
+	if properties, ok := schema["properties"].(map[string]interface{}); ok {
+		strictProperties := make(map[string]interface{}, len(properties))
+		required := make([]interface{}, 0, len(properties))
+		for name, property := range properties {
+			strictProperties[name] = strictSubschema(property)
+			required = append(required, name)
+		}
+		sort.Slice(required, func(i, j int) bool {
+			return required[i].(string) < required[j].(string)
+		})
+
+		strict["properties"] = strictProperties
+		strict["required"] = required
+		strict["additionalProperties"] = false
+	}
+
+	for _, key := range []string{"items", "additionalProperties"} {
+		if subschema, ok := strict[key].(map[string]interface{}); ok {
+			strict[key] = strictJSONSchema(subschema)
+		}
+	}
+
+	for _, key := range []string{"anyOf", "oneOf", "allOf"} {
+		if subschemas, ok := strict[key].([]interface{}); ok {
+			strictSubschemas := make([]interface{}, 0, len(subschemas))
+			for _, subschema := range subschemas {
+				strictSubschemas = append(strictSubschemas, strictSubschema(subschema))
+			}
+			strict[key] = strictSubschemas
+		}
+	}
+
+	return strict
+}
+
+func strictSubschema(schema interface{}) interface{} {
+	if schemaAsMap, ok := schema.(map[string]interface{}); ok {
+		return strictJSONSchema(schemaAsMap)
+	}
+
+	return schema
+}
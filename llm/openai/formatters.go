@@ -1,13 +1,26 @@
 package openai
 
 import (
+	"context"
+	"fmt"
+
 	"github.com/sashabaranov/go-openai"
 
+	"github.com/maksymenkoml/lingoose/loader"
 	"github.com/maksymenkoml/lingoose/thread"
 )
 
+var (
+	// the chat completion client has no input_audio message part
+	errUnsupportedAudio = fmt.Errorf("%w: audio input", thread.ErrUnsupportedContent)
+)
+
+// threadToChatCompletionMessages converts the thread messages. It fails if the text of
+// a file cannot be extracted, or with thread.ErrUnsupportedContent if a message holds
+// audio, which the API client cannot encode.
+//
 //nolint:gocognit
-func threadToChatCompletionMessages(t *thread.Thread) []openai.ChatCompletionMessage {
+func threadToChatCompletionMessages(ctx context.Context, t *thread.Thread) ([]openai.ChatCompletionMessage, error) {
 	chatCompletionMessages := make([]openai.ChatCompletionMessage, len(t.Messages))
 	for i, message := range t.Messages {
 		chatCompletionMessages[i] = openai.ChatCompletionMessage{
@@ -15,7 +28,12 @@ func threadToChatCompletionMessages(t *thread.Thread) []openai.ChatCompletionMes
 		}
 
 		if len(message.Contents) > 1 {
-			chatCompletionMessages[i].MultiContent = threadContentsToChatMessageParts(message)
+			multiContent, err := threadContentsToChatMessageParts(ctx, message)
+			if err != nil {
+				return nil, err
+			}
+
+			chatCompletionMessages[i].MultiContent = multiContent
 			continue
 		}
 
@@ -23,6 +41,15 @@ func threadToChatCompletionMessages(t *thread.Thread) []openai.ChatCompletionMes
 		case thread.RoleUser, thread.RoleSystem:
 			if data, isUserTextData := message.Contents[0].Data.(string); isUserTextData {
 				chatCompletionMessages[i].Content = data
+			} else if message.Contents[0].Type == thread.ContentTypeFile {
+				text, err := fileContentToText(ctx, message.Contents[0])
+				if err != nil {
+					return nil, err
+				}
+
+				chatCompletionMessages[i].Content = text
+			} else if message.Contents[0].Type == thread.ContentTypeAudio {
+				return nil, errUnsupportedAudio
 			} else {
 				continue
 			}
@@ -56,10 +83,10 @@ func threadToChatCompletionMessages(t *thread.Thread) []openai.ChatCompletionMes
 		}
 	}
 
-	return chatCompletionMessages
+	return chatCompletionMessages, nil
 }
 
-func threadContentsToChatMessageParts(m *thread.Message) []openai.ChatMessagePart {
+func threadContentsToChatMessageParts(ctx context.Context, m *thread.Message) ([]openai.ChatMessagePart, error) {
 	chatMessageParts := make([]openai.ChatMessagePart, len(m.Contents))
 
 	for i, content := range m.Contents {
@@ -78,6 +105,9 @@ func threadContentsToChatMessageParts(m *thread.Message) []openai.ChatMessagePar
 			}
 		case thread.ContentTypeImage:
 			contentAsString, ok := content.Data.(string)
+			if imageData := content.AsImageData(); imageData != nil {
+				contentAsString, ok = imageData.DataURI(), true
+			}
 			if !ok {
 				continue
 			}
@@ -89,6 +119,18 @@ func threadContentsToChatMessageParts(m *thread.Message) []openai.ChatMessagePar
 					Detail: openai.ImageURLDetailAuto,
 				},
 			}
+		case thread.ContentTypeFile:
+			text, err := fileContentToText(ctx, content)
+			if err != nil {
+				return nil, err
+			}
+
+			chatMessagePart = &openai.ChatMessagePart{
+				Type: openai.ChatMessagePartTypeText,
+				Text: text,
+			}
+		case thread.ContentTypeAudio:
+			return nil, errUnsupportedAudio
 		case thread.ContentTypeToolCall, thread.ContentTypeToolResponse:
 			continue
 		default:
@@ -98,19 +140,18 @@ func threadContentsToChatMessageParts(m *thread.Message) []openai.ChatMessagePar
 		chatMessageParts[i] = *chatMessagePart
 	}
 
-	return chatMessageParts
+	return chatMessageParts, nil
 }
 
-func toolCallResultToThreadMessage(toolCall openai.ToolCall, result string) *thread.Message {
-	return thread.NewToolMessage().AddContent(
-		thread.NewToolResponseContent(
-			thread.ToolResponseData{
-				ID:     toolCall.ID,
-				Name:   toolCall.Function.Name,
-				Result: result,
-			},
-		),
-	)
+// fileContentToText returns the text extracted from a file content, since documents
+// are not accepted natively by the chat completion API, see loader.ContentFileText.
+func fileContentToText(ctx context.Context, c *thread.Content) (string, error) {
+	text, err := loader.ContentFileText(ctx, c)
+	if err != nil {
+		return "", err
+	}
+
+	return "File " + c.AsFileData().Name + ":\n" + text, nil
 }
 
 func toolCallsToToolCallMessage(toolCalls []openai.ToolCall) *thread.Message {
@@ -118,6 +159,14 @@ func toolCallsToToolCallMessage(toolCalls []openai.ToolCall) *thread.Message {
 		return nil
 	}
 
+	return thread.NewAssistantMessage().AddContent(
+		thread.NewToolCallContent(
+			toolCallsToToolCallData(toolCalls),
+		),
+	)
+}
+
+func toolCallsToToolCallData(toolCalls []openai.ToolCall) []thread.ToolCallData {
 	var toolCallData []thread.ToolCallData
 	for _, toolCall := range toolCalls {
 		toolCallData = append(toolCallData, thread.ToolCallData{
@@ -127,9 +176,5 @@ func toolCallsToToolCallMessage(toolCalls []openai.ToolCall) *thread.Message {
 		})
 	}
 
-	return thread.NewAssistantMessage().AddContent(
-		thread.NewToolCallContent(
-			toolCallData,
-		),
-	)
+	return toolCallData
 }
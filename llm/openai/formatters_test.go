@@ -0,0 +1,108 @@
+package openai
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/maksymenkoml/lingoose/loader"
+	"github.com/maksymenkoml/lingoose/thread"
+)
+
+func Test_fileContentToText(t *testing.T) {
+	ctx := context.Background()
+
+	// the content is not modified by the formatter
+	content := thread.NewFileContent("notes.txt", []byte("some notes"), "text/plain")
+	got, err := fileContentToText(ctx, content)
+	if err != nil {
+		t.Fatalf("fileContentToText() error = %v", err)
+	}
+	if want := "File notes.txt:\nsome notes"; got != want {
+		t.Errorf("fileContentToText() = %q, want %q", got, want)
+	}
+	if got := content.AsFileData().Text; got != "" {
+		t.Errorf("content text = %q, want the content not modified", got)
+	}
+
+	// the text set by loader.ExtractFiles is used without converting the file again
+	th := thread.New().AddMessage(thread.NewUserMessage().AddContent(content))
+	if err = loader.ExtractFiles(ctx, th); err != nil {
+		t.Fatalf("ExtractFiles() error = %v", err)
+	}
+	if got := content.AsFileData().Text; got != "some notes" {
+		t.Errorf("extracted text = %q, want %q", got, "some notes")
+	}
+
+	content = &thread.Content{
+		Type: thread.ContentTypeFile,
+		Data: thread.FileData{Name: "report.pdf", MIMEType: "application/pdf", Data: []byte("not a pdf"), Text: "report"},
+	}
+	got, err = fileContentToText(ctx, content)
+	if err != nil {
+		t.Fatalf("fileContentToText() error = %v", err)
+	}
+	if want := "File report.pdf:\nreport"; got != want {
+		t.Errorf("fileContentToText() = %q, want %q", got, want)
+	}
+
+	// the failure of the conversion is not dropped
+	content = thread.NewFileContent("report.pdf", []byte("not a pdf"), "")
+	if _, err = fileContentToText(ctx, content); err == nil {
+		t.Errorf("fileContentToText() error = nil, want the conversion error")
+	}
+}
+
+func Test_threadToChatCompletionMessages(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		contents []*thread.Content
+		wantErr  error
+	}{
+		{
+			name:     "audio",
+			contents: []*thread.Content{thread.NewAudioContent([]byte("RIFF"), "audio/wav")},
+			wantErr:  thread.ErrUnsupportedContent,
+		},
+		{
+			name: "audio with text",
+			contents: []*thread.Content{
+				thread.NewTextContent("transcribe this"),
+				thread.NewAudioContent([]byte("RIFF"), "audio/wav"),
+			},
+			wantErr: thread.ErrUnsupportedContent,
+		},
+		{
+			name: "file with text",
+			contents: []*thread.Content{
+				thread.NewTextContent("summarize this"),
+				thread.NewFileContent("notes.txt", []byte("some notes"), ""),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := thread.NewUserMessage()
+			for _, content := range tt.contents {
+				m.AddContent(content)
+			}
+			th := thread.New().AddMessage(m)
+
+			messages, err := threadToChatCompletionMessages(ctx, th)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("threadToChatCompletionMessages() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+
+			parts := messages[0].MultiContent
+			if len(parts) != 2 || parts[1].Text != "File notes.txt:\nsome notes" {
+				t.Errorf("threadToChatCompletionMessages() parts = %+v, want the text of the file", parts)
+			}
+		})
+	}
+}
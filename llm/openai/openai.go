@@ -11,12 +11,16 @@ import (
 	"github.com/mitchellh/mapstructure"
 	"github.com/sashabaranov/go-openai"
 
+	"github.com/maksymenkoml/lingoose/event"
 	"github.com/maksymenkoml/lingoose/llm/cache"
 	llmobserver "github.com/maksymenkoml/lingoose/llm/observer"
+	"github.com/maksymenkoml/lingoose/llm/retry"
 	"github.com/maksymenkoml/lingoose/observer"
 	"github.com/maksymenkoml/lingoose/thread"
+	"github.com/maksymenkoml/lingoose/tool"
 	"github.com/maksymenkoml/lingoose/tool/llm_with_usage"
 	"github.com/maksymenkoml/lingoose/types"
+	"github.com/maksymenkoml/lingoose/usage"
 )
 
 const (
@@ -39,11 +43,14 @@ type OpenAI struct {
 	reasoningEffort     string
 	stop                []string
 	usageCallback       UsageCallback
-	functions           map[string]Function
+	tools               *tool.Registry
+	toolsErr            error
 	streamCallbackFn    StreamCallback
 	responseFormat      *ResponseFormat
 	toolChoice          *string
 	cache               *cache.Cache
+	window              thread.WindowFn
+	retry               *retry.Policy
 	Name                string
 }
 
@@ -95,6 +102,13 @@ func (o *OpenAI) WithClient(client *openai.Client) *OpenAI {
 	return o
 }
 
+// WithRetry sets the policy retrying the requests failing with a transient error. A nil
+// policy disables the retries.
+func (o *OpenAI) WithRetry(policy *retry.Policy) *OpenAI {
+	o.retry = policy
+	return o
+}
+
 func (o *OpenAI) WithToolChoice(toolChoice *string) *OpenAI {
 	o.toolChoice = toolChoice
 	return o
@@ -102,7 +116,21 @@ func (o *OpenAI) WithToolChoice(toolChoice *string) *OpenAI {
 
 // WithFunctions sets the functions to use for the OpenAI instance.
 func (o *OpenAI) WithFunctions(functions map[string]Function) *OpenAI {
-	o.functions = functions
+	o.tools.Clear()
+	for _, function := range functions {
+		o.tools.Add(function)
+	}
+	return o
+}
+
+// WithToolRegistry sets the registry holding the tools available to the OpenAI instance.
+// A nil registry is an empty one.
+func (o *OpenAI) WithToolRegistry(registry *tool.Registry) *OpenAI {
+	if registry == nil {
+		registry = tool.NewRegistry()
+	}
+
+	o.tools = registry
 	return o
 }
 
@@ -121,6 +149,12 @@ func (o *OpenAI) WithCache(cache *cache.Cache) *OpenAI {
 	return o
 }
 
+// WithWindow sets the window function used to select the thread messages sent to the model.
+func (o *OpenAI) WithWindow(window thread.WindowFn) *OpenAI {
+	o.window = window
+	return o
+}
+
 func (o *OpenAI) WithResponseFormat(responseFormat ResponseFormat) *OpenAI {
 	o.responseFormat = &responseFormat
 	return o
@@ -148,7 +182,8 @@ func New() *OpenAI {
 	return &OpenAI{
 		openAIClient: openai.NewClient(openAIKey),
 		model:        GPT3Dot5Turbo,
-		functions:    make(map[string]Function),
+		tools:        tool.NewRegistry(),
+		retry:        retry.New(),
 		Name:         "openai",
 	}
 }
@@ -194,10 +229,44 @@ func (o *OpenAI) setCache(ctx context.Context, t *thread.Thread, cacheResult *ca
 }
 
 func (o *OpenAI) Generate(ctx context.Context, t *thread.Thread) error {
+	return o.generateChat(ctx, t, nil)
+}
+
+// GenerateWithJSONSchema generates the next message of the thread using the json_schema
+// response format in strict mode, so that the message content is a JSON object matching
+// the schema. In strict mode every property is required, only the pointer fields may be
+// null.
+func (o *OpenAI) GenerateWithJSONSchema(
+	ctx context.Context,
+	t *thread.Thread,
+	name string,
+	schema map[string]interface{},
+) error {
+	return o.generateChat(ctx, t, &openai.ChatCompletionResponseFormat{
+		Type: openai.ChatCompletionResponseFormatTypeJSONSchema,
+		JSONSchema: &openai.ChatCompletionResponseFormatJSONSchema{
+			Name:   name,
+			Schema: jsonSchema(strictJSONSchema(schema)),
+			Strict: true,
+		},
+	})
+}
+
+// generateChat generates the next messages of the thread. If responseFormat is not nil
+// it overrides the response format of the OpenAI instance.
+func (o *OpenAI) generateChat(
+	ctx context.Context,
+	t *thread.Thread,
+	responseFormat *openai.ChatCompletionResponseFormat,
+) error {
 	if t == nil {
 		return nil
 	}
 
+	if o.toolsErr != nil {
+		return fmt.Errorf("%w: %w", ErrOpenAIChat, o.toolsErr)
+	}
+
 	var err error
 	var cacheResult *cache.Result
 	if o.cache != nil {
@@ -209,9 +278,16 @@ func (o *OpenAI) Generate(ctx context.Context, t *thread.Thread) error {
 		}
 	}
 
-	chatCompletionRequest := o.buildChatCompletionRequest(t)
+	chatCompletionRequest, err := o.buildChatCompletionRequest(ctx, t.Window(o.window))
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrOpenAIChat, err)
+	}
+
+	if responseFormat != nil {
+		chatCompletionRequest.ResponseFormat = responseFormat
+	}
 
-	if len(o.functions) > 0 {
+	if o.tools.Len() > 0 {
 		chatCompletionRequest.Tools = o.getChatCompletionRequestTools()
 		chatCompletionRequest.ToolChoice = o.getChatCompletionRequestToolChoice()
 	}
@@ -224,7 +300,9 @@ func (o *OpenAI) Generate(ctx context.Context, t *thread.Thread) error {
 	nMessageBeforeGeneration := len(t.Messages)
 
 	if o.streamCallbackFn != nil {
-		err = o.stream(ctx, t, chatCompletionRequest)
+		// the usage is requested only when it is consumed
+		includeUsage := o.usageCallback != nil || usage.ContextValueTracker(ctx) != nil
+		_, err = o.stream(ctx, t, chatCompletionRequest, includeUsage)
 	} else {
 		err = o.generate(ctx, t, chatCompletionRequest)
 	}
@@ -252,6 +330,10 @@ func (o *OpenAI) GenerateWithUsage(ctx context.Context, t *thread.Thread) (*llm_
 		return nil, nil
 	}
 
+	if o.toolsErr != nil {
+		return nil, fmt.Errorf("%w: %w", ErrOpenAIChat, o.toolsErr)
+	}
+
 	var err error
 	var cacheResult *cache.Result
 	if o.cache != nil {
@@ -263,9 +345,12 @@ func (o *OpenAI) GenerateWithUsage(ctx context.Context, t *thread.Thread) (*llm_
 		}
 	}
 
-	chatCompletionRequest := o.buildChatCompletionRequest(t)
+	chatCompletionRequest, err := o.buildChatCompletionRequest(ctx, t.Window(o.window))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrOpenAIChat, err)
+	}
 
-	if len(o.functions) > 0 {
+	if o.tools.Len() > 0 {
 		chatCompletionRequest.Tools = o.getChatCompletionRequestTools()
 		chatCompletionRequest.ToolChoice = o.getChatCompletionRequestToolChoice()
 	}
@@ -279,9 +364,7 @@ func (o *OpenAI) GenerateWithUsage(ctx context.Context, t *thread.Thread) (*llm_
 
 	var usage *llm_with_usage.TokensUsage
 	if o.streamCallbackFn != nil {
-		// Streaming is not supported for GenerateWithUsage yet
-		err = o.stream(ctx, t, chatCompletionRequest)
-		usage = &llm_with_usage.TokensUsage{} // We can't get accurate token counts from streaming
+		usage, err = o.stream(ctx, t, chatCompletionRequest, true)
 	} else {
 		usage, err = o.generateWithUsage(ctx, t, chatCompletionRequest)
 	}
@@ -325,6 +408,7 @@ func handleStreamToolCallResponse(
 }
 
 func (o *OpenAI) handleEndOfStream(
+	ctx context.Context,
 	messages []*thread.Message,
 	content string,
 	currentToolCall *openai.ToolCall,
@@ -338,38 +422,62 @@ func (o *OpenAI) handleEndOfStream(
 	}
 	if currentToolCall.ID != "" {
 		allToolCalls = append(allToolCalls, *currentToolCall)
-		messages = append(messages, toolCallsToToolCallMessage(allToolCalls))
-		messages = append(messages, o.callTools(allToolCalls)...)
+		toolCallMessage := toolCallsToToolCallMessage(allToolCalls)
+		messages = append(messages, toolCallMessage)
+		messages = append(messages, o.callTools(ctx, toolCallMessage)...)
 	}
 	return messages
 }
 
+// stream streams the next messages of the thread. If includeUsage is true the usage is
+// requested with the stream options, and sent by OpenAI in a last chunk without choices;
+// otherwise the returned usage has no tokens.
 func (o *OpenAI) stream(
 	ctx context.Context,
 	t *thread.Thread,
 	chatCompletionRequest openai.ChatCompletionRequest,
-) error {
-	stream, err := o.openAIClient.CreateChatCompletionStream(
-		ctx,
-		chatCompletionRequest,
-	)
+	includeUsage bool,
+) (*llm_with_usage.TokensUsage, error) {
+	if includeUsage {
+		chatCompletionRequest.StreamOptions = &openai.StreamOptions{IncludeUsage: true}
+	}
+
+	var stream *openai.ChatCompletionStream
+	err := o.retry.Do(ctx, func(ctx context.Context) error {
+		var errCreate error
+		stream, errCreate = o.openAIClient.CreateChatCompletionStream(
+			ctx,
+			chatCompletionRequest,
+		)
+		return errCreate
+	})
 	if err != nil {
-		return fmt.Errorf("%w: %w", ErrOpenAIChat, err)
+		return nil, fmt.Errorf("%w: %w", ErrOpenAIChat, err)
 	}
 
 	var content string
 	var messages []*thread.Message
 	var allToolCalls []openai.ToolCall
 	var currentToolCall openai.ToolCall
+	var model string
+	var streamUsage openai.Usage
 	for {
 		response, errRecv := stream.Recv()
 		if errors.Is(errRecv, io.EOF) {
-			messages = o.handleEndOfStream(messages, content, &currentToolCall, allToolCalls)
+			messages = o.handleEndOfStream(ctx, messages, content, &currentToolCall, allToolCalls)
 			break
 		}
 
+		if response.Usage != nil {
+			model = response.Model
+			streamUsage = *response.Usage
+		}
+
 		if len(response.Choices) == 0 {
-			return fmt.Errorf("%w: no choices returned", ErrOpenAIChat)
+			if response.Usage != nil {
+				continue
+			}
+			return nil, fmt.Errorf("%w: no choices returned", ErrOpenAIChat)
 		}
 
 		if isStreamToolCallResponse(&response) {
@@ -385,11 +493,21 @@ func (o *OpenAI) stream(
 		}
 
 		o.streamCallbackFn(response.Choices[0].Delta.Content)
+		if response.Choices[0].Delta.Content != "" {
+			event.Emit(ctx, event.NewTextDelta(response.Choices[0].Delta.Content))
+		}
 	}
 
 	t.AddMessages(messages...)
 
-	return nil
+	if o.usageCallback != nil {
+		o.setUsageMetadata(streamUsage)
+	}
+
+	u := o.usage(model, streamUsage)
+	usage.Record(ctx, *u)
+
+	return u, nil
 }
 
 func (o *OpenAI) generate(
@@ -397,10 +515,15 @@ func (o *OpenAI) generate(
 	t *thread.Thread,
 	chatCompletionRequest openai.ChatCompletionRequest,
 ) error {
-	response, err := o.openAIClient.CreateChatCompletion(
-		ctx,
-		chatCompletionRequest,
-	)
+	var response openai.ChatCompletionResponse
+	err := o.retry.Do(ctx, func(ctx context.Context) error {
+		var errCreate error
+		response, errCreate = o.openAIClient.CreateChatCompletion(
+			ctx,
+			chatCompletionRequest,
+		)
+		return errCreate
+	})
 	if err != nil {
 		return fmt.Errorf("%w: %w", ErrOpenAIChat, err)
 	}
@@ -409,14 +532,17 @@ func (o *OpenAI) generate(
 		o.setUsageMetadata(response.Usage)
 	}
 
+	usage.Record(ctx, *o.usage(response.Model, response.Usage))
+
 	if len(response.Choices) == 0 {
 		return fmt.Errorf("%w: no choices returned", ErrOpenAIChat)
 	}
 
 	var messages []*thread.Message
 	if response.Choices[0].FinishReason == "tool_calls" || len(response.Choices[0].Message.ToolCalls) > 0 {
-		messages = append(messages, toolCallsToToolCallMessage(response.Choices[0].Message.ToolCalls))
-		messages = append(messages, o.callTools(response.Choices[0].Message.ToolCalls)...)
+		toolCallMessage := toolCallsToToolCallMessage(response.Choices[0].Message.ToolCalls)
+		messages = append(messages, toolCallMessage)
+		messages = append(messages, o.callTools(ctx, toolCallMessage)...)
 	} else {
 		messages = []*thread.Message{
 			thread.NewAssistantMessage().AddContent(
@@ -436,10 +562,15 @@ func (o *OpenAI) generateWithUsage(
 	t *thread.Thread,
 	chatCompletionRequest openai.ChatCompletionRequest,
 ) (*llm_with_usage.TokensUsage, error) {
-	response, err := o.openAIClient.CreateChatCompletion(
-		ctx,
-		chatCompletionRequest,
-	)
+	var response openai.ChatCompletionResponse
+	err := o.retry.Do(ctx, func(ctx context.Context) error {
+		var errCreate error
+		response, errCreate = o.openAIClient.CreateChatCompletion(
+			ctx,
+			chatCompletionRequest,
+		)
+		return errCreate
+	})
 	if err != nil {
 		return nil, fmt.Errorf("%w: %w", ErrOpenAIChat, err)
 	}
@@ -448,14 +579,17 @@ func (o *OpenAI) generateWithUsage(
 		o.setUsageMetadata(response.Usage)
 	}
 
+	usage.Record(ctx, *o.usage(response.Model, response.Usage))
+
 	if len(response.Choices) == 0 {
 		return nil, fmt.Errorf("%w: no choices returned", ErrOpenAIChat)
 	}
 
 	var messages []*thread.Message
 	if response.Choices[0].FinishReason == "tool_calls" || len(response.Choices[0].Message.ToolCalls) > 0 {
-		messages = append(messages, toolCallsToToolCallMessage(response.Choices[0].Message.ToolCalls))
-		messages = append(messages, o.callTools(response.Choices[0].Message.ToolCalls)...)
+		toolCallMessage := toolCallsToToolCallMessage(response.Choices[0].Message.ToolCalls)
+		messages = append(messages, toolCallMessage)
+		messages = append(messages, o.callTools(ctx, toolCallMessage)...)
 	} else {
 		messages = []*thread.Message{
 			thread.NewAssistantMessage().AddContent(
@@ -466,23 +600,39 @@ func (o *OpenAI) generateWithUsage(
 
 	t.Messages = append(t.Messages, messages...)
 
-	// Create and return TokensUsage from the response, using the llm_with_usage.TokensUsage type
-	usage := &llm_with_usage.TokensUsage{
-		PromptTokens:     response.Usage.PromptTokens,
-		CompletionTokens: response.Usage.CompletionTokens,
-		AudioTokens:      0,
-		CachedTokens:     0,
+	return o.usage(response.Model, response.Usage), nil
+}
+
+// usage converts the usage of a response.
+func (o *OpenAI) usage(model string, openAIUsage openai.Usage) *llm_with_usage.TokensUsage {
+	if model == "" {
+		model = string(o.model)
 	}
 
-	if response.Usage.PromptTokensDetails != nil {
-		usage.AudioTokens = response.Usage.PromptTokensDetails.AudioTokens
-		usage.CachedTokens = response.Usage.PromptTokensDetails.CachedTokens
+	u := &llm_with_usage.TokensUsage{
+		Provider:         o.Name,
+		Model:            model,
+		PromptTokens:     openAIUsage.PromptTokens,
+		CompletionTokens: openAIUsage.CompletionTokens,
 	}
 
-	return usage, nil
+	if openAIUsage.PromptTokensDetails != nil {
+		u.AudioTokens = openAIUsage.PromptTokensDetails.AudioTokens
+		u.CachedTokens = openAIUsage.PromptTokensDetails.CachedTokens
+	}
+
+	return u
 }
 
-func (o *OpenAI) buildChatCompletionRequest(t *thread.Thread) openai.ChatCompletionRequest {
+func (o *OpenAI) buildChatCompletionRequest(
+	ctx context.Context,
+	t *thread.Thread,
+) (openai.ChatCompletionRequest, error) {
+	messages, err := threadToChatCompletionMessages(ctx, t)
+	if err != nil {
+		return openai.ChatCompletionRequest{}, err
+	}
+
 	var responseFormat *openai.ChatCompletionResponseFormat
 	if o.responseFormat != nil {
 		responseFormat = &openai.ChatCompletionResponseFormat{
@@ -492,7 +642,7 @@ func (o *OpenAI) buildChatCompletionRequest(t *thread.Thread) openai.ChatComplet
 
 	r := openai.ChatCompletionRequest{
 		Model:          string(o.model),
-		Messages:       threadToChatCompletionMessages(t),
+		Messages:       messages,
 		N:              DefaultOpenAINumResults,
 		TopP:           DefaultOpenAITopP,
 		Stop:           o.stop,
@@ -509,13 +659,13 @@ func (o *OpenAI) buildChatCompletionRequest(t *thread.Thread) openai.ChatComplet
 		r.Temperature = o.temperature
 	}
 
-	return r
+	return r, nil
 }
 
 func (o *OpenAI) getChatCompletionRequestTools() []openai.Tool {
 	tools := []openai.Tool{}
 
-	for _, function := range o.functions {
+	for _, function := range o.tools.Functions() {
 		tools = append(tools, openai.Tool{
 			Type: "function",
 			Function: &openai.FunctionDefinition{
@@ -546,36 +696,10 @@ func (o *OpenAI) getChatCompletionRequestToolChoice() any {
 	}
 }
 
-func (o *OpenAI) callTool(toolCall openai.ToolCall) (string, error) {
-	fn, ok := o.functions[toolCall.Function.Name]
-	if !ok {
-		return "", fmt.Errorf("unknown function %s", toolCall.Function.Name)
-	}
-
-	resultAsJSON, err := callFnWithArgumentAsJSON(fn.Fn, toolCall.Function.Arguments)
-	if err != nil {
-		return "", err
-	}
-
-	return resultAsJSON, nil
-}
-
-func (o *OpenAI) callTools(toolCalls []openai.ToolCall) []*thread.Message {
-	if len(o.functions) == 0 || len(toolCalls) == 0 {
-		return nil
-	}
-
-	var messages []*thread.Message
-	for _, toolCall := range toolCalls {
-		result, err := o.callTool(toolCall)
-		if err != nil {
-			result = fmt.Sprintf("error: %s", err)
-		}
-
-		messages = append(messages, toolCallResultToThreadMessage(toolCall, result))
-	}
-
-	return messages
+// callTools executes the tool calls of the message, updating the arguments edited on
+// approval.
+func (o *OpenAI) callTools(ctx context.Context, toolCallMessage *thread.Message) []*thread.Message {
+	return o.tools.CallTools(ctx, toolCallMessage.Contents[0].AsToolCallData())
 }
 
 func (o *OpenAI) startObserveGeneration(ctx context.Context, t *thread.Thread) (*observer.Generation, error) {
@@ -0,0 +1,198 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/sashabaranov/go-openai"
+
+	"github.com/maksymenkoml/lingoose/thread"
+	"github.com/maksymenkoml/lingoose/tool"
+	"github.com/maksymenkoml/lingoose/types"
+	"github.com/maksymenkoml/lingoose/usage"
+)
+
+func TestOpenAI_GenerateWithUsage_Stream(t *testing.T) {
+	var streamOptions *openai.StreamOptions
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req openai.ChatCompletionRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		streamOptions = req.StreamOptions
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte(
+			`data: {"model":"gpt-4o-2024-08-06","choices":[{"index":0,"delta":{"content":"hel"}}]}` + "\n\n" +
+				`data: {"model":"gpt-4o-2024-08-06","choices":[{"index":0,"delta":{"content":"lo"}}]}` + "\n\n" +
+				`data: {"model":"gpt-4o-2024-08-06","choices":[],` +
+				`"usage":{"prompt_tokens":10,"completion_tokens":2,"total_tokens":12}}` + "\n\n" +
+				"data: [DONE]\n\n",
+		))
+	}))
+	defer server.Close()
+
+	config := openai.DefaultConfig("test")
+	config.BaseURL = server.URL
+	llm := New().WithClient(openai.NewClientWithConfig(config)).WithStream(true, func(string) {})
+
+	tracker := usage.NewTracker()
+	ctx := usage.ContextWithTracker(context.Background(), tracker)
+
+	th := thread.New().AddMessage(thread.NewUserMessage().AddContent(thread.NewTextContent("hi")))
+	u, err := llm.GenerateWithUsage(ctx, th)
+	if err != nil {
+		t.Fatalf("GenerateWithUsage() error = %v", err)
+	}
+
+	if streamOptions == nil || !streamOptions.IncludeUsage {
+		t.Errorf("stream options = %+v, want the usage included", streamOptions)
+	}
+
+	want := usage.Usage{Provider: "openai", Model: "gpt-4o-2024-08-06", PromptTokens: 10, CompletionTokens: 2}
+	if u == nil || *u != want {
+		t.Errorf("GenerateWithUsage() usage = %+v, want %+v", u, want)
+	}
+	if got := tracker.Total(); got.TotalTokens() != want.TotalTokens() || got.Cost == 0 {
+		t.Errorf("recorded usage = %+v, want %+v priced", got, want)
+	}
+	if got := th.LastMessage().Contents[0].AsString(); got != "hello" {
+		t.Errorf("reply = %q, want hello", got)
+	}
+}
+
+func TestOpenAI_Generate_StreamOptions(t *testing.T) {
+	var streamOptions *openai.StreamOptions
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req openai.ChatCompletionRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		streamOptions = req.StreamOptions
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte(
+			`data: {"model":"gpt-4o-2024-08-06","choices":[{"index":0,"delta":{"content":"hello"}}]}` + "\n\n" +
+				"data: [DONE]\n\n",
+		))
+	}))
+	defer server.Close()
+
+	config := openai.DefaultConfig("test")
+	config.BaseURL = server.URL
+
+	tests := []struct {
+		name string
+		llm  *OpenAI
+		ctx  context.Context
+		want bool
+	}{
+		{
+			name: "usage not consumed",
+			llm:  New(),
+			ctx:  context.Background(),
+		},
+		{
+			name: "usage tracker",
+			llm:  New(),
+			ctx:  usage.ContextWithTracker(context.Background(), usage.NewTracker()),
+			want: true,
+		},
+		{
+			name: "usage callback",
+			llm:  New().WithUsageCallback(func(types.Meta) {}),
+			ctx:  context.Background(),
+			want: true,
+		},
+	}
+	for _, tt := range tests {
+		streamOptions = nil
+		tt.llm.WithClient(openai.NewClientWithConfig(config)).WithStream(true, func(string) {})
+
+		th := thread.New().AddMessage(thread.NewUserMessage().AddContent(thread.NewTextContent("hi")))
+		err := tt.llm.Generate(tt.ctx, th)
+		if err != nil {
+			t.Fatalf("%s: Generate() error = %v", tt.name, err)
+		}
+
+		if got := streamOptions != nil && streamOptions.IncludeUsage; got != tt.want {
+			t.Errorf("%s: usage included = %v, want %v", tt.name, got, tt.want)
+		}
+		if got := th.LastMessage().Contents[0].AsString(); got != "hello" {
+			t.Errorf("%s: reply = %q, want hello", tt.name, got)
+		}
+	}
+}
+
+type strictOutput struct {
+	Name    string   `json:"name"`
+	Age     *int     `json:"age,omitempty"`
+	Tags    []string `json:"tags,omitempty"`
+	Address struct {
+		City string `json:"city"`
+		Zip  string `json:"zip,omitempty"`
+	} `json:"address"`
+}
+
+func TestOpenAI_GenerateWithJSONSchema(t *testing.T) {
+	var responseFormat struct {
+		JSONSchema struct {
+			Schema map[string]interface{} `json:"schema"`
+			Strict bool                   `json:"strict"`
+		} `json:"json_schema"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ResponseFormat json.RawMessage `json:"response_format"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		_ = json.Unmarshal(req.ResponseFormat, &responseFormat)
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"model":"gpt-4o","choices":[{"index":0,"message":{"role":"assistant","content":"{}"}}]}`))
+	}))
+	defer server.Close()
+
+	config := openai.DefaultConfig("test")
+	config.BaseURL = server.URL
+	llm := New().WithClient(openai.NewClientWithConfig(config))
+
+	schema, err := tool.JSONSchema(strictOutput{})
+	if err != nil {
+		t.Fatalf("JSONSchema() error = %v", err)
+	}
+
+	th := thread.New().AddMessage(thread.NewUserMessage().AddContent(thread.NewTextContent("hi")))
+	err = llm.GenerateWithJSONSchema(context.Background(), th, "output", schema)
+	if err != nil {
+		t.Fatalf("GenerateWithJSONSchema() error = %v", err)
+	}
+
+	if !responseFormat.JSONSchema.Strict {
+		t.Errorf("response format = %+v, want a strict JSON schema", responseFormat)
+	}
+
+	sent := responseFormat.JSONSchema.Schema
+	address, _ := sent["properties"].(map[string]interface{})["address"].(map[string]interface{})
+	tests := []struct {
+		name     string
+		schema   map[string]interface{}
+		required []interface{}
+	}{
+		{name: "root", schema: sent, required: []interface{}{"address", "age", "name", "tags"}},
+		{name: "address", schema: address, required: []interface{}{"city", "zip"}},
+	}
+	for _, tt := range tests {
+		if !reflect.DeepEqual(tt.schema["required"], tt.required) {
+			t.Errorf("%s: required = %v, want %v", tt.name, tt.schema["required"], tt.required)
+		}
+		if tt.schema["additionalProperties"] != false {
+			t.Errorf("%s: additionalProperties = %v, want false", tt.name, tt.schema["additionalProperties"])
+		}
+	}
+
+	// the schema validating the response is left unchanged
+	if required := schema["required"].([]interface{}); len(required) != 2 {
+		t.Errorf("schema required = %v, want the original one", required)
+	}
+}
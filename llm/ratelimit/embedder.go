@@ -0,0 +1,64 @@
+package ratelimit
+
+import (
+	"context"
+
+	"github.com/maksymenkoml/lingoose/embedder"
+)
+
+const (
+	estimatedCharsPerToken = 4
+)
+
+type Embedder interface {
+	Embed(ctx context.Context, texts []string) ([]embedder.Embedding, error)
+}
+
+// TextTokenCountFn returns the number of tokens of a text.
+type TextTokenCountFn func(string) int
+
+// LimitedEmbedder is an embedder waiting for the budgets of its limiter before each
+// call, e.g. to load large batches of documents into an index.
+type LimitedEmbedder struct {
+	embedder Embedder
+	limiter  *Limiter
+	countFn  TextTokenCountFn
+}
+
+// Embedder wraps e so that its calls are kept under the budgets of the limiter.
+func (l *Limiter) Embedder(e Embedder) *LimitedEmbedder {
+	return &LimitedEmbedder{
+		embedder: e,
+		limiter:  l,
+		countFn:  EstimateTextTokens,
+	}
+}
+
+// WithTokenCounter sets the function counting the tokens of the texts. The tokens are
+// estimated by default, see EstimateTextTokens.
+func (e *LimitedEmbedder) WithTokenCounter(countFn TextTokenCountFn) *LimitedEmbedder {
+	e.countFn = countFn
+	return e
+}
+
+// Embed waits for the tokens of the texts to fit the budgets, then returns their
+// embeddings.
+func (e *LimitedEmbedder) Embed(ctx context.Context, texts []string) ([]embedder.Embedding, error) {
+	tokens := 0
+	for _, text := range texts {
+		tokens += e.countFn(text)
+	}
+
+	err := e.limiter.Wait(ctx, tokens)
+	if err != nil {
+		return nil, err
+	}
+
+	return e.embedder.Embed(ctx, texts)
+}
+
+// EstimateTextTokens roughly estimates the number of tokens of a text without a
+// tokenizer.
+func EstimateTextTokens(text string) int {
+	return (len(text) + estimatedCharsPerToken - 1) / estimatedCharsPerToken
+}
@@ -0,0 +1,99 @@
+package ratelimit
+
+import (
+	"context"
+
+	"github.com/maksymenkoml/lingoose/thread"
+	"github.com/maksymenkoml/lingoose/tool/llm_with_usage"
+)
+
+type LLM interface {
+	Generate(context.Context, *thread.Thread) error
+}
+
+// LimitedLLM is an LLM waiting for the budgets of its limiter before each generation.
+type LimitedLLM struct {
+	llm     LLM
+	limiter *Limiter
+	countFn thread.TokenCountFn
+}
+
+// LLM wraps llm so that its generations are kept under the budgets of the limiter.
+func (l *Limiter) LLM(llm LLM) *LimitedLLM {
+	return &LimitedLLM{
+		llm:     llm,
+		limiter: l,
+		countFn: thread.EstimateTokens,
+	}
+}
+
+// WithTokenCounter sets the function counting the tokens of the messages. The tokens
+// are estimated by default, see thread.EstimateTokens.
+func (l *LimitedLLM) WithTokenCounter(countFn thread.TokenCountFn) *LimitedLLM {
+	l.countFn = countFn
+	return l
+}
+
+// Generate waits for the tokens of the thread to fit the budgets, then generates the
+// next messages of the thread. The tokens of the generated messages are accounted
+// afterwards.
+func (l *LimitedLLM) Generate(ctx context.Context, t *thread.Thread) error {
+	if t == nil {
+		return nil
+	}
+
+	err := l.limiter.Wait(ctx, l.countTokens(t.Messages))
+	if err != nil {
+		return err
+	}
+
+	nMessagesBeforeGeneration := len(t.Messages)
+
+	err = l.llm.Generate(ctx, t)
+	l.limiter.Consume(l.countTokens(t.Messages[nMessagesBeforeGeneration:]))
+
+	return err
+}
+
+// GenerateWithUsage is like Generate, but it accounts the tokens reported by the LLM
+// instead of the estimated ones when the LLM reports them. The usage is nil otherwise.
+func (l *LimitedLLM) GenerateWithUsage(
+	ctx context.Context,
+	t *thread.Thread,
+) (*llm_with_usage.TokensUsage, error) {
+	llmWithUsage, ok := l.llm.(llm_with_usage.LLMWithUsage)
+	if !ok {
+		return nil, l.Generate(ctx, t)
+	}
+
+	if t == nil {
+		return nil, nil
+	}
+
+	estimatedTokens := l.countTokens(t.Messages)
+
+	err := l.limiter.Wait(ctx, estimatedTokens)
+	if err != nil {
+		return nil, err
+	}
+
+	nMessagesBeforeGeneration := len(t.Messages)
+
+	usage, err := llmWithUsage.GenerateWithUsage(ctx, t)
+	if usage != nil && usage.PromptTokens+usage.CompletionTokens > 0 {
+		l.limiter.Consume(usage.PromptTokens + usage.CompletionTokens - estimatedTokens)
+	} else {
+		l.limiter.Consume(l.countTokens(t.Messages[nMessagesBeforeGeneration:]))
+	}
+
+	return usage, err
+}
+
+func (l *LimitedLLM) countTokens(messages []*thread.Message) int {
+	tokens := 0
+	for _, message := range messages {
+		tokens += l.countFn(message)
+	}
+
+	return tokens
+}
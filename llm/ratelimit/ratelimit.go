@@ -0,0 +1,137 @@
+// Package ratelimit keeps the calls to the LLM and embedder providers under a requests
+// per minute (RPM) and a tokens per minute (TPM) budget. The tokens of a call are
+// estimated before it is sent, and corrected afterwards with the tokens actually used
+// when they are known. A Limiter can be shared by several wrapped clients, so that a
+// whole process stays under the quota of a provider account.
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// Limiter enforces the RPM and TPM budgets. It is safe for concurrent use.
+type Limiter struct {
+	mu       sync.Mutex
+	requests *bucket
+	tokens   *bucket
+	now      func() time.Time
+}
+
+// New creates a limiter allowing requestsPerMinute requests and tokensPerMinute tokens
+// per minute. A budget lower than or equal to 0 is not enforced.
+func New(requestsPerMinute, tokensPerMinute int) *Limiter {
+	now := time.Now()
+
+	return &Limiter{
+		requests: newBucket(requestsPerMinute, now),
+		tokens:   newBucket(tokensPerMinute, now),
+		now:      time.Now,
+	}
+}
+
+// Wait blocks until a request using the given number of tokens fits the budgets, or
+// the context is done. The request and its tokens are accounted as soon as Wait is
+// called, so concurrent callers are served in order.
+func (l *Limiter) Wait(ctx context.Context, tokens int) error {
+	l.mu.Lock()
+	now := l.now()
+	delay := l.reserve(now, tokens)
+	l.mu.Unlock()
+
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		l.mu.Lock()
+		l.requests.add(now, 1)
+		l.tokens.add(now, float64(tokens))
+		l.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// Consume accounts tokens used by a request without waiting, e.g. the completion
+// tokens known once the request is done. The following requests wait for them. A
+// negative number of tokens gives back an overestimate.
+func (l *Limiter) Consume(tokens int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.tokens.add(l.now(), -float64(tokens))
+}
+
+// reserve takes a request and its tokens from the budgets and returns the time to wait
+// before they are available.
+func (l *Limiter) reserve(now time.Time, tokens int) time.Duration {
+	requestsDelay := l.requests.take(now, 1)
+	tokensDelay := l.tokens.take(now, float64(tokens))
+
+	if requestsDelay > tokensDelay {
+		return requestsDelay
+	}
+
+	return tokensDelay
+}
+
+// bucket is a token bucket refilled continuously at limit per minute, up to limit. Its
+// level goes below zero when more than available is taken, delaying the next takers. A
+// nil bucket has no limit.
+type bucket struct {
+	limit     float64
+	available float64
+	last      time.Time
+}
+
+func newBucket(limit int, now time.Time) *bucket {
+	if limit <= 0 {
+		return nil
+	}
+
+	return &bucket{
+		limit:     float64(limit),
+		available: float64(limit),
+		last:      now,
+	}
+}
+
+func (b *bucket) refill(now time.Time) {
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.available = math.Min(b.limit, b.available+elapsed.Minutes()*b.limit)
+		b.last = now
+	}
+}
+
+// take takes n from the bucket and returns the time to wait until the bucket level is
+// back to zero.
+func (b *bucket) take(now time.Time, n float64) time.Duration {
+	if b == nil {
+		return 0
+	}
+
+	b.refill(now)
+	b.available -= n
+	if b.available >= 0 {
+		return 0
+	}
+
+	return time.Duration(-b.available / b.limit * float64(time.Minute))
+}
+
+func (b *bucket) add(now time.Time, n float64) {
+	if b == nil {
+		return
+	}
+
+	b.refill(now)
+	b.available = math.Min(b.limit, b.available+n)
+}
@@ -0,0 +1,150 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/maksymenkoml/lingoose/embedder"
+	"github.com/maksymenkoml/lingoose/thread"
+	"github.com/maksymenkoml/lingoose/tool/llm_with_usage"
+)
+
+func TestLimiter_reserve(t *testing.T) {
+	now := time.Now()
+	l := New(2, 100)
+	l.requests.last, l.tokens.last = now, now
+
+	tests := []struct {
+		name    string
+		elapsed time.Duration
+		tokens  int
+		want    time.Duration
+	}{
+		{name: "within budgets", tokens: 50, want: 0},
+		{name: "tokens exhausted", tokens: 75, want: 15 * time.Second},
+		{name: "requests exhausted", tokens: 0, want: 30 * time.Second},
+		{name: "refilled", elapsed: 2 * time.Minute, tokens: 100, want: 0},
+		{name: "larger than budget", tokens: 150, want: 90 * time.Second},
+	}
+	for _, tt := range tests {
+		now = now.Add(tt.elapsed)
+		if got := l.reserve(now, tt.tokens); got != tt.want {
+			t.Errorf("%s: reserve() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestLimiter_Wait_Canceled(t *testing.T) {
+	l := New(1, 0)
+
+	err := l.Wait(context.Background(), 0)
+	if err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	err = l.Wait(ctx, 0)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Wait() error = %v, want the context error", err)
+	}
+
+	// the canceled request is given back
+	if l.requests.available < -0.01 {
+		t.Errorf("available requests = %v, want about 0", l.requests.available)
+	}
+}
+
+type fakeLLM struct {
+	usage *llm_with_usage.TokensUsage
+}
+
+func (l *fakeLLM) Generate(_ context.Context, t *thread.Thread) error {
+	t.AddMessage(thread.NewAssistantMessage().AddContent(thread.NewTextContent("0123456789abcdef")))
+	return nil
+}
+
+func (l *fakeLLM) GenerateWithUsage(ctx context.Context, t *thread.Thread) (*llm_with_usage.TokensUsage, error) {
+	return l.usage, l.Generate(ctx, t)
+}
+
+type fakeEmbedder struct {
+	mu    sync.Mutex
+	calls int
+}
+
+func (e *fakeEmbedder) Embed(_ context.Context, texts []string) ([]embedder.Embedding, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.calls++
+	return make([]embedder.Embedding, len(texts)), nil
+}
+
+func TestLimitedLLM_Generate(t *testing.T) {
+	l := New(0, 1000)
+	countFn := func(m *thread.Message) int { return len(m.Contents[0].AsString()) }
+
+	th := thread.New().AddMessage(thread.NewUserMessage().AddContent(thread.NewTextContent("0123456789")))
+	err := l.LLM(&fakeLLM{}).WithTokenCounter(countFn).Generate(context.Background(), th)
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	// 10 prompt tokens and 16 completion tokens
+	if used := 1000 - l.tokens.available; used < 25.9 || used > 26 {
+		t.Errorf("used tokens = %v, want 26", used)
+	}
+
+	usage := &llm_with_usage.TokensUsage{PromptTokens: 100, CompletionTokens: 200}
+	th = thread.New().AddMessage(thread.NewUserMessage().AddContent(thread.NewTextContent("0123456789")))
+	_, err = l.LLM(&fakeLLM{usage: usage}).WithTokenCounter(countFn).GenerateWithUsage(context.Background(), th)
+	if err != nil {
+		t.Fatalf("GenerateWithUsage() error = %v", err)
+	}
+
+	// the reported usage replaces the estimate
+	if used := 1000 - l.tokens.available; used < 325.9 || used > 326 {
+		t.Errorf("used tokens = %v, want 326", used)
+	}
+}
+
+func TestLimitedEmbedder_Shared(t *testing.T) {
+	// 6000 requests per minute is a request every 10ms, once the first 6000 are used
+	l := New(6000, 0)
+	l.requests.available = 0
+
+	e := &fakeEmbedder{}
+	embedders := []*LimitedEmbedder{l.Embedder(e), l.Embedder(e)}
+
+	start := time.Now()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(e *LimitedEmbedder) {
+			defer wg.Done()
+			_, _ = e.Embed(context.Background(), []string{"text"})
+		}(embedders[i%2])
+	}
+	wg.Wait()
+
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Errorf("10 shared requests took %v, want at least 90ms", elapsed)
+	}
+	if e.calls != 10 {
+		t.Errorf("calls = %d, want 10", e.calls)
+	}
+}
+
+func TestEstimateTextTokens(t *testing.T) {
+	for text, want := range map[string]int{"": 0, "abc": 1, "abcd": 1, "abcde": 2} {
+		if got := EstimateTextTokens(text); got != want {
+			t.Errorf("EstimateTextTokens(%q) = %d, want %d", text, got, want)
+		}
+	}
+}
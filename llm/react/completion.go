@@ -0,0 +1,51 @@
+package react
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/maksymenkoml/lingoose/thread"
+)
+
+type CompletionLLM interface {
+	Completion(ctx context.Context, prompt string) (string, error)
+}
+
+var threadRoleToPrefix = map[thread.Role]string{
+	thread.RoleSystem:    "System",
+	thread.RoleUser:      "User",
+	thread.RoleAssistant: "Assistant",
+	thread.RoleTool:      "Tool",
+}
+
+// Completion adapts a text completion LLM, e.g. llm/huggingface or llm/llamacpp, to
+// the Generate interface by rendering the thread as a transcript.
+type Completion struct {
+	llm CompletionLLM
+}
+
+func NewCompletion(llm CompletionLLM) *Completion {
+	return &Completion{
+		llm: llm,
+	}
+}
+
+func (c *Completion) Generate(ctx context.Context, t *thread.Thread) error {
+	var prompt strings.Builder
+	for _, message := range t.Messages {
+		prompt.WriteString(threadRoleToPrefix[message.Role] + ": " + messageText(message) + "\n\n")
+	}
+	prompt.WriteString(threadRoleToPrefix[thread.RoleAssistant] + ":")
+
+	completion, err := c.llm.Completion(ctx, prompt.String())
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrReAct, err)
+	}
+
+	t.AddMessage(thread.NewAssistantMessage().AddContent(
+		thread.NewTextContent(strings.TrimSpace(completion)),
+	))
+
+	return nil
+}
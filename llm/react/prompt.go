@@ -0,0 +1,23 @@
+package react
+
+const (
+	promptName = "react/prompt"
+
+	//nolint:lll
+	defaultPrompt = `Answer the following request as best you can. You have access to the following tools:
+
+{{range .tools}}{{.name}}: {{.description}}
+Action Input JSON schema: {{.parameters}}
+
+{{end}}Use the following format:
+
+Thought: you should always think about what to do
+Action: the action to take, should be one of [{{.toolNames}}]
+Action Input: the input to the action, as a JSON object matching the action schema
+Observation: the result of the action
+... (this Thought/Action/Action Input/Observation can repeat N times)
+Thought: I now know the final answer
+Final Answer: the final answer to the original request
+
+Never write the Observation yourself, it will be provided after each Action.`
+)
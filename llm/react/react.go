@@ -0,0 +1,316 @@
+// Package react provides a ReAct agent that lets any LLM use tools without native tool
+// calling support. Tools are described in the prompt and the model answers with
+// Thought/Action/Action Input lines, which are parsed and executed as tool calls.
+package react
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/maksymenkoml/lingoose/prompt"
+	"github.com/maksymenkoml/lingoose/thread"
+	"github.com/maksymenkoml/lingoose/tool"
+	"github.com/maksymenkoml/lingoose/types"
+)
+
+const (
+	DefaultMaxIterations = 5
+)
+
+var (
+	ErrReAct         = errors.New("react agent error")
+	ErrMaxIterations = errors.New("maximum number of iterations reached")
+)
+
+var (
+	actionRegexp      = regexp.MustCompile(`(?s)Action\s*:\s*(.*?)\s*\n\s*Action\s*Input\s*:\s*(.*)`)
+	finalAnswerRegexp = regexp.MustCompile(`(?s)Final\s*Answer\s*:\s*(.*)`)
+	thoughtRegexp     = regexp.MustCompile(`(?s)^\s*Thought\s*:\s*`)
+)
+
+type LLM interface {
+	Generate(context.Context, *thread.Thread) error
+}
+
+// ReAct wraps an LLM and runs the Thought/Action/Observation loop until the model gives
+// its final answer. Tool calls and results are added to the thread as tool call and
+// tool messages, so that the thread looks the same as with a native tool calling LLM.
+type ReAct struct {
+	llm           LLM
+	tools         *tool.Registry
+	toolsErr      error
+	prompt        string
+	maxIterations uint
+}
+
+func New(llm LLM) *ReAct {
+	return &ReAct{
+		llm:           llm,
+		tools:         tool.NewRegistry(),
+		prompt:        defaultPrompt,
+		maxIterations: DefaultMaxIterations,
+	}
+}
+
+// WithTools adds the tools to the ReAct instance. If a tool is invalid, Generate
+// fails with its error.
+func (r *ReAct) WithTools(tools ...tool.Tool) *ReAct {
+	err := r.tools.AddTools(tools...)
+	if err != nil && r.toolsErr == nil {
+		r.toolsErr = err
+	}
+
+	return r
+}
+
+// WithToolRegistry sets the registry holding the tools available to the ReAct instance.
+// A nil registry is an empty one.
+func (r *ReAct) WithToolRegistry(registry *tool.Registry) *ReAct {
+	if registry == nil {
+		registry = tool.NewRegistry()
+	}
+
+	r.tools = registry
+	return r
+}
+
+func (r *ReAct) BindFunction(
+	fn interface{},
+	name string,
+	description string,
+	functionParameterOptions ...tool.FunctionParameterOption,
+) error {
+	return r.tools.Bind(fn, name, description, functionParameterOptions...)
+}
+
+// WithPrompt sets the prompt template describing the tools and the answer format. The
+// template receives the tools as {{.tools}}, each one with name, description and
+// parameters, and the comma separated tool names as {{.toolNames}}. Generate fails if the
+// template cannot be parsed or executed.
+func (r *ReAct) WithPrompt(prompt string) *ReAct {
+	r.prompt = prompt
+	return r
+}
+
+// WithMaxIterations sets the maximum number of actions executed by a single Generate call.
+func (r *ReAct) WithMaxIterations(maxIterations uint) *ReAct {
+	r.maxIterations = maxIterations
+	return r
+}
+
+// Generate runs the ReAct loop on the thread. It returns when the model gives its final
+// answer, added to the thread as an assistant message. It fails with ErrMaxIterations
+// when the maximum number of iterations is reached without a final answer, leaving the
+// thread with the last tool results, and with tool.ErrRepairsExhausted when the model
+// keeps calling a tool with invalid arguments.
+func (r *ReAct) Generate(ctx context.Context, t *thread.Thread) error {
+	if t == nil {
+		return nil
+	}
+
+	if r.toolsErr != nil {
+		return fmt.Errorf("%w: %w", ErrReAct, r.toolsErr)
+	}
+
+	ctx = tool.ContextWithRepairs(ctx)
+
+	for i := 0; i < int(r.maxIterations); i++ {
+		reactThread, err := r.buildThread(t)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrReAct, err)
+		}
+
+		err = r.llm.Generate(ctx, reactThread)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrReAct, err)
+		}
+
+		thought, toolCall, finalAnswer := parse(messageText(reactThread.LastMessage()))
+		if toolCall == nil {
+			t.AddMessage(thread.NewAssistantMessage().AddContent(
+				thread.NewTextContent(finalAnswer),
+			))
+			return nil
+		}
+
+		toolCallMessage := thread.NewAssistantMessage()
+		if thought != "" {
+			toolCallMessage.AddContent(thread.NewTextContent(thought))
+		}
+		toolCalls := []thread.ToolCallData{*toolCall}
+		toolCallMessage.AddContent(thread.NewToolCallContent(toolCalls))
+
+		t.AddMessage(toolCallMessage)
+		t.AddMessages(r.tools.CallTools(ctx, toolCalls)...)
+
+		err = tool.CheckRepairs(ctx)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrReAct, err)
+		}
+	}
+
+	return fmt.Errorf("%w: %w: %d", ErrReAct, ErrMaxIterations, r.maxIterations)
+}
+
+// buildThread returns the thread sent to the LLM: the ReAct prompt as system message,
+// tool calls as assistant Thought/Action text and tool results as user Observation text.
+func (r *ReAct) buildThread(t *thread.Thread) (*thread.Thread, error) {
+	systemPrompt, err := r.systemPrompt()
+	if err != nil {
+		return nil, err
+	}
+
+	reactThread := thread.New()
+	for _, message := range t.Messages {
+		switch message.Role {
+		case thread.RoleSystem:
+			systemPrompt = messageText(message) + "\n\n" + systemPrompt
+		case thread.RoleUser:
+			reactThread.AddMessage(message)
+		case thread.RoleAssistant:
+			reactThread.AddMessage(thread.NewAssistantMessage().AddContent(
+				thread.NewTextContent(assistantMessageText(message)),
+			))
+		case thread.RoleTool:
+			reactThread.AddMessage(thread.NewUserMessage().AddContent(
+				thread.NewTextContent(observationText(message)),
+			))
+		}
+	}
+
+	reactThread.Messages = append([]*thread.Message{
+		thread.NewSystemMessage().AddContent(thread.NewTextContent(systemPrompt)),
+	}, reactThread.Messages...)
+
+	return reactThread, nil
+}
+
+func (r *ReAct) systemPrompt() (string, error) {
+	var tools []types.M
+	var toolNames []string
+	for _, function := range r.tools.Functions() {
+		schema := make(map[string]interface{}, len(function.Parameters))
+		for key, value := range function.Parameters {
+			if key != "$id" {
+				schema[key] = value
+			}
+		}
+
+		parameters, err := json.Marshal(schema)
+		if err != nil {
+			parameters = []byte("{}")
+		}
+
+		tools = append(tools, types.M{
+			"name":        function.Name,
+			"description": function.Description,
+			"parameters":  string(parameters),
+		})
+		toolNames = append(toolNames, function.Name)
+	}
+
+	return prompt.Format(nil, promptName, r.prompt, types.M{
+		"tools":     tools,
+		"toolNames": strings.Join(toolNames, ", "),
+	})
+}
+
+// parse extracts the thought and the action from the model output. If the output has
+// no action, it is the final answer.
+func parse(output string) (string, *thread.ToolCallData, string) {
+	// the model may go on writing the observation by itself
+	output, _, _ = strings.Cut(output, "\nObservation:")
+
+	finalAnswerLoc := finalAnswerRegexp.FindStringSubmatchIndex(output)
+	actionLoc := actionRegexp.FindStringSubmatchIndex(output)
+
+	if actionLoc == nil || (finalAnswerLoc != nil && finalAnswerLoc[0] < actionLoc[0]) {
+		if finalAnswerLoc != nil {
+			return "", nil, strings.TrimSpace(output[finalAnswerLoc[2]:finalAnswerLoc[3]])
+		}
+		return "", nil, strings.TrimSpace(thoughtRegexp.ReplaceAllString(output, ""))
+	}
+
+	thought := strings.TrimSpace(thoughtRegexp.ReplaceAllString(output[:actionLoc[0]], ""))
+	name := strings.Trim(output[actionLoc[2]:actionLoc[3]], " `[]\"'")
+
+	arguments := output[actionLoc[4]:actionLoc[5]]
+	if finalAnswerLoc != nil && finalAnswerLoc[0] > actionLoc[0] {
+		arguments = output[actionLoc[4]:finalAnswerLoc[0]]
+	}
+
+	return thought, &thread.ToolCallData{
+		ID:        uuid.NewString(),
+		Name:      name,
+		Arguments: cleanArguments(arguments),
+	}, ""
+}
+
+// cleanArguments removes code fences and the text around the JSON object.
+func cleanArguments(arguments string) string {
+	arguments = strings.TrimSpace(arguments)
+	arguments = strings.TrimPrefix(arguments, "```json")
+	arguments = strings.Trim(arguments, "`")
+
+	start := strings.Index(arguments, "{")
+	end := strings.LastIndex(arguments, "}")
+	if start >= 0 && end > start {
+		return arguments[start : end+1]
+	}
+
+	return strings.TrimSpace(arguments)
+}
+
+func assistantMessageText(message *thread.Message) string {
+	var text string
+	var toolCalls []thread.ToolCallData
+	for _, content := range message.Contents {
+		switch content.Type {
+		case thread.ContentTypeText:
+			text += content.AsString()
+		case thread.ContentTypeToolCall:
+			toolCalls = append(toolCalls, content.AsToolCallData()...)
+		case thread.ContentTypeImage, thread.ContentTypeToolResponse, thread.ContentTypeAudio, thread.ContentTypeFile:
+			continue
+		}
+	}
+
+	if len(toolCalls) == 0 {
+		return text
+	}
+
+	text = "Thought: " + text
+	for _, toolCall := range toolCalls {
+		text += "\nAction: " + toolCall.Name + "\nAction Input: " + toolCall.Arguments
+	}
+
+	return text
+}
+
+func observationText(message *thread.Message) string {
+	var observations []string
+	for _, content := range message.Contents {
+		if toolResponseData := content.AsToolResponseData(); toolResponseData != nil {
+			observations = append(observations, "Observation: "+toolResponseData.Result)
+		}
+	}
+
+	return strings.Join(observations, "\n")
+}
+
+func messageText(message *thread.Message) string {
+	var text string
+	for _, content := range message.Contents {
+		if content.Type == thread.ContentTypeText {
+			text += content.AsString()
+		}
+	}
+
+	return text
+}
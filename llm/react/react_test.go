@@ -0,0 +1,172 @@
+package react
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/maksymenkoml/lingoose/prompt"
+	"github.com/maksymenkoml/lingoose/thread"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name            string
+		output          string
+		wantThought     string
+		wantName        string
+		wantArguments   string
+		wantFinalAnswer string
+	}{
+		{
+			name:          "action",
+			output:        "Thought: I need to sum\nAction: sum\nAction Input: {\"a\": 1, \"b\": 2}\nObservation: 3",
+			wantThought:   "I need to sum",
+			wantName:      "sum",
+			wantArguments: `{"a": 1, "b": 2}`,
+		},
+		{
+			name:          "fenced input",
+			output:        "Action: `sum`\nAction Input:\n```json\n{\"a\": 1}\n```",
+			wantName:      "sum",
+			wantArguments: `{"a": 1}`,
+		},
+		{
+			name:            "final answer",
+			output:          "Thought: I now know the final answer\nFinal Answer: 3",
+			wantFinalAnswer: "3",
+		},
+		{
+			name:            "plain text",
+			output:          "The answer is 3",
+			wantFinalAnswer: "The answer is 3",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			thought, toolCall, finalAnswer := parse(tt.output)
+
+			if finalAnswer != tt.wantFinalAnswer {
+				t.Errorf("parse() final answer = %q, want %q", finalAnswer, tt.wantFinalAnswer)
+			}
+
+			if tt.wantName == "" {
+				if toolCall != nil {
+					t.Errorf("parse() tool call = %v, want nil", toolCall)
+				}
+				return
+			}
+
+			if toolCall == nil || toolCall.Name != tt.wantName || toolCall.Arguments != tt.wantArguments {
+				t.Fatalf("parse() tool call = %v, want %s(%s)", toolCall, tt.wantName, tt.wantArguments)
+			}
+
+			if thought != tt.wantThought {
+				t.Errorf("parse() thought = %q, want %q", thought, tt.wantThought)
+			}
+		})
+	}
+}
+
+type sumInput struct {
+	A int `json:"a"`
+	B int `json:"b"`
+}
+
+// scriptedLLM answers with the given outputs in order and records the last observation.
+type scriptedLLM struct {
+	outputs     []string
+	observation string
+}
+
+func (l *scriptedLLM) Generate(_ context.Context, t *thread.Thread) error {
+	if last := messageText(t.LastMessage()); strings.HasPrefix(last, "Observation:") {
+		l.observation = last
+	}
+
+	t.AddMessage(thread.NewAssistantMessage().AddContent(thread.NewTextContent(l.outputs[0])))
+	l.outputs = l.outputs[1:]
+
+	return nil
+}
+
+func TestReAct_Generate(t *testing.T) {
+	llm := &scriptedLLM{
+		outputs: []string{
+			"Thought: I need to sum\nAction: sum\nAction Input: {\"a\": 1, \"b\": 2}",
+			"Thought: I now know the final answer\nFinal Answer: 3",
+		},
+	}
+
+	r := New(llm)
+	err := r.BindFunction(func(i sumInput) int { return i.A + i.B }, "sum", "sum two numbers")
+	if err != nil {
+		t.Fatalf("ReAct.BindFunction() error = %v", err)
+	}
+
+	th := thread.New().AddMessage(thread.NewUserMessage().AddContent(thread.NewTextContent("1+2?")))
+
+	err = r.Generate(context.Background(), th)
+	if err != nil {
+		t.Fatalf("ReAct.Generate() error = %v", err)
+	}
+
+	// user, tool call, tool response, final answer
+	if len(th.Messages) != 4 {
+		t.Fatalf("ReAct.Generate() messages = %d, want 4", len(th.Messages))
+	}
+
+	if got := th.Messages[2].Contents[0].AsToolResponseData(); got == nil || got.Result != "3" {
+		t.Errorf("ReAct.Generate() tool response = %v, want 3", got)
+	}
+
+	if llm.observation != "Observation: 3" {
+		t.Errorf("ReAct.Generate() observation = %q, want %q", llm.observation, "Observation: 3")
+	}
+
+	if got := th.LastMessage().Contents[0].AsString(); got != "3" {
+		t.Errorf("ReAct.Generate() answer = %q, want 3", got)
+	}
+}
+
+func TestReAct_Generate_PromptError(t *testing.T) {
+	llm := &scriptedLLM{outputs: []string{"Final Answer: 3"}}
+	r := New(llm).WithPrompt("Use the tools {{.toolNames")
+
+	th := thread.New().AddMessage(thread.NewUserMessage().AddContent(thread.NewTextContent("1+2?")))
+
+	err := r.Generate(context.Background(), th)
+	if !errors.Is(err, ErrReAct) || !errors.Is(err, prompt.ErrPromptTemplate) {
+		t.Fatalf("ReAct.Generate() error = %v, want %v", err, prompt.ErrPromptTemplate)
+	}
+
+	if len(th.Messages) != 1 || len(llm.outputs) != 1 {
+		t.Errorf("ReAct.Generate() sent the prompt of an invalid template")
+	}
+}
+
+func TestReAct_Generate_MaxIterations(t *testing.T) {
+	action := "Thought: I need to sum\nAction: sum\nAction Input: {\"a\": 1, \"b\": 2}"
+	llm := &scriptedLLM{outputs: []string{action, action}}
+
+	// the registry is empty, the model is told that the tool does not exist
+	r := New(llm).WithMaxIterations(2)
+
+	th := thread.New().AddMessage(thread.NewUserMessage().AddContent(thread.NewTextContent("1+2?")))
+
+	err := r.Generate(context.Background(), th)
+	if !errors.Is(err, ErrReAct) || !errors.Is(err, ErrMaxIterations) {
+		t.Fatalf("ReAct.Generate() error = %v, want %v", err, ErrMaxIterations)
+	}
+
+	// user, then a tool call and its tool response for each iteration
+	if len(th.Messages) != 5 || th.LastMessage().Role != thread.RoleTool {
+		t.Fatalf("ReAct.Generate() messages = %d, want 5 ending with a tool response", len(th.Messages))
+	}
+
+	if !strings.Contains(llm.observation, "unknown function sum") {
+		t.Errorf("ReAct.Generate() observation = %q, want the unknown function error", llm.observation)
+	}
+}
@@ -0,0 +1,161 @@
+// Package retry retries the calls to the LLM and embedder providers failing with a
+// transient error, waiting with an exponential backoff with jitter, or for the delay
+// requested by the Retry-After header of the error response.
+package retry
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/maksymenkoml/lingoose/llm/apierror"
+)
+
+const (
+	DefaultMaxAttempts    = 3
+	DefaultInitialBackoff = 500 * time.Millisecond
+	DefaultMaxBackoff     = 30 * time.Second
+	DefaultMultiplier     = 2
+)
+
+// RetryableFn reports whether a failed call must be retried.
+type RetryableFn func(error) bool
+
+// Policy defines how failed calls are retried. A nil policy never retries.
+type Policy struct {
+	maxAttempts    uint
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+	multiplier     float64
+	jitter         bool
+	retryableFn    RetryableFn
+}
+
+// New creates a policy retrying the transient errors, see apierror.IsTransient.
+func New() *Policy {
+	return &Policy{
+		maxAttempts:    DefaultMaxAttempts,
+		initialBackoff: DefaultInitialBackoff,
+		maxBackoff:     DefaultMaxBackoff,
+		multiplier:     DefaultMultiplier,
+		jitter:         true,
+		retryableFn:    apierror.IsTransient,
+	}
+}
+
+// WithMaxAttempts sets the maximum number of attempts, including the first one.
+func (p *Policy) WithMaxAttempts(maxAttempts uint) *Policy {
+	p.maxAttempts = maxAttempts
+	return p
+}
+
+// WithBackoff sets the backoff before the first retry, and the maximum backoff. The
+// backoff is multiplied by the multiplier after each retry.
+func (p *Policy) WithBackoff(initialBackoff, maxBackoff time.Duration) *Policy {
+	p.initialBackoff = initialBackoff
+	p.maxBackoff = maxBackoff
+	return p
+}
+
+func (p *Policy) WithMultiplier(multiplier float64) *Policy {
+	p.multiplier = multiplier
+	return p
+}
+
+// WithJitter enables or disables the random jitter of the backoff, which spreads the
+// retries of concurrent clients.
+func (p *Policy) WithJitter(jitter bool) *Policy {
+	p.jitter = jitter
+	return p
+}
+
+// WithRetryable sets the function deciding whether a failed call must be retried.
+func (p *Policy) WithRetryable(retryableFn RetryableFn) *Policy {
+	p.retryableFn = retryableFn
+	return p
+}
+
+// permanentError is an error that must not be retried, see Permanent.
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e *permanentError) Unwrap() error {
+	return e.err
+}
+
+// Permanent wraps err so that Do returns it, unwrapped, without retrying. It is used
+// when the call cannot be repeated, e.g. a stream failing after some of its deltas have
+// been delivered.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return &permanentError{err: err}
+}
+
+// Do calls fn until it succeeds, fails with an error that is not retryable, or the
+// maximum number of attempts is reached. It returns the error of the last attempt. The
+// call is not retried when the Retry-After delay of the error exceeds the maximum
+// backoff.
+func (p *Policy) Do(ctx context.Context, fn func(context.Context) error) error {
+	var permanent *permanentError
+
+	if p == nil {
+		err := fn(ctx)
+		if errors.As(err, &permanent) {
+			return permanent.err
+		}
+		return err
+	}
+
+	var err error
+	for attempt := uint(1); ; attempt++ {
+		err = fn(ctx)
+		if errors.As(err, &permanent) {
+			return permanent.err
+		}
+		if err == nil || attempt >= p.maxAttempts || ctx.Err() != nil || !p.retryableFn(err) {
+			return err
+		}
+		if retryAfter, ok := apierror.RetryAfter(err); ok && retryAfter > p.maxBackoff {
+			return err
+		}
+
+		timer := time.NewTimer(p.Backoff(attempt, err))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// Backoff returns the delay before retrying the given failed attempt, starting from 1.
+// The delay requested by the Retry-After header of the error response prevails, up to
+// the maximum backoff.
+func (p *Policy) Backoff(attempt uint, err error) time.Duration {
+	if retryAfter, ok := apierror.RetryAfter(err); ok {
+		return min(retryAfter, p.maxBackoff)
+	}
+
+	backoff := float64(p.initialBackoff) * math.Pow(p.multiplier, float64(attempt-1))
+	if backoff > float64(p.maxBackoff) {
+		backoff = float64(p.maxBackoff)
+	}
+
+	if p.jitter {
+		//nolint:gosec
+		backoff = backoff/2 + rand.Float64()*backoff/2
+	}
+
+	return time.Duration(backoff)
+}
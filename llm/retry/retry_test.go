@@ -0,0 +1,211 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/maksymenkoml/lingoose/llm/apierror"
+)
+
+// newServer returns a server replying with the given status codes in turn, and then
+// with 200.
+func newServer(t *testing.T, statusCodes ...int) (*httptest.Server, *int) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		calls++
+		if calls <= len(statusCodes) {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(statusCodes[calls-1])
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	t.Cleanup(server.Close)
+
+	return server, &calls
+}
+
+func get(ctx context.Context, url string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return apierror.New(resp.StatusCode, body).WithRetryAfter(resp.Header.Get("Retry-After"))
+	}
+
+	return nil
+}
+
+func TestPolicy_Do(t *testing.T) {
+	tests := []struct {
+		name        string
+		statusCodes []int
+		wantErr     bool
+		wantCalls   int
+	}{
+		{name: "success", wantCalls: 1},
+		{
+			name:        "transient errors",
+			statusCodes: []int{http.StatusServiceUnavailable, http.StatusTooManyRequests},
+			wantCalls:   3,
+		},
+		{
+			name:        "too many attempts",
+			statusCodes: []int{http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway},
+			wantErr:     true,
+			wantCalls:   3,
+		},
+		{
+			name:        "not retryable",
+			statusCodes: []int{http.StatusBadRequest},
+			wantErr:     true,
+			wantCalls:   1,
+		},
+	}
+	for _, tt := range tests {
+		server, calls := newServer(t, tt.statusCodes...)
+		policy := New().WithBackoff(time.Millisecond, time.Millisecond)
+
+		err := policy.Do(context.Background(), func(ctx context.Context) error {
+			return get(ctx, server.URL)
+		})
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: Do() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+		if *calls != tt.wantCalls {
+			t.Errorf("%s: calls = %d, want %d", tt.name, *calls, tt.wantCalls)
+		}
+	}
+}
+
+func TestPolicy_Do_Nil(t *testing.T) {
+	server, calls := newServer(t, http.StatusServiceUnavailable)
+
+	var policy *Policy
+	err := policy.Do(context.Background(), func(ctx context.Context) error {
+		return get(ctx, server.URL)
+	})
+	if !apierror.IsServerError(err) || *calls != 1 {
+		t.Errorf("Do() error = %v, calls = %d, want a single failed call", err, *calls)
+	}
+}
+
+func TestPolicy_Do_Canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	calls := 0
+	err := New().WithBackoff(time.Hour, time.Hour).Do(ctx, func(context.Context) error {
+		calls++
+		cancel()
+		return apierror.New(http.StatusServiceUnavailable, nil)
+	})
+	if err == nil || calls != 1 {
+		t.Errorf("Do() error = %v, calls = %d, want a single failed call", err, calls)
+	}
+}
+
+func TestPolicy_Do_RetryAfterExceeded(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		calls++
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	policy := New().WithBackoff(time.Millisecond, time.Second)
+
+	err := policy.Do(context.Background(), func(ctx context.Context) error {
+		return get(ctx, server.URL)
+	})
+	if !apierror.IsRateLimit(err) || calls != 1 {
+		t.Errorf("Do() error = %v, calls = %d, want a single rate limited call", err, calls)
+	}
+}
+
+func TestPolicy_Do_Permanent(t *testing.T) {
+	errStream := errors.New("stream interrupted")
+
+	for _, policy := range []*Policy{New().WithRetryable(func(error) bool { return true }), nil} {
+		calls := 0
+		err := policy.Do(context.Background(), func(context.Context) error {
+			calls++
+			return Permanent(errStream)
+		})
+		if err != errStream || calls != 1 {
+			t.Errorf("Do() error = %v, calls = %d, want the unwrapped error of a single call", err, calls)
+		}
+	}
+
+	if err := Permanent(nil); err != nil {
+		t.Errorf("Permanent(nil) = %v, want nil", err)
+	}
+}
+
+func TestPolicy_Backoff(t *testing.T) {
+	policy := New().WithBackoff(100*time.Millisecond, time.Second).WithJitter(false)
+
+	err := errors.New("connection reset")
+	for attempt, want := range []time.Duration{100, 200, 400, 800, 1000, 1000} {
+		if got := policy.Backoff(uint(attempt+1), err); got != want*time.Millisecond {
+			t.Errorf("Backoff(%d) = %v, want %v", attempt+1, got, want*time.Millisecond)
+		}
+	}
+
+	retryAfter := apierror.New(http.StatusTooManyRequests, nil).WithRetryAfter("1")
+	if got := policy.Backoff(1, retryAfter); got != time.Second {
+		t.Errorf("Backoff() = %v, want the Retry-After delay", got)
+	}
+
+	retryAfter = apierror.New(http.StatusTooManyRequests, nil).WithRetryAfter("7")
+	if got := policy.Backoff(1, retryAfter); got != time.Second {
+		t.Errorf("Backoff() = %v, want the Retry-After delay capped to 1s", got)
+	}
+
+	policy.WithJitter(true)
+	for i := 0; i < 100; i++ {
+		if got := policy.Backoff(2, err); got < 100*time.Millisecond || got > 200*time.Millisecond {
+			t.Fatalf("Backoff() = %v, want between 100ms and 200ms", got)
+		}
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	date := time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)
+
+	tests := []struct {
+		value string
+		min   time.Duration
+		max   time.Duration
+	}{
+		{value: "", min: 0, max: 0},
+		{value: "invalid", min: 0, max: 0},
+		{value: "-1", min: 0, max: 0},
+		{value: "3", min: 3 * time.Second, max: 3 * time.Second},
+		{value: date, min: 58 * time.Second, max: time.Minute},
+		{value: "Mon, 02 Jan 2006 15:04:05 GMT", min: 0, max: 0},
+	}
+	for _, tt := range tests {
+		if got := apierror.ParseRetryAfter(tt.value); got < tt.min || got > tt.max {
+			t.Errorf("ParseRetryAfter(%q) = %v, want between %v and %v", tt.value, got, tt.min, tt.max)
+		}
+	}
+}
@@ -0,0 +1,18 @@
+package structured
+
+const (
+	// PromptName is the name of the prompt constraining the response of the LLMs
+	// without native JSON schema support in the prompt registry. The template receives
+	// the schema.
+	PromptName = "structured/prompt"
+	// RetryPromptName is the name of the prompt asking to fix an invalid response in
+	// the prompt registry. The template receives the validation error.
+	RetryPromptName = "structured/retry"
+)
+
+const (
+	//nolint:lll
+	defaultPrompt = "Respond only with a JSON object that matches the following JSON schema, without any other text or code fences:\n\n{{.schema}}"
+	//nolint:lll
+	defaultRetryPrompt = "Your response does not match the JSON schema: {{.error}}. Respond again only with the corrected JSON object."
+)
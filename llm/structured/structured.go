@@ -0,0 +1,204 @@
+// Package structured generates responses decoded into Go values. The JSON schema of the
+// value is derived from its type and enforced with the native JSON schema response
+// format of the LLM when available, or with prompt instructions otherwise.
+package structured
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/maksymenkoml/lingoose/prompt"
+	"github.com/maksymenkoml/lingoose/thread"
+	"github.com/maksymenkoml/lingoose/tool"
+	"github.com/maksymenkoml/lingoose/types"
+)
+
+const (
+	DefaultMaxRetries = 2
+)
+
+var (
+	ErrStructuredOutput = errors.New("structured output error")
+)
+
+var schemaNameRegexp = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+
+type LLM interface {
+	Generate(context.Context, *thread.Thread) error
+}
+
+// JSONSchemaLLM is implemented by the LLMs supporting a native JSON schema response
+// format, e.g. llm/openai and llm/ollama.
+type JSONSchemaLLM interface {
+	GenerateWithJSONSchema(ctx context.Context, t *thread.Thread, name string, schema map[string]interface{}) error
+}
+
+// Generator generates values of type T, which must be a struct or a pointer to a struct.
+type Generator[T any] struct {
+	llm         LLM
+	maxRetries  uint
+	prompt      string
+	retryPrompt string
+	prompts     *prompt.Registry
+}
+
+func New[T any](llm LLM) *Generator[T] {
+	return &Generator[T]{
+		llm:         llm,
+		maxRetries:  DefaultMaxRetries,
+		prompt:      defaultPrompt,
+		retryPrompt: defaultRetryPrompt,
+	}
+}
+
+// WithMaxRetries sets how many times the model is asked to fix a response that does not
+// match the schema.
+func (g *Generator[T]) WithMaxRetries(maxRetries uint) *Generator[T] {
+	g.maxRetries = maxRetries
+	return g
+}
+
+// WithPrompt sets the prompt template used to constrain the response of LLMs without
+// native JSON schema support. The template receives the schema as {{.schema}}, Generate
+// fails if it cannot be formatted.
+func (g *Generator[T]) WithPrompt(prompt string) *Generator[T] {
+	g.prompt = prompt
+	return g
+}
+
+// WithPrompts sets the registry used to override the built-in prompts, see PromptName
+// and RetryPromptName.
+func (g *Generator[T]) WithPrompts(prompts *prompt.Registry) *Generator[T] {
+	g.prompts = prompts
+	return g
+}
+
+// GenerateStructured generates the next message of the thread and decodes it into a
+// value of type T. It is a shortcut for New[T](llm).Generate(ctx, t).
+func GenerateStructured[T any](ctx context.Context, llm LLM, t *thread.Thread) (*T, error) {
+	return New[T](llm).Generate(ctx, t)
+}
+
+// Generate generates the next message of the thread and decodes it into a value of type
+// T. The response is validated against the schema of T and the model is asked to fix
+// it when it does not match. Only the valid response is added to the thread.
+func (g *Generator[T]) Generate(ctx context.Context, t *thread.Thread) (*T, error) {
+	var value T
+
+	name, schema, err := jsonSchema(value)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrStructuredOutput, err)
+	}
+
+	schemaAsJSON, err := json.Marshal(schema)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrStructuredOutput, err)
+	}
+
+	jsonSchemaLLM, isJSONSchemaLLM := g.llm.(JSONSchemaLLM)
+
+	structuredThread := thread.New()
+	if !isJSONSchemaLLM {
+		text, formatErr := prompt.Format(g.prompts, PromptName, g.prompt, types.M{"schema": string(schemaAsJSON)})
+		if formatErr != nil {
+			return nil, fmt.Errorf("%w: %w", ErrStructuredOutput, formatErr)
+		}
+
+		structuredThread.AddMessage(thread.NewSystemMessage().AddContent(
+			thread.NewTextContent(text),
+		))
+	}
+	structuredThread.AddMessages(t.Messages...)
+
+	for i := 0; i <= int(g.maxRetries); i++ {
+		if isJSONSchemaLLM {
+			err = jsonSchemaLLM.GenerateWithJSONSchema(ctx, structuredThread, name, schema)
+		} else {
+			err = g.llm.Generate(ctx, structuredThread)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrStructuredOutput, err)
+		}
+
+		response := structuredThread.LastMessage()
+		content := extractJSON(messageText(response))
+
+		err = tool.ValidateArguments(schema, content)
+		if err == nil {
+			err = json.Unmarshal([]byte(content), &value)
+		}
+		if err == nil {
+			t.AddMessage(response)
+			return &value, nil
+		}
+
+		text, formatErr := prompt.Format(g.prompts, RetryPromptName, g.retryPrompt, types.M{"error": err.Error()})
+		if formatErr != nil {
+			return nil, fmt.Errorf("%w: %w", ErrStructuredOutput, formatErr)
+		}
+
+		structuredThread.AddMessage(thread.NewUserMessage().AddContent(
+			thread.NewTextContent(text),
+		))
+	}
+
+	return nil, fmt.Errorf("%w: %w", ErrStructuredOutput, err)
+}
+
+// jsonSchema returns the name and the JSON schema of the type of value.
+func jsonSchema(value interface{}) (string, map[string]interface{}, error) {
+	valueType := reflect.TypeOf(value)
+	if valueType == nil {
+		return "", nil, errors.New("type must be a struct")
+	}
+
+	if valueType.Kind() == reflect.Pointer {
+		valueType = valueType.Elem()
+	}
+
+	if valueType.Kind() != reflect.Struct {
+		return "", nil, errors.New("type must be a struct")
+	}
+
+	schema, err := tool.JSONSchema(reflect.New(valueType).Elem().Interface())
+	if err != nil {
+		return "", nil, err
+	}
+
+	delete(schema, "$id")
+
+	name := schemaNameRegexp.ReplaceAllString(valueType.Name(), "_")
+	if name == "" {
+		name = "response"
+	}
+
+	return name, schema, nil
+}
+
+// extractJSON returns the JSON object in the text, removing code fences and any text
+// around it.
+func extractJSON(text string) string {
+	start := strings.Index(text, "{")
+	end := strings.LastIndex(text, "}")
+	if start >= 0 && end > start {
+		return text[start : end+1]
+	}
+
+	return strings.TrimSpace(text)
+}
+
+func messageText(message *thread.Message) string {
+	var text string
+	for _, content := range message.Contents {
+		if content.Type == thread.ContentTypeText {
+			text += content.AsString()
+		}
+	}
+
+	return text
+}
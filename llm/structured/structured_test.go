@@ -0,0 +1,148 @@
+package structured
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/maksymenkoml/lingoose/prompt"
+	"github.com/maksymenkoml/lingoose/thread"
+)
+
+type person struct {
+	Name string `json:"name"`
+	Age  int    `json:"age" jsonschema:"minimum=0"`
+}
+
+// scriptedLLM answers with the given outputs in order.
+type scriptedLLM struct {
+	outputs []string
+	threads []*thread.Thread
+}
+
+func (l *scriptedLLM) Generate(_ context.Context, t *thread.Thread) error {
+	l.threads = append(l.threads, t)
+	t.AddMessage(thread.NewAssistantMessage().AddContent(thread.NewTextContent(l.outputs[0])))
+	l.outputs = l.outputs[1:]
+	return nil
+}
+
+type scriptedJSONSchemaLLM struct {
+	scriptedLLM
+	name string
+}
+
+func (l *scriptedJSONSchemaLLM) GenerateWithJSONSchema(
+	ctx context.Context,
+	t *thread.Thread,
+	name string,
+	_ map[string]interface{},
+) error {
+	l.name = name
+	return l.Generate(ctx, t)
+}
+
+func TestGenerateStructured(t *testing.T) {
+	llm := &scriptedLLM{
+		outputs: []string{
+			`{"name": "Simone", "age": -1}`,
+			"```json\n{\"name\": \"Simone\", \"age\": 40}\n```",
+		},
+	}
+
+	th := thread.New().AddMessage(thread.NewUserMessage().AddContent(thread.NewTextContent("who am I?")))
+
+	got, err := GenerateStructured[person](context.Background(), llm, th)
+	if err != nil {
+		t.Fatalf("GenerateStructured() error = %v", err)
+	}
+
+	if *got != (person{Name: "Simone", Age: 40}) {
+		t.Errorf("GenerateStructured() = %v, want Simone 40", got)
+	}
+
+	if len(th.Messages) != 2 {
+		t.Errorf("GenerateStructured() messages = %d, want 2", len(th.Messages))
+	}
+
+	if prompt := llm.threads[0].Messages[0].Contents[0].AsString(); !strings.Contains(prompt, `"age"`) {
+		t.Errorf("GenerateStructured() prompt = %s, want schema", prompt)
+	}
+
+	// system prompt, user, invalid response, retry prompt, valid response
+	if retry := llm.threads[0].Messages[3].Contents[0].AsString(); !strings.Contains(retry, `field "age"`) {
+		t.Errorf("GenerateStructured() retry prompt = %s, want field error", retry)
+	}
+}
+
+func TestGenerator_GenerateJSONSchema(t *testing.T) {
+	llm := &scriptedJSONSchemaLLM{scriptedLLM: scriptedLLM{outputs: []string{`{"name": "Simone", "age": 40}`}}}
+
+	th := thread.New().AddMessage(thread.NewUserMessage().AddContent(thread.NewTextContent("who am I?")))
+
+	got, err := New[*person](llm).Generate(context.Background(), th)
+	if err != nil {
+		t.Fatalf("Generator.Generate() error = %v", err)
+	}
+
+	if **got != (person{Name: "Simone", Age: 40}) || llm.name != "person" {
+		t.Errorf("Generator.Generate() = %v with schema %s, want Simone 40 with schema person", got, llm.name)
+	}
+
+	if role := llm.threads[0].Messages[0].Role; role != thread.RoleUser {
+		t.Errorf("Generator.Generate() first message role = %s, want no system prompt", role)
+	}
+}
+
+func TestGenerator_GenerateMaxRetries(t *testing.T) {
+	llm := &scriptedLLM{outputs: []string{"not json", "still not json"}}
+
+	th := thread.New().AddMessage(thread.NewUserMessage().AddContent(thread.NewTextContent("who am I?")))
+
+	_, err := New[person](llm).WithMaxRetries(1).Generate(context.Background(), th)
+	if !errors.Is(err, ErrStructuredOutput) {
+		t.Errorf("Generator.Generate() error = %v, want ErrStructuredOutput", err)
+	}
+}
+
+func TestGenerator_GeneratePrompts(t *testing.T) {
+	prompts := prompt.NewRegistry()
+	err := prompts.Register(RetryPromptName, "", "Invalid response: {{.error}}")
+	if err != nil {
+		t.Fatalf("Registry.Register() error = %v", err)
+	}
+
+	llm := &scriptedLLM{outputs: []string{"not json", `{"name": "Simone", "age": 40}`}}
+	th := thread.New().AddMessage(thread.NewUserMessage().AddContent(thread.NewTextContent("who am I?")))
+
+	_, err = New[person](llm).WithPrompts(prompts).Generate(context.Background(), th)
+	if err != nil {
+		t.Fatalf("Generator.Generate() error = %v", err)
+	}
+
+	if retry := llm.threads[0].Messages[3].Contents[0].AsString(); !strings.HasPrefix(retry, "Invalid response: ") {
+		t.Errorf("Generator.Generate() retry prompt = %s, want the registered one", retry)
+	}
+
+	// the prompts that cannot be formatted are not sent unformatted
+	tests := []struct {
+		prompt  string
+		wantErr error
+	}{
+		{"Use the schema {{.schema", prompt.ErrPromptTemplate},
+		{"Use the schema {{.jsonSchema}}", prompt.ErrMissingVariable},
+	}
+
+	for _, tt := range tests {
+		llm = &scriptedLLM{outputs: []string{`{"name": "Simone", "age": 40}`}}
+
+		_, err = New[person](llm).WithPrompt(tt.prompt).Generate(context.Background(), th)
+		if !errors.Is(err, ErrStructuredOutput) || !errors.Is(err, tt.wantErr) {
+			t.Errorf("Generator.Generate() with prompt %q error = %v, want %v", tt.prompt, err, tt.wantErr)
+		}
+		if len(llm.threads) != 0 {
+			t.Errorf("Generator.Generate() with prompt %q called the LLM", tt.prompt)
+		}
+	}
+}
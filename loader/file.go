@@ -0,0 +1,196 @@
+package loader
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/maksymenkoml/lingoose/document"
+	"github.com/maksymenkoml/lingoose/thread"
+	"github.com/maksymenkoml/lingoose/types"
+)
+
+const (
+	pdfMIMEType = "application/pdf"
+)
+
+// FileLoader extracts the text of a file embedded in a thread, e.g. to pass it to the
+// LLMs that do not accept documents natively. Text files are used as they are, PDF
+// files are converted with pdftotext and any other document with LibreOffice.
+type FileLoader struct {
+	loader Loader
+
+	fileData        *thread.FileData
+	pdfToTextPath   string
+	libreOfficePath string
+}
+
+func NewFileLoader(fileData *thread.FileData) *FileLoader {
+	return &FileLoader{
+		fileData:        fileData,
+		pdfToTextPath:   defaultPdfToTextPath,
+		libreOfficePath: defaultLibreOfficePath,
+	}
+}
+
+func (f *FileLoader) WithPDFToTextPath(pdfToTextPath string) *FileLoader {
+	f.pdfToTextPath = pdfToTextPath
+	return f
+}
+
+func (f *FileLoader) WithLibreOfficePath(libreOfficePath string) *FileLoader {
+	f.libreOfficePath = libreOfficePath
+	return f
+}
+
+func (f *FileLoader) WithTextSplitter(textSplitter TextSplitter) *FileLoader {
+	f.loader.textSplitter = textSplitter
+	return f
+}
+
+func (f *FileLoader) Load(ctx context.Context) ([]document.Document, error) {
+	if f.fileData == nil {
+		return nil, fmt.Errorf("%w: missing file data", ErrInternal)
+	}
+
+	var documents []document.Document
+	var err error
+	if isTextMIMEType(f.fileData.MIMEType) {
+		documents = []document.Document{
+			{
+				Content: string(f.fileData.Data),
+			},
+		}
+	} else {
+		documents, err = f.loadFile(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for i := range documents {
+		documents[i].Metadata = types.Meta{
+			SourceMetadataKey: f.fileData.Name,
+		}
+	}
+
+	if f.loader.textSplitter != nil {
+		documents = f.loader.textSplitter.SplitDocuments(documents)
+	}
+
+	return documents, nil
+}
+
+// loadFile writes the file data to a temporary file and converts it with the loader
+// matching its MIME type.
+func (f *FileLoader) loadFile(ctx context.Context) ([]document.Document, error) {
+	extension := filepath.Ext(f.fileData.Name)
+	if extension == "" {
+		if extensions, _ := mime.ExtensionsByType(f.fileData.MIMEType); len(extensions) > 0 {
+			extension = extensions[0]
+		}
+	}
+
+	tmpFile, err := os.CreateTemp("", "lingoose-*"+extension)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInternal, err)
+	}
+	defer os.Remove(tmpFile.Name())
+
+	_, err = tmpFile.Write(f.fileData.Data)
+	if closeErr := tmpFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInternal, err)
+	}
+
+	if mediaType, _, _ := mime.ParseMediaType(f.fileData.MIMEType); mediaType == pdfMIMEType {
+		return NewPDFToTextLoader(tmpFile.Name()).WithPDFToTextPath(f.pdfToTextPath).loadFile(ctx)
+	}
+
+	return NewLibreOfficeLoader(tmpFile.Name()).WithLibreOfficePath(f.libreOfficePath).loadFile(ctx)
+}
+
+// FileText returns the text extracted from the file data. It is a shortcut for
+// NewFileLoader(fileData).Load(ctx) joining the documents. The Text of the file data
+// is returned as is when already extracted.
+func FileText(ctx context.Context, fileData *thread.FileData) (string, error) {
+	if fileData.Text != "" {
+		return fileData.Text, nil
+	}
+
+	documents, err := NewFileLoader(fileData).Load(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	var text []string
+	for _, document := range documents {
+		text = append(text, document.Content)
+	}
+
+	return strings.Join(text, "\n"), nil
+}
+
+// ContentFileText returns the text of a file content: its Text when already extracted,
+// see ExtractFiles, or the text converted from the file otherwise. The content is not
+// modified, so the LLMs may format a thread concurrently.
+func ContentFileText(ctx context.Context, c *thread.Content) (string, error) {
+	fileData := c.AsFileData()
+	if fileData == nil {
+		return "", fmt.Errorf("%w: not a file content", ErrInternal)
+	}
+
+	text, err := FileText(ctx, fileData)
+	if err != nil {
+		return "", fmt.Errorf("file %s: %w", fileData.Name, err)
+	}
+
+	return text, nil
+}
+
+// ExtractFiles extracts the text of the file contents of the thread not extracted yet,
+// and sets it as their Text. Call it before the generations sending the files as text,
+// so that the files are not converted again at each request.
+func ExtractFiles(ctx context.Context, t *thread.Thread) error {
+	for _, message := range t.Messages {
+		for _, content := range message.Contents {
+			fileData := content.AsFileData()
+			if fileData == nil || fileData.Text != "" {
+				continue
+			}
+
+			text, err := ContentFileText(ctx, content)
+			if err != nil {
+				return err
+			}
+
+			fileData.Text = text
+			content.Data = *fileData
+		}
+	}
+
+	return nil
+}
+
+func isTextMIMEType(mimeType string) bool {
+	mediaType, _, err := mime.ParseMediaType(mimeType)
+	if err != nil {
+		return false
+	}
+
+	if strings.HasPrefix(mediaType, "text/") {
+		return true
+	}
+
+	switch mediaType {
+	case "application/json", "application/xml", "application/x-yaml", "application/yaml":
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,309 @@
+// Package prompt provides a registry of named and versioned prompt templates. Templates
+// use the text/template syntax, can include the registered partials with
+// {{template "name" .}} and fail when a variable is missing from the input.
+//
+// The prompts of the library, e.g. the assistant system prompt, can be overridden by
+// registering a template with the same name and passing the registry to the component.
+package prompt
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+
+	"github.com/maksymenkoml/lingoose/types"
+)
+
+const (
+	// FileExtension is the extension of the template files loaded by LoadFS.
+	FileExtension = ".tmpl"
+
+	versionSeparator = "@"
+	partialPrefix    = "_"
+)
+
+var (
+	ErrPromptNotFound  = errors.New("prompt not found")
+	ErrPromptTemplate  = errors.New("prompt template error")
+	ErrMissingVariable = errors.New("missing prompt variable")
+)
+
+var missingKeyRegexp = regexp.MustCompile(`map has no entry for key "([^"]*)"`)
+
+// Template is a named and versioned prompt template.
+type Template struct {
+	Name    string
+	Version string
+	Text    string
+
+	registry *Registry
+}
+
+// Registry holds prompt templates by name and version, and the partials they share.
+type Registry struct {
+	mu        sync.RWMutex
+	templates map[string]map[string]*Template
+	partials  map[string]string
+	pinned    map[string]string
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		templates: make(map[string]map[string]*Template),
+		partials:  make(map[string]string),
+		pinned:    make(map[string]string),
+	}
+}
+
+// Register adds the template text with the given name and version. The version can
+// be empty for unversioned templates.
+func (r *Registry) Register(name, version, text string) error {
+	_, err := template.New(name).Parse(text)
+	if err != nil {
+		return fmt.Errorf("%w: %s: %w", ErrPromptTemplate, name, err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.templates[name]; !ok {
+		r.templates[name] = make(map[string]*Template)
+	}
+
+	r.templates[name][version] = &Template{
+		Name:     name,
+		Version:  version,
+		Text:     text,
+		registry: r,
+	}
+
+	return nil
+}
+
+// RegisterPartial adds a partial that every template can include with
+// {{template "name" .}}.
+func (r *Registry) RegisterPartial(name, text string) error {
+	_, err := template.New(name).Parse(text)
+	if err != nil {
+		return fmt.Errorf("%w: %s: %w", ErrPromptTemplate, name, err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.partials[name] = text
+
+	return nil
+}
+
+// LoadFS registers all the template files of fsys, e.g. an embed.FS. The template name
+// is the file path without the extension, and an optional version follows the "@"
+// separator, e.g. "assistant/system@2.tmpl". Files whose name starts with "_" are
+// registered as partials, e.g. "_persona.tmpl" is the "persona" partial.
+func (r *Registry) LoadFS(fsys fs.FS) error {
+	return fs.WalkDir(fsys, ".", func(filePath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() || path.Ext(filePath) != FileExtension {
+			return nil
+		}
+
+		text, err := fs.ReadFile(fsys, filePath)
+		if err != nil {
+			return err
+		}
+
+		dir, file := path.Split(strings.TrimSuffix(filePath, FileExtension))
+		if strings.HasPrefix(file, partialPrefix) {
+			return r.RegisterPartial(dir+strings.TrimPrefix(file, partialPrefix), string(text))
+		}
+
+		name, version, _ := strings.Cut(file, versionSeparator)
+
+		return r.Register(dir+name, version, string(text))
+	})
+}
+
+// LoadDir registers all the template files of the directory. See LoadFS.
+func (r *Registry) LoadDir(dir string) error {
+	return r.LoadFS(os.DirFS(dir))
+}
+
+// Pin makes Get return the given version of the named template instead of the latest.
+func (r *Registry) Pin(name, version string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.templates[name][version]; !ok {
+		return fmt.Errorf("%w: %s%s%s", ErrPromptNotFound, name, versionSeparator, version)
+	}
+
+	r.pinned[name] = version
+
+	return nil
+}
+
+// Has reports whether a template with the given name is registered.
+func (r *Registry) Has(name string) bool {
+	if r == nil {
+		return false
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return len(r.templates[name]) > 0
+}
+
+// Versions returns the registered versions of the named template, from the oldest.
+func (r *Registry) Versions(name string) []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var versions []string
+	for version := range r.templates[name] {
+		versions = append(versions, version)
+	}
+
+	sort.Slice(versions, func(i, j int) bool {
+		return compareVersions(versions[i], versions[j]) < 0
+	})
+
+	return versions
+}
+
+// Get returns the pinned version of the named template, or the latest one.
+func (r *Registry) Get(name string) (*Template, error) {
+	r.mu.RLock()
+	version, pinned := r.pinned[name]
+	r.mu.RUnlock()
+
+	if !pinned {
+		versions := r.Versions(name)
+		if len(versions) == 0 {
+			return nil, fmt.Errorf("%w: %s", ErrPromptNotFound, name)
+		}
+		version = versions[len(versions)-1]
+	}
+
+	return r.GetVersion(name, version)
+}
+
+// GetVersion returns the given version of the named template.
+func (r *Registry) GetVersion(name, version string) (*Template, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	t, ok := r.templates[name][version]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s%s%s", ErrPromptNotFound, name, versionSeparator, version)
+	}
+
+	return t, nil
+}
+
+// Format formats the template returned by Get with the input.
+func (r *Registry) Format(name string, input types.M) (string, error) {
+	t, err := r.Get(name)
+	if err != nil {
+		return "", err
+	}
+
+	return t.Format(input)
+}
+
+// Format formats the template with the input. It returns an error wrapping
+// ErrMissingVariable when a variable used by the template is not in the input.
+func (t *Template) Format(input types.M) (string, error) {
+	var partials map[string]string
+	if t.registry != nil {
+		t.registry.mu.RLock()
+		partials = make(map[string]string, len(t.registry.partials))
+		for name, text := range t.registry.partials {
+			partials[name] = text
+		}
+		t.registry.mu.RUnlock()
+	}
+
+	return format(t.Name, t.Text, partials, input)
+}
+
+// Format formats the named template of the registry with the input or, when the
+// registry is nil or does not hold the template, the fallback text. It lets the
+// components of the library use their built-in prompts unless overridden.
+func Format(r *Registry, name, fallback string, input types.M) (string, error) {
+	if r.Has(name) {
+		return r.Format(name, input)
+	}
+
+	return format(name, fallback, nil, input)
+}
+
+func format(name, text string, partials map[string]string, input types.M) (string, error) {
+	root := template.New(name).Option("missingkey=error")
+	for partialName, partialText := range partials {
+		_, err := root.New(partialName).Parse(partialText)
+		if err != nil {
+			return "", fmt.Errorf("%w: %s: %w", ErrPromptTemplate, partialName, err)
+		}
+	}
+
+	_, err := root.Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s: %w", ErrPromptTemplate, name, err)
+	}
+
+	if input == nil {
+		input = types.M{}
+	}
+
+	var buffer bytes.Buffer
+	err = root.Execute(&buffer, input)
+	if err != nil {
+		if match := missingKeyRegexp.FindStringSubmatch(err.Error()); match != nil {
+			return "", fmt.Errorf("%w %q in %s", ErrMissingVariable, match[1], name)
+		}
+		return "", fmt.Errorf("%w: %s: %w", ErrPromptTemplate, name, err)
+	}
+
+	return buffer.String(), nil
+}
+
+// compareVersions compares versions like "1", "v1.2" or "2024-01-01" segment by
+// segment, numerically when both segments are numbers. The empty version comes first.
+func compareVersions(a, b string) int {
+	aSegments := strings.FieldsFunc(strings.TrimPrefix(a, "v"), isVersionSeparator)
+	bSegments := strings.FieldsFunc(strings.TrimPrefix(b, "v"), isVersionSeparator)
+
+	for i := 0; i < len(aSegments) && i < len(bSegments); i++ {
+		aNumber, aErr := strconv.Atoi(aSegments[i])
+		bNumber, bErr := strconv.Atoi(bSegments[i])
+
+		switch {
+		case aErr == nil && bErr == nil && aNumber != bNumber:
+			if aNumber < bNumber {
+				return -1
+			}
+			return 1
+		case (aErr != nil || bErr != nil) && aSegments[i] != bSegments[i]:
+			return strings.Compare(aSegments[i], bSegments[i])
+		}
+	}
+
+	return len(aSegments) - len(bSegments)
+}
+
+func isVersionSeparator(r rune) bool {
+	return r == '.' || r == '-'
+}
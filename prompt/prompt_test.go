@@ -0,0 +1,126 @@
+package prompt
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"testing/fstest"
+
+	"github.com/maksymenkoml/lingoose/types"
+)
+
+func TestRegistry_LoadFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"greeting.tmpl":             {Data: []byte("Hello {{.name}}")},
+		"assistant/system@1.tmpl":   {Data: []byte("You are {{.name}}.")},
+		"assistant/system@2.tmpl":   {Data: []byte("You are {{.name}}. {{template \"assistant/rules\" .}}")},
+		"assistant/system@10.tmpl":  {Data: []byte("{{template \"signature\" .}} {{.name}}")},
+		"assistant/_rules.tmpl":     {Data: []byte("Be concise, {{.name}}.")},
+		"_signature.tmpl":           {Data: []byte("Signed:")},
+		"assistant/README.md":       {Data: []byte("not a template")},
+		"assistant/system@bad.tmpl": {Data: []byte("{{.name}}")},
+	}
+
+	r := NewRegistry()
+	err := r.LoadFS(fsys)
+	if err != nil {
+		t.Fatalf("LoadFS() error = %v", err)
+	}
+
+	if got, want := r.Versions("assistant/system"), []string{"1", "2", "10", "bad"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Versions() = %v, want %v", got, want)
+	}
+
+	tests := []struct {
+		name    string
+		version string
+		want    string
+	}{
+		{name: "greeting", version: "", want: "Hello Bob"},
+		{name: "assistant/system", version: "2", want: "You are Bob. Be concise, Bob."},
+		{name: "assistant/system", version: "10", want: "Signed: Bob"},
+	}
+	for _, tt := range tests {
+		template, getErr := r.GetVersion(tt.name, tt.version)
+		if getErr != nil {
+			t.Fatalf("GetVersion(%s, %s) error = %v", tt.name, tt.version, getErr)
+		}
+
+		got, formatErr := template.Format(types.M{"name": "Bob"})
+		if formatErr != nil {
+			t.Fatalf("Format(%s, %s) error = %v", tt.name, tt.version, formatErr)
+		}
+		if got != tt.want {
+			t.Errorf("Format(%s, %s) = %q, want %q", tt.name, tt.version, got, tt.want)
+		}
+	}
+
+	err = r.Pin("assistant/system", "1")
+	if err != nil {
+		t.Fatalf("Pin() error = %v", err)
+	}
+
+	got, err := r.Format("assistant/system", types.M{"name": "Bob"})
+	if err != nil || got != "You are Bob." {
+		t.Errorf("Format() = %q, %v, want pinned version", got, err)
+	}
+
+	if err = r.Pin("assistant/system", "3"); !errors.Is(err, ErrPromptNotFound) {
+		t.Errorf("Pin() error = %v, want %v", err, ErrPromptNotFound)
+	}
+}
+
+func TestRegistry_Format_Errors(t *testing.T) {
+	r := NewRegistry()
+
+	if err := r.Register("invalid", "", "{{.name"); !errors.Is(err, ErrPromptTemplate) {
+		t.Errorf("Register() error = %v, want %v", err, ErrPromptTemplate)
+	}
+
+	if _, err := r.Format("unknown", nil); !errors.Is(err, ErrPromptNotFound) {
+		t.Errorf("Format() error = %v, want %v", err, ErrPromptNotFound)
+	}
+
+	err := r.Register("question", "", "Question: {{.question}} Context: {{.context}}")
+	if err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	_, err = r.Format("question", types.M{"question": "why?"})
+	if !errors.Is(err, ErrMissingVariable) {
+		t.Errorf("Format() error = %v, want %v", err, ErrMissingVariable)
+	}
+}
+
+func TestFormat_Fallback(t *testing.T) {
+	r := NewRegistry()
+	err := r.Register("overridden", "", "custom {{.value}}")
+	if err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		registry *Registry
+		prompt   string
+		want     string
+	}{
+		{name: "nil registry", registry: nil, prompt: "overridden", want: "default 1"},
+		{name: "not registered", registry: r, prompt: "other", want: "default 1"},
+		{name: "registered", registry: r, prompt: "overridden", want: "custom 1"},
+	}
+	for _, tt := range tests {
+		got, formatErr := Format(tt.registry, tt.prompt, "default {{.value}}", types.M{"value": 1})
+		if formatErr != nil {
+			t.Fatalf("%s: Format() error = %v", tt.name, formatErr)
+		}
+		if got != tt.want {
+			t.Errorf("%s: Format() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	_, err = Format(nil, "fallback", "default {{.value}}", nil)
+	if !errors.Is(err, ErrMissingVariable) {
+		t.Errorf("Format() error = %v, want %v", err, ErrMissingVariable)
+	}
+}
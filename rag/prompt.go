@@ -0,0 +1,19 @@
+package rag
+
+const (
+	// FusionSystemPromptName, FusionQueryPromptName and FusionOutputPromptName are the
+	// names of the prompts used by Fusion to generate the search queries. The query
+	// template receives the query.
+	FusionSystemPromptName = "rag/fusion/system"
+	FusionQueryPromptName  = "rag/fusion/query"
+	FusionOutputPromptName = "rag/fusion/output"
+	// SubDocumentSummarizePromptName is the name of the prompt used by SubDocumentRAG to
+	// summarize the documents. The template receives the context.
+	SubDocumentSummarizePromptName = "rag/sub_document/summarize"
+)
+
+const (
+	fusionSystemPrompt = "You are a helpful assistant that generates multiple search queries based on a single input query."
+	fusionQueryPrompt  = "Generate multiple search queries related to: {{.query}}"
+	fusionOutputPrompt = "OUTPUT (4 queries):"
+)
@@ -10,6 +10,7 @@ import (
 	"github.com/maksymenkoml/lingoose/index/option"
 	"github.com/maksymenkoml/lingoose/loader"
 	obs "github.com/maksymenkoml/lingoose/observer"
+	"github.com/maksymenkoml/lingoose/prompt"
 	"github.com/maksymenkoml/lingoose/textsplitter"
 	"github.com/maksymenkoml/lingoose/thread"
 	"github.com/maksymenkoml/lingoose/types"
@@ -40,6 +41,7 @@ type RAG struct {
 	chunkOverlap uint
 	topK         uint
 	loaders      map[*regexp.Regexp]Loader // this map a regexp as string to a loader
+	prompts      *prompt.Registry
 }
 
 func New(index *index.Index) *RAG {
@@ -9,16 +9,11 @@ import (
 	"github.com/maksymenkoml/lingoose/index"
 	"github.com/maksymenkoml/lingoose/index/option"
 	obs "github.com/maksymenkoml/lingoose/observer"
+	"github.com/maksymenkoml/lingoose/prompt"
 	"github.com/maksymenkoml/lingoose/thread"
 	"github.com/maksymenkoml/lingoose/types"
 )
 
-var ragFusionPrompts = []string{
-	"You are a helpful assistant that generates multiple search queries based on a single input query.",
-	"Generate multiple search queries related to: %s",
-	"OUTPUT (4 queries):",
-}
-
 type Fusion struct {
 	RAG
 	llm LLM
@@ -31,6 +26,11 @@ func NewFusion(index *index.Index, llm LLM) *Fusion {
 	}
 }
 
+func (r *Fusion) WithPrompts(prompts *prompt.Registry) *Fusion {
+	r.prompts = prompts
+	return r
+}
+
 func (r *Fusion) Retrieve(ctx context.Context, query string) ([]string, error) {
 	ctx, span, err := r.startObserveSpan(
 		ctx,
@@ -63,25 +63,40 @@ func (r *Fusion) retrieve(ctx context.Context, query string) ([]string, error) {
 		return nil, fmt.Errorf("llm is not set")
 	}
 
+	systemPrompt, err := prompt.Format(r.prompts, FusionSystemPromptName, fusionSystemPrompt, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	queryPrompt, err := prompt.Format(r.prompts, FusionQueryPromptName, fusionQueryPrompt, types.M{"query": query})
+	if err != nil {
+		return nil, err
+	}
+
+	outputPrompt, err := prompt.Format(r.prompts, FusionOutputPromptName, fusionOutputPrompt, nil)
+	if err != nil {
+		return nil, err
+	}
+
 	t := thread.New().AddMessages(
 		thread.NewSystemMessage().AddContent(
 			thread.NewTextContent(
-				ragFusionPrompts[0],
+				systemPrompt,
 			),
 		),
 		thread.NewUserMessage().AddContent(
 			thread.NewTextContent(
-				fmt.Sprintf(ragFusionPrompts[1], query),
+				queryPrompt,
 			),
 		),
 		thread.NewUserMessage().AddContent(
 			thread.NewTextContent(
-				ragFusionPrompts[2],
+				outputPrompt,
 			),
 		),
 	)
 
-	err := r.llm.Generate(ctx, t)
+	err = r.llm.Generate(ctx, t)
 	if err != nil {
 		return nil, err
 	}
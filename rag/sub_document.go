@@ -6,6 +6,7 @@ import (
 
 	"github.com/maksymenkoml/lingoose/document"
 	"github.com/maksymenkoml/lingoose/index"
+	"github.com/maksymenkoml/lingoose/prompt"
 	"github.com/maksymenkoml/lingoose/textsplitter"
 	"github.com/maksymenkoml/lingoose/thread"
 	"github.com/maksymenkoml/lingoose/types"
@@ -56,6 +57,11 @@ func (r *SubDocumentRAG) WithTopK(topK uint) *SubDocumentRAG {
 	return r
 }
 
+func (r *SubDocumentRAG) WithPrompts(prompts *prompt.Registry) *SubDocumentRAG {
+	r.prompts = prompts
+	return r
+}
+
 func (r *SubDocumentRAG) WithLoader(sourceRegexp *regexp.Regexp, loader Loader) *SubDocumentRAG {
 	r.loaders[sourceRegexp] = loader
 	return r
@@ -107,17 +113,25 @@ func (r *SubDocumentRAG) generateSubDocuments(
 	var subDocuments []document.Document
 
 	for _, doc := range documents {
+		summarizePrompt, err := prompt.Format(
+			r.prompts,
+			SubDocumentSummarizePromptName,
+			SubDocumentRAGSummarizePrompt,
+			types.M{
+				"context": doc.Content,
+			},
+		)
+		if err != nil {
+			return nil, err
+		}
+
 		t := thread.New().AddMessages(
 			thread.NewUserMessage().AddContent(
-				thread.NewTextContent(SubDocumentRAGSummarizePrompt).Format(
-					types.M{
-						"context": doc.Content,
-					},
-				),
+				thread.NewTextContent(summarizePrompt),
 			),
 		)
 
-		err := r.llm.Generate(ctx, t)
+		err = r.llm.Generate(ctx, t)
 		if err != nil {
 			return nil, err
 		}
@@ -0,0 +1,194 @@
+package compactor
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/maksymenkoml/lingoose/prompt"
+	"github.com/maksymenkoml/lingoose/thread"
+	"github.com/maksymenkoml/lingoose/types"
+)
+
+const (
+	DefaultKeepMessages = 10
+)
+
+type LLM interface {
+	Generate(context.Context, *thread.Thread) error
+}
+
+// Compactor condenses the older messages of a thread into a running summary
+// system message written by an LLM, keeping the most recent messages verbatim.
+type Compactor struct {
+	llm          LLM
+	keepMessages int
+	maxTokens    int
+	tokenCountFn thread.TokenCountFn
+	prompt       string
+}
+
+func New(llm LLM) *Compactor {
+	return &Compactor{
+		llm:          llm,
+		keepMessages: DefaultKeepMessages,
+		tokenCountFn: thread.EstimateTokens,
+		prompt:       summaryPrompt,
+	}
+}
+
+// WithKeepMessages sets the number of most recent messages that are never summarized.
+func (c *Compactor) WithKeepMessages(keepMessages int) *Compactor {
+	c.keepMessages = keepMessages
+	return c
+}
+
+// WithMaxTokens sets the token budget of the thread: Compact does nothing until the
+// thread exceeds it. Without a budget the thread is never compacted. If tokenCountFn is
+// nil thread.EstimateTokens is used.
+func (c *Compactor) WithMaxTokens(maxTokens int, tokenCountFn thread.TokenCountFn) *Compactor {
+	c.maxTokens = maxTokens
+	if tokenCountFn != nil {
+		c.tokenCountFn = tokenCountFn
+	}
+	return c
+}
+
+// WithPrompt sets the prompt template used to generate the summary. The template
+// receives the previous summary as {{.summary}} and the messages to condense as
+// {{.conversation}}. Compact fails if the template cannot be parsed or executed.
+func (c *Compactor) WithPrompt(prompt string) *Compactor {
+	c.prompt = prompt
+	return c
+}
+
+// Compact replaces the messages older than the most recent ones with a summary
+// system message, once the thread exceeds the token budget. A summary produced by a previous compaction is refined rather than
+// replaced. System messages are preserved and a tool call is never separated from
+// its tool responses.
+func (c *Compactor) Compact(ctx context.Context, t *thread.Thread) error {
+	if t == nil || !c.exceedsBudget(t) {
+		return nil
+	}
+
+	cut := len(t.Messages) - c.keepMessages
+	for cut > 0 && cut < len(t.Messages) && t.Messages[cut].Role == thread.RoleTool {
+		cut--
+	}
+	if cut <= 0 {
+		return nil
+	}
+
+	var systemMessages []*thread.Message
+	var oldMessages []*thread.Message
+	var summary string
+	for _, message := range t.Messages[:cut] {
+		if previousSummary, isSummary := summaryFromMessage(message); isSummary {
+			summary = previousSummary
+		} else if message.Role == thread.RoleSystem {
+			systemMessages = append(systemMessages, message)
+		} else {
+			oldMessages = append(oldMessages, message)
+		}
+	}
+
+	if len(oldMessages) == 0 {
+		return nil
+	}
+
+	summary, err := c.summarize(ctx, summary, oldMessages)
+	if err != nil {
+		return err
+	}
+
+	messages := append(systemMessages, thread.NewSystemMessage().AddContent(
+		thread.NewTextContent(summaryMessagePrefix+summary),
+	))
+	t.Messages = append(messages, t.Messages[cut:]...)
+
+	return nil
+}
+
+func (c *Compactor) exceedsBudget(t *thread.Thread) bool {
+	if c.maxTokens <= 0 {
+		return false
+	}
+
+	tokens := 0
+	for _, message := range t.Messages {
+		tokens += c.tokenCountFn(message)
+	}
+
+	return tokens > c.maxTokens
+}
+
+func (c *Compactor) summarize(ctx context.Context, summary string, messages []*thread.Message) (string, error) {
+	text, err := prompt.Format(nil, summaryPromptName, c.prompt, types.M{
+		"summary":      summary,
+		"conversation": messagesToTranscript(messages),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	t := thread.New().AddMessage(
+		thread.NewUserMessage().AddContent(thread.NewTextContent(text)),
+	)
+
+	err = c.llm.Generate(ctx, t)
+	if err != nil {
+		return "", err
+	}
+
+	lastMessage := t.LastMessage()
+	if lastMessage.Role != thread.RoleAssistant || len(lastMessage.Contents) == 0 {
+		return "", fmt.Errorf("no summary generated")
+	}
+
+	return strings.TrimSpace(lastMessage.Contents[0].AsString()), nil
+}
+
+func summaryFromMessage(m *thread.Message) (string, bool) {
+	if m.Role != thread.RoleSystem || len(m.Contents) != 1 {
+		return "", false
+	}
+
+	text := m.Contents[0].AsString()
+	if !strings.HasPrefix(text, summaryMessagePrefix) {
+		return "", false
+	}
+
+	return strings.TrimPrefix(text, summaryMessagePrefix), true
+}
+
+func messagesToTranscript(messages []*thread.Message) string {
+	var sb strings.Builder
+	for _, message := range messages {
+		for _, content := range message.Contents {
+			switch content.Type {
+			case thread.ContentTypeText:
+				sb.WriteString(string(message.Role) + ": " + content.AsString() + "\n")
+			case thread.ContentTypeImage:
+				sb.WriteString(string(message.Role) + ": [image]\n")
+			case thread.ContentTypeToolCall:
+				for _, toolCallData := range content.AsToolCallData() {
+					sb.WriteString(string(message.Role) + ": called tool " + toolCallData.Name +
+						" with arguments " + toolCallData.Arguments + "\n")
+				}
+			case thread.ContentTypeToolResponse:
+				if toolResponseData := content.AsToolResponseData(); toolResponseData != nil {
+					sb.WriteString(string(message.Role) + ": tool " + toolResponseData.Name +
+						" returned " + toolResponseData.Result + "\n")
+				}
+			case thread.ContentTypeAudio:
+				sb.WriteString(string(message.Role) + ": [audio]\n")
+			case thread.ContentTypeFile:
+				if fileData := content.AsFileData(); fileData != nil {
+					sb.WriteString(string(message.Role) + ": [file " + fileData.Name + "]\n")
+				}
+			}
+		}
+	}
+
+	return sb.String()
+}
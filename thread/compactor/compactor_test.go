@@ -0,0 +1,125 @@
+package compactor
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/maksymenkoml/lingoose/prompt"
+	"github.com/maksymenkoml/lingoose/thread"
+)
+
+type summaryLLM struct {
+	prompts []string
+}
+
+func (l *summaryLLM) Generate(_ context.Context, t *thread.Thread) error {
+	l.prompts = append(l.prompts, t.LastMessage().Contents[0].AsString())
+	t.AddMessage(thread.NewAssistantMessage().AddContent(
+		thread.NewTextContent("summary"),
+	))
+	return nil
+}
+
+func TestCompactor_Compact(t *testing.T) {
+	llm := &summaryLLM{}
+	c := New(llm).WithKeepMessages(2).WithMaxTokens(1, nil)
+
+	th := thread.New().AddMessages(
+		thread.NewSystemMessage().AddContent(thread.NewTextContent("system")),
+		thread.NewUserMessage().AddContent(thread.NewTextContent("first question")),
+		thread.NewAssistantMessage().AddContent(thread.NewToolCallContent([]thread.ToolCallData{
+			{ID: "1", Name: "search", Arguments: "{}"},
+		})),
+		thread.NewToolMessage().AddContent(thread.NewToolResponseContent(thread.ToolResponseData{
+			ID: "1", Name: "search", Result: "found",
+		})),
+		thread.NewAssistantMessage().AddContent(thread.NewTextContent("first answer")),
+	)
+
+	err := c.Compact(context.Background(), th)
+	if err != nil {
+		t.Fatalf("Compactor.Compact() error = %v", err)
+	}
+
+	// the tool call must not be separated from its response
+	if th.CountMessages() != 5 {
+		t.Fatalf("Compactor.Compact() messages = %d, want 5", th.CountMessages())
+	}
+	if got := th.Messages[1].Contents[0].AsString(); got != summaryMessagePrefix+"summary" {
+		t.Errorf("Compactor.Compact() summary = %q", got)
+	}
+	if !strings.Contains(llm.prompts[0], "user: first question") {
+		t.Errorf("Compactor.Compact() prompt = %q", llm.prompts[0])
+	}
+
+	th.AddMessages(
+		thread.NewUserMessage().AddContent(thread.NewTextContent("second question")),
+		thread.NewAssistantMessage().AddContent(thread.NewTextContent("second answer")),
+	)
+
+	err = c.Compact(context.Background(), th)
+	if err != nil {
+		t.Fatalf("Compactor.Compact() error = %v", err)
+	}
+
+	if th.CountMessages() != 4 {
+		t.Fatalf("Compactor.Compact() messages = %d, want 4", th.CountMessages())
+	}
+	if th.Messages[0].Contents[0].AsString() != "system" {
+		t.Errorf("Compactor.Compact() did not preserve the system message")
+	}
+	if !strings.Contains(llm.prompts[1], "summary") || !strings.Contains(llm.prompts[1], "tool search returned found") {
+		t.Errorf("Compactor.Compact() prompt = %q", llm.prompts[1])
+	}
+}
+
+func TestCompactor_CompactWithinBudget(t *testing.T) {
+	tests := []struct {
+		name      string
+		maxTokens int
+	}{
+		{name: "within budget", maxTokens: 1000},
+		{name: "no budget", maxTokens: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			llm := &summaryLLM{}
+			c := New(llm).WithKeepMessages(1).WithMaxTokens(tt.maxTokens, nil)
+
+			th := thread.New().AddMessages(
+				thread.NewUserMessage().AddContent(thread.NewTextContent("question")),
+				thread.NewAssistantMessage().AddContent(thread.NewTextContent("answer")),
+			)
+
+			err := c.Compact(context.Background(), th)
+			if err != nil {
+				t.Fatalf("Compactor.Compact() error = %v", err)
+			}
+
+			if th.CountMessages() != 2 || len(llm.prompts) != 0 {
+				t.Errorf("Compactor.Compact() compacted a thread within budget")
+			}
+		})
+	}
+}
+
+func TestCompactor_CompactPromptError(t *testing.T) {
+	llm := &summaryLLM{}
+	c := New(llm).WithKeepMessages(1).WithMaxTokens(1, nil).WithPrompt("Summarize {{.conversation")
+
+	th := thread.New().AddMessages(
+		thread.NewUserMessage().AddContent(thread.NewTextContent("question")),
+		thread.NewAssistantMessage().AddContent(thread.NewTextContent("answer")),
+	)
+
+	err := c.Compact(context.Background(), th)
+	if !errors.Is(err, prompt.ErrPromptTemplate) {
+		t.Fatalf("Compactor.Compact() error = %v, want %v", err, prompt.ErrPromptTemplate)
+	}
+
+	if th.CountMessages() != 2 || len(llm.prompts) != 0 {
+		t.Errorf("Compactor.Compact() sent the prompt of an invalid template")
+	}
+}
@@ -0,0 +1,21 @@
+package compactor
+
+const (
+	summaryMessagePrefix = "Summary of the earlier conversation:\n"
+	summaryPromptName    = "compactor/summary"
+
+	//nolint:lll
+	summaryPrompt = `Your job is to produce a concise summary of a conversation between a user and an AI assistant.
+{{if ne .summary ""}}
+We have provided an existing summary of the conversation up to a certain point:
+
+{{.summary}}
+
+Refine the existing summary with the new part of the conversation below.
+{{end}}
+Keep facts, decisions, user preferences, open questions and tool results that may be needed later. Omit greetings and small talk.
+------------
+{{.conversation}}
+------------
+Return only the summary.`
+)
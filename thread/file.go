@@ -0,0 +1,129 @@
+package thread
+
+import (
+	"encoding/base64"
+	"errors"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	// ErrUnsupportedContent is returned by the LLMs asked to send a content they, or
+	// their API client, cannot encode, e.g. audio.
+	ErrUnsupportedContent = errors.New("unsupported content")
+)
+
+// AudioData is an audio clip embedded in the thread, for the audio capable models.
+type AudioData struct {
+	MIMEType string `json:"mime_type"`
+	Data     []byte `json:"data"`
+}
+
+// Base64 returns the audio data encoded as standard base64.
+func (a *AudioData) Base64() string {
+	return base64.StdEncoding.EncodeToString(a.Data)
+}
+
+// Format returns the audio format derived from the MIME type, e.g. "wav" or "mp3".
+func (a *AudioData) Format() string {
+	mimeType, _, _ := mime.ParseMediaType(a.MIMEType)
+	switch mimeType {
+	case "audio/mpeg", "audio/mp3":
+		return "mp3"
+	case "audio/wav", "audio/wave", "audio/x-wav", "audio/vnd.wave":
+		return "wav"
+	}
+
+	_, format, _ := strings.Cut(mimeType, "/")
+	return strings.TrimPrefix(format, "x-")
+}
+
+// FileData is a file or document embedded in the thread, e.g. a PDF. Text holds the
+// text extracted from the file for the LLMs that do not accept documents, see
+// loader.ExtractFiles, and is saved with the thread.
+type FileData struct {
+	Name     string `json:"name"`
+	MIMEType string `json:"mime_type"`
+	Data     []byte `json:"data"`
+	Text     string `json:"text,omitempty"`
+}
+
+// Base64 returns the file data encoded as standard base64.
+func (f *FileData) Base64() string {
+	return base64.StdEncoding.EncodeToString(f.Data)
+}
+
+// NewAudioContent creates an audio content from raw bytes. If mimeType is empty it is
+// detected from the data.
+func NewAudioContent(data []byte, mimeType string) *Content {
+	if mimeType == "" {
+		mimeType = http.DetectContentType(data)
+	}
+
+	return &Content{
+		Type: ContentTypeAudio,
+		Data: AudioData{
+			MIMEType: mimeType,
+			Data:     data,
+		},
+	}
+}
+
+// NewAudioContentFromFile creates an audio content reading the file at path. The MIME
+// type is derived from the file extension, or detected from the data.
+func NewAudioContentFromFile(path string) (*Content, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewAudioContent(data, mime.TypeByExtension(filepath.Ext(path))), nil
+}
+
+// NewFileContent creates a file content from raw bytes. If mimeType is empty it is
+// derived from the extension of name, or detected from the data.
+func NewFileContent(name string, data []byte, mimeType string) *Content {
+	if mimeType == "" {
+		mimeType = mime.TypeByExtension(filepath.Ext(name))
+	}
+	if mimeType == "" {
+		mimeType = http.DetectContentType(data)
+	}
+
+	return &Content{
+		Type: ContentTypeFile,
+		Data: FileData{
+			Name:     name,
+			MIMEType: mimeType,
+			Data:     data,
+		},
+	}
+}
+
+// NewFileContentFromFile creates a file content reading the file at path. The file
+// name is the base name of path.
+func NewFileContentFromFile(path string) (*Content, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewFileContent(filepath.Base(path), data, ""), nil
+}
+
+func (c *Content) AsAudioData() *AudioData {
+	if contentAsAudioData, ok := c.Data.(AudioData); ok {
+		return &contentAsAudioData
+	}
+	return nil
+}
+
+func (c *Content) AsFileData() *FileData {
+	if contentAsFileData, ok := c.Data.(FileData); ok {
+		return &contentAsFileData
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package thread
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestNewFileContent(t *testing.T) {
+	pdf := []byte("%PDF-1.4\n")
+	path := filepath.Join(t.TempDir(), "report.pdf")
+	err := os.WriteFile(path, pdf, 0o600)
+	if err != nil {
+		t.Fatalf("os.WriteFile() error = %v", err)
+	}
+
+	fromFile, err := NewFileContentFromFile(path)
+	if err != nil {
+		t.Fatalf("NewFileContentFromFile() error = %v", err)
+	}
+
+	want := &FileData{Name: "report.pdf", MIMEType: "application/pdf", Data: pdf}
+	for name, content := range map[string]*Content{
+		"bytes": NewFileContent("report.pdf", pdf, ""),
+		"file":  fromFile,
+	} {
+		if content.Type != ContentTypeFile {
+			t.Errorf("%s: Type = %s, want %s", name, content.Type, ContentTypeFile)
+		}
+		if got := content.AsFileData(); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: AsFileData() = %v, want %v", name, got, want)
+		}
+	}
+
+	if got := NewTextContent("text").AsFileData(); got != nil {
+		t.Errorf("AsFileData() = %v, want nil", got)
+	}
+}
+
+func TestNewAudioContent(t *testing.T) {
+	wav := []byte("RIFF\x24\x00\x00\x00WAVEfmt ")
+	path := filepath.Join(t.TempDir(), "speech.mp3")
+	err := os.WriteFile(path, wav, 0o600)
+	if err != nil {
+		t.Fatalf("os.WriteFile() error = %v", err)
+	}
+
+	fromFile, err := NewAudioContentFromFile(path)
+	if err != nil {
+		t.Fatalf("NewAudioContentFromFile() error = %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		content    *Content
+		wantFormat string
+	}{
+		{name: "detected", content: NewAudioContent(wav, ""), wantFormat: "wav"},
+		{name: "file extension", content: fromFile, wantFormat: "mp3"},
+		{name: "explicit", content: NewAudioContent(wav, "audio/ogg"), wantFormat: "ogg"},
+	}
+	for _, tt := range tests {
+		audioData := tt.content.AsAudioData()
+		if audioData == nil {
+			t.Fatalf("%s: AsAudioData() = nil", tt.name)
+		}
+		if got := audioData.Format(); got != tt.wantFormat {
+			t.Errorf("%s: Format() = %s, want %s", tt.name, got, tt.wantFormat)
+		}
+	}
+}
+
+func TestThread_JSON_AudioAndFile(t *testing.T) {
+	original := New().AddMessage(NewUserMessage().
+		AddContent(NewTextContent("summarize the report and the recording")).
+		AddContent(NewFileContent("report.txt", []byte("quarterly report"), "")).
+		AddContent(NewAudioContent([]byte("audio"), "audio/wav")),
+	)
+
+	data, err := json.Marshal(original)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+
+	decoded := New()
+	err = json.Unmarshal(data, decoded)
+	if err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+
+	if !reflect.DeepEqual(decoded, original) {
+		t.Errorf("json round trip = %v, want %v", decoded, original)
+	}
+}
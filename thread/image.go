@@ -0,0 +1,144 @@
+package thread
+
+import (
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	ErrInvalidDataURI = fmt.Errorf("invalid data URI")
+)
+
+const (
+	dataURIPrefix = "data:"
+	base64Marker  = ";base64"
+)
+
+// ImageData is an image embedded in the thread, as opposed to an image URL.
+type ImageData struct {
+	MIMEType string `json:"mime_type"`
+	Data     []byte `json:"data"`
+}
+
+// Base64 returns the image data encoded as standard base64.
+func (i *ImageData) Base64() string {
+	return base64.StdEncoding.EncodeToString(i.Data)
+}
+
+// DataURI returns the image encoded as a base64 data URI.
+func (i *ImageData) DataURI() string {
+	return dataURIPrefix + i.MIMEType + base64Marker + "," + i.Base64()
+}
+
+// NewImageContentFromBytes creates an image content from raw bytes. If mimeType is
+// empty it is detected from the data.
+func NewImageContentFromBytes(data []byte, mimeType string) *Content {
+	if mimeType == "" {
+		mimeType = http.DetectContentType(data)
+	}
+
+	return &Content{
+		Type: ContentTypeImage,
+		Data: ImageData{
+			MIMEType: mimeType,
+			Data:     data,
+		},
+	}
+}
+
+// NewImageContentFromFile creates an image content reading the file at path. The MIME
+// type is derived from the file extension, or detected from the data.
+func NewImageContentFromFile(path string) (*Content, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewImageContentFromBytes(data, mime.TypeByExtension(filepath.Ext(path))), nil
+}
+
+// NewImageContentFromBase64 creates an image content from standard base64 encoded
+// data. If mimeType is empty it is detected from the data.
+func NewImageContentFromBase64(encodedData string, mimeType string) (*Content, error) {
+	data, err := base64.StdEncoding.DecodeString(encodedData)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewImageContentFromBytes(data, mimeType), nil
+}
+
+// NewImageContentFromDataURI creates an image content from a data URI, e.g.
+// "data:image/png;base64,iVBORw0KGgo...".
+func NewImageContentFromDataURI(dataURI string) (*Content, error) {
+	imageData, err := parseDataURI(dataURI)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Content{
+		Type: ContentTypeImage,
+		Data: *imageData,
+	}, nil
+}
+
+// AsImageData returns the embedded image of the content. Images referenced by a data
+// URI string are decoded. It returns nil for images referenced by URL or file path.
+func (c *Content) AsImageData() *ImageData {
+	if c.Type != ContentTypeImage {
+		return nil
+	}
+
+	switch data := c.Data.(type) {
+	case ImageData:
+		return &data
+	case string:
+		if !strings.HasPrefix(data, dataURIPrefix) {
+			return nil
+		}
+
+		imageData, err := parseDataURI(data)
+		if err != nil {
+			return nil
+		}
+
+		return imageData
+	}
+
+	return nil
+}
+
+func parseDataURI(dataURI string) (*ImageData, error) {
+	if !strings.HasPrefix(dataURI, dataURIPrefix) {
+		return nil, fmt.Errorf("%w: missing %s prefix", ErrInvalidDataURI, dataURIPrefix)
+	}
+
+	header, encodedData, found := strings.Cut(strings.TrimPrefix(dataURI, dataURIPrefix), ",")
+	if !found {
+		return nil, fmt.Errorf("%w: missing data", ErrInvalidDataURI)
+	}
+
+	if !strings.HasSuffix(header, base64Marker) {
+		return nil, fmt.Errorf("%w: only base64 data is supported", ErrInvalidDataURI)
+	}
+
+	data, err := base64.StdEncoding.DecodeString(encodedData)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidDataURI, err)
+	}
+
+	mimeType := strings.TrimSuffix(header, base64Marker)
+	if mimeType == "" {
+		mimeType = http.DetectContentType(data)
+	}
+
+	return &ImageData{
+		MIMEType: mimeType,
+		Data:     data,
+	}, nil
+}
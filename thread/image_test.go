@@ -0,0 +1,59 @@
+package thread
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+var pngHeader = []byte("\x89PNG\r\n\x1a\n")
+
+func TestNewImageContent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "image.png")
+	err := os.WriteFile(path, pngHeader, 0o600)
+	if err != nil {
+		t.Fatalf("os.WriteFile() error = %v", err)
+	}
+
+	fromFile, err := NewImageContentFromFile(path)
+	if err != nil {
+		t.Fatalf("NewImageContentFromFile() error = %v", err)
+	}
+
+	fromBase64, err := NewImageContentFromBase64("iVBORw0KGgo=", "")
+	if err != nil {
+		t.Fatalf("NewImageContentFromBase64() error = %v", err)
+	}
+
+	fromDataURI, err := NewImageContentFromDataURI("data:image/png;base64,iVBORw0KGgo=")
+	if err != nil {
+		t.Fatalf("NewImageContentFromDataURI() error = %v", err)
+	}
+
+	want := &ImageData{MIMEType: "image/png", Data: pngHeader}
+	for name, content := range map[string]*Content{
+		"bytes":    NewImageContentFromBytes(pngHeader, ""),
+		"file":     fromFile,
+		"base64":   fromBase64,
+		"data URI": fromDataURI,
+		"URL":      NewImageContentFromURL("data:image/png;base64,iVBORw0KGgo="),
+	} {
+		if got := content.AsImageData(); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: AsImageData() = %v, want %v", name, got, want)
+		}
+	}
+
+	if got := want.DataURI(); got != "data:image/png;base64,iVBORw0KGgo=" {
+		t.Errorf("ImageData.DataURI() = %s", got)
+	}
+
+	if got := NewImageContentFromURL("https://example.com/image.png").AsImageData(); got != nil {
+		t.Errorf("AsImageData() = %v, want nil for URL", got)
+	}
+
+	_, err = NewImageContentFromDataURI("data:image/png,not-base64")
+	if err == nil {
+		t.Errorf("NewImageContentFromDataURI() error = nil, want %v", ErrInvalidDataURI)
+	}
+}
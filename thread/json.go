@@ -0,0 +1,216 @@
+package thread
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+var (
+	ErrUnknownContentType = fmt.Errorf("unknown content type")
+)
+
+type jsonThread struct {
+	Messages []*Message `json:"messages"`
+}
+
+type jsonMessage struct {
+	Role     Role       `json:"role"`
+	Contents []*Content `json:"contents"`
+}
+
+type jsonContent struct {
+	Type ContentType     `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+// MarshalJSON encodes the thread and all its messages as JSON.
+func (t *Thread) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonThread{
+		Messages: t.Messages,
+	})
+}
+
+// UnmarshalJSON decodes a thread previously encoded with MarshalJSON.
+func (t *Thread) UnmarshalJSON(data []byte) error {
+	var jt jsonThread
+	err := json.Unmarshal(data, &jt)
+	if err != nil {
+		return err
+	}
+
+	t.Messages = jt.Messages
+	return nil
+}
+
+// MarshalJSON encodes the message role and its contents as JSON.
+func (m *Message) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonMessage{
+		Role:     m.Role,
+		Contents: m.Contents,
+	})
+}
+
+// UnmarshalJSON decodes a message previously encoded with MarshalJSON.
+func (m *Message) UnmarshalJSON(data []byte) error {
+	var jm jsonMessage
+	err := json.Unmarshal(data, &jm)
+	if err != nil {
+		return err
+	}
+
+	m.Role = jm.Role
+	m.Contents = jm.Contents
+	return nil
+}
+
+// MarshalJSON encodes the content as JSON. The content type is stored alongside
+// the data so that UnmarshalJSON can restore the original Go type.
+func (c *Content) MarshalJSON() ([]byte, error) {
+	data, err := json.Marshal(c.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(jsonContent{
+		Type: c.Type,
+		Data: data,
+	})
+}
+
+// UnmarshalJSON decodes a content previously encoded with MarshalJSON, restoring
+// the Go type of Data according to the content type.
+func (c *Content) UnmarshalJSON(data []byte) error {
+	var jc jsonContent
+	err := json.Unmarshal(data, &jc)
+	if err != nil {
+		return err
+	}
+
+	contentData, err := unmarshalContentData(jc.Type, jc.Data)
+	if err != nil {
+		return err
+	}
+
+	c.Type = jc.Type
+	c.Data = contentData
+	return nil
+}
+
+func unmarshalContentData(contentType ContentType, data json.RawMessage) (any, error) {
+	switch contentType {
+	case ContentTypeText:
+		var s string
+		err := json.Unmarshal(data, &s)
+		return s, err
+	case ContentTypeImage:
+		// images are stored either as URL strings or as embedded image data
+		var s string
+		if err := json.Unmarshal(data, &s); err == nil {
+			return s, nil
+		}
+		var imageData ImageData
+		err := json.Unmarshal(data, &imageData)
+		return imageData, err
+	case ContentTypeToolCall:
+		var toolCallData []ToolCallData
+		err := json.Unmarshal(data, &toolCallData)
+		return toolCallData, err
+	case ContentTypeToolResponse:
+		var toolResponseData ToolResponseData
+		err := json.Unmarshal(data, &toolResponseData)
+		return toolResponseData, err
+	case ContentTypeAudio:
+		var audioData AudioData
+		err := json.Unmarshal(data, &audioData)
+		return audioData, err
+	case ContentTypeFile:
+		var fileData FileData
+		err := json.Unmarshal(data, &fileData)
+		return fileData, err
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownContentType, contentType)
+	}
+}
+
+type jsonTree struct {
+	Nodes []jsonNode `json:"nodes"`
+	Head  string     `json:"head,omitempty"`
+}
+
+type jsonNode struct {
+	ID       string   `json:"id"`
+	ParentID string   `json:"parent_id,omitempty"`
+	Message  *Message `json:"message"`
+	Active   bool     `json:"active,omitempty"`
+}
+
+// MarshalJSON encodes every branch of the tree as JSON, along with its active branch.
+func (tr *Tree) MarshalJSON() ([]byte, error) {
+	jt := jsonTree{
+		Nodes: []jsonNode{},
+	}
+	if tr.head != tr.root {
+		jt.Head = tr.head.ID
+	}
+
+	// parents are encoded before their children
+	queue := append([]*Node{}, tr.root.children...)
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = append(queue[1:], node.children...)
+
+		jn := jsonNode{
+			ID:      node.ID,
+			Message: node.Message,
+			Active:  node.parent.active == node,
+		}
+		if node.parent != tr.root {
+			jn.ParentID = node.parent.ID
+		}
+
+		jt.Nodes = append(jt.Nodes, jn)
+	}
+
+	return json.Marshal(jt)
+}
+
+// UnmarshalJSON decodes a tree previously encoded with MarshalJSON.
+func (tr *Tree) UnmarshalJSON(data []byte) error {
+	var jt jsonTree
+	err := json.Unmarshal(data, &jt)
+	if err != nil {
+		return err
+	}
+
+	*tr = *NewTree()
+	for _, jn := range jt.Nodes {
+		parent := tr.root
+		if jn.ParentID != "" {
+			parent, err = tr.Node(jn.ParentID)
+			if err != nil {
+				return err
+			}
+		}
+
+		node := &Node{
+			ID:      jn.ID,
+			Message: jn.Message,
+			parent:  parent,
+		}
+
+		parent.children = append(parent.children, node)
+		if jn.Active {
+			parent.active = node
+		}
+		tr.nodes[node.ID] = node
+	}
+
+	if jt.Head != "" {
+		tr.head, err = tr.Node(jt.Head)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,72 @@
+package thread
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestThread_JSON(t *testing.T) {
+	th := New().AddMessages(
+		NewSystemMessage().AddContent(
+			NewTextContent("You are a helpful assistant."),
+		),
+		NewUserMessage().AddContent(
+			NewTextContent("What is in this image?"),
+		).AddContent(
+			NewImageContentFromURL("https://example.com/image.png"),
+		).AddContent(
+			NewImageContentFromBytes([]byte("\x89PNG\r\n\x1a\n"), "image/png"),
+		),
+		NewAssistantMessage().AddContent(
+			NewToolCallContent([]ToolCallData{
+				{
+					ID:        "call_1",
+					Name:      "search",
+					Arguments: `{"query":"image"}`,
+				},
+			}),
+		),
+		NewToolMessage().AddContent(
+			NewToolResponseContent(ToolResponseData{
+				ID:     "call_1",
+				Name:   "search",
+				Result: `{"result":"a cat"}`,
+			}),
+		),
+		NewAssistantMessage().AddContent(
+			NewTextContent("It is a cat."),
+		),
+	)
+
+	data, err := json.Marshal(th)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+
+	got := New()
+	err = json.Unmarshal(data, got)
+	if err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+
+	if !reflect.DeepEqual(got, th) {
+		t.Errorf("json round trip = %v, want %v", got, th)
+	}
+
+	if got.Messages[2].Contents[0].AsToolCallData() == nil {
+		t.Errorf("AsToolCallData() = nil, want []ToolCallData")
+	}
+
+	if got.Messages[3].Contents[0].AsToolResponseData() == nil {
+		t.Errorf("AsToolResponseData() = nil, want ToolResponseData")
+	}
+}
+
+func TestContent_UnmarshalJSON_UnknownType(t *testing.T) {
+	var c Content
+	err := json.Unmarshal([]byte(`{"type":"unknown","data":"x"}`), &c)
+	if err == nil {
+		t.Errorf("json.Unmarshal() error = nil, want %v", ErrUnknownContentType)
+	}
+}
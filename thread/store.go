@@ -0,0 +1,19 @@
+package thread
+
+import (
+	"context"
+	"fmt"
+)
+
+var (
+	ErrThreadNotFound = fmt.Errorf("thread not found")
+)
+
+// Store persists threads by conversation ID. Implementations must return an
+// error wrapping ErrThreadNotFound when the requested thread does not exist.
+type Store interface {
+	Save(ctx context.Context, id string, t *Thread) error
+	Load(ctx context.Context, id string) (*Thread, error)
+	List(ctx context.Context) ([]string, error)
+	Delete(ctx context.Context, id string) error
+}
@@ -0,0 +1,146 @@
+package filesystem
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/maksymenkoml/lingoose/thread"
+)
+
+var _ thread.Store = &Store{}
+
+var (
+	ErrInvalidID = fmt.Errorf("invalid thread id")
+)
+
+const (
+	fileExtension = ".json"
+)
+
+// Store saves each thread as a JSON file named after its conversation ID
+// inside a base directory.
+type Store struct {
+	dir string
+	mu  sync.RWMutex
+}
+
+func New(dir string) *Store {
+	return &Store{
+		dir: dir,
+	}
+}
+
+func (s *Store) Save(ctx context.Context, id string, t *thread.Thread) error {
+	_ = ctx
+	path, err := s.path(id)
+	if err != nil {
+		return err
+	}
+
+	jsonContent, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	err = os.MkdirAll(s.dir, 0700)
+	if err != nil {
+		return err
+	}
+
+	// write to a temporary file first so that a crash never leaves a truncated thread
+	tmpPath := path + ".tmp"
+	err = os.WriteFile(tmpPath, jsonContent, 0600)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmpPath, path)
+}
+
+func (s *Store) Load(ctx context.Context, id string) (*thread.Thread, error) {
+	_ = ctx
+	path, err := s.path(id)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", thread.ErrThreadNotFound, id)
+	} else if err != nil {
+		return nil, err
+	}
+
+	t := thread.New()
+	err = json.Unmarshal(content, t)
+	if err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+func (s *Store) List(ctx context.Context) ([]string, error) {
+	_ = ctx
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entries, err := os.ReadDir(s.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return []string{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	ids := []string{}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), fileExtension) {
+			continue
+		}
+
+		ids = append(ids, strings.TrimSuffix(entry.Name(), fileExtension))
+	}
+
+	sort.Strings(ids)
+
+	return ids, nil
+}
+
+func (s *Store) Delete(ctx context.Context, id string) error {
+	_ = ctx
+	path, err := s.path(id)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	err = os.Remove(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("%w: %s", thread.ErrThreadNotFound, id)
+	}
+
+	return err
+}
+
+func (s *Store) path(id string) (string, error) {
+	if id == "" || id == "." || id == ".." || strings.ContainsAny(id, `/\`) {
+		return "", fmt.Errorf("%w: %q", ErrInvalidID, id)
+	}
+
+	return filepath.Join(s.dir, id+fileExtension), nil
+}
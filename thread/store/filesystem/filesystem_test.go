@@ -0,0 +1,57 @@
+package filesystem
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/maksymenkoml/lingoose/thread"
+)
+
+func TestStore(t *testing.T) {
+	ctx := context.Background()
+	s := New(t.TempDir())
+
+	th := thread.New().AddMessage(
+		thread.NewUserMessage().AddContent(
+			thread.NewTextContent("Hello"),
+		),
+	)
+
+	err := s.Save(ctx, "session-1", th)
+	if err != nil {
+		t.Fatalf("Store.Save() error = %v", err)
+	}
+
+	got, err := s.Load(ctx, "session-1")
+	if err != nil {
+		t.Fatalf("Store.Load() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, th) {
+		t.Errorf("Store.Load() = %v, want %v", got, th)
+	}
+
+	ids, err := s.List(ctx)
+	if err != nil {
+		t.Fatalf("Store.List() error = %v", err)
+	}
+	if !reflect.DeepEqual(ids, []string{"session-1"}) {
+		t.Errorf("Store.List() = %v, want [session-1]", ids)
+	}
+
+	err = s.Delete(ctx, "session-1")
+	if err != nil {
+		t.Fatalf("Store.Delete() error = %v", err)
+	}
+
+	_, err = s.Load(ctx, "session-1")
+	if !errors.Is(err, thread.ErrThreadNotFound) {
+		t.Errorf("Store.Load() error = %v, want %v", err, thread.ErrThreadNotFound)
+	}
+
+	err = s.Save(ctx, "../escape", th)
+	if !errors.Is(err, ErrInvalidID) {
+		t.Errorf("Store.Save() error = %v, want %v", err, ErrInvalidID)
+	}
+}
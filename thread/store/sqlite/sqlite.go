@@ -0,0 +1,196 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sync"
+
+	"github.com/maksymenkoml/lingoose/thread"
+)
+
+var _ thread.Store = &Store{}
+
+const (
+	defaultTable = "threads"
+)
+
+var (
+	ErrInvalidTable = errors.New("invalid table name")
+)
+
+// tablePattern matches the unquoted SQL identifiers, the table name is part of the
+// statements and cannot be passed as a parameter.
+var tablePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Store saves threads as JSON documents in a SQLite table. The caller is
+// responsible for opening the database with the SQLite driver of its choice.
+type Store struct {
+	db           *sql.DB
+	table        string
+	tableCreated bool
+	mu           sync.Mutex
+}
+
+// Options configures the store. Table must be a plain SQL identifier, letters, digits
+// and underscores, otherwise every operation fails with ErrInvalidTable.
+type Options struct {
+	DB    *sql.DB
+	Table string
+}
+
+func New(options Options) *Store {
+	table := options.Table
+	if table == "" {
+		table = defaultTable
+	}
+
+	return &Store{
+		db:    options.DB,
+		table: table,
+	}
+}
+
+func (s *Store) Save(ctx context.Context, id string, t *thread.Thread) error {
+	err := s.createTableIfRequired(ctx)
+	if err != nil {
+		return err
+	}
+
+	jsonContent, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.ExecContext(
+		ctx,
+		fmt.Sprintf(
+			"INSERT INTO %s (id, thread) VALUES (?, ?) "+
+				"ON CONFLICT(id) DO UPDATE SET thread = excluded.thread, updated_at = CURRENT_TIMESTAMP",
+			s.table,
+		),
+		id,
+		string(jsonContent),
+	)
+
+	return err
+}
+
+func (s *Store) Load(ctx context.Context, id string) (*thread.Thread, error) {
+	err := s.createTableIfRequired(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var content string
+	err = s.db.QueryRowContext(
+		ctx,
+		fmt.Sprintf("SELECT thread FROM %s WHERE id = ?", s.table),
+		id,
+	).Scan(&content)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", thread.ErrThreadNotFound, id)
+	} else if err != nil {
+		return nil, err
+	}
+
+	t := thread.New()
+	err = json.Unmarshal([]byte(content), t)
+	if err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+func (s *Store) List(ctx context.Context) ([]string, error) {
+	err := s.createTableIfRequired(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(
+		ctx,
+		fmt.Sprintf("SELECT id FROM %s ORDER BY id", s.table),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []string{}
+	for rows.Next() {
+		var id string
+		scanErr := rows.Scan(&id)
+		if scanErr != nil {
+			return nil, scanErr
+		}
+		ids = append(ids, id)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+func (s *Store) Delete(ctx context.Context, id string) error {
+	err := s.createTableIfRequired(ctx)
+	if err != nil {
+		return err
+	}
+
+	result, err := s.db.ExecContext(
+		ctx,
+		fmt.Sprintf("DELETE FROM %s WHERE id = ?", s.table),
+		id,
+	)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return fmt.Errorf("%w: %s", thread.ErrThreadNotFound, id)
+	}
+
+	return nil
+}
+
+func (s *Store) createTableIfRequired(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.tableCreated {
+		return nil
+	}
+
+	if !tablePattern.MatchString(s.table) {
+		return fmt.Errorf("%w: %q", ErrInvalidTable, s.table)
+	}
+
+	_, err := s.db.ExecContext(
+		ctx,
+		fmt.Sprintf(
+			"CREATE TABLE IF NOT EXISTS %s ("+
+				"id TEXT PRIMARY KEY, "+
+				"thread TEXT NOT NULL, "+
+				"updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP)",
+			s.table,
+		),
+	)
+	if err != nil {
+		return err
+	}
+
+	s.tableCreated = true
+
+	return nil
+}
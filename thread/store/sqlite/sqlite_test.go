@@ -0,0 +1,136 @@
+//go:build sqlite
+
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	_ "modernc.org/sqlite"
+
+	"github.com/maksymenkoml/lingoose/thread"
+)
+
+// The tests need a SQLite driver, they are run with: go test -tags sqlite
+
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "threads.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	return db
+}
+
+func TestStore(t *testing.T) {
+	ctx := context.Background()
+
+	db := openTestDB(t)
+	s := New(Options{DB: db, Table: "sessions"})
+
+	th := thread.New().AddMessage(
+		thread.NewUserMessage().AddContent(
+			thread.NewTextContent("Hello"),
+		),
+	)
+
+	err := s.Save(ctx, "session-2", thread.New())
+	if err != nil {
+		t.Fatalf("Store.Save() error = %v", err)
+	}
+
+	// saving again replaces the thread
+	for i := 0; i < 2; i++ {
+		err = s.Save(ctx, "session-1", th)
+		if err != nil {
+			t.Fatalf("Store.Save() error = %v", err)
+		}
+	}
+
+	got, err := s.Load(ctx, "session-1")
+	if err != nil {
+		t.Fatalf("Store.Load() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, th) {
+		t.Errorf("Store.Load() = %v, want %v", got, th)
+	}
+
+	ids, err := s.List(ctx)
+	if err != nil {
+		t.Fatalf("Store.List() error = %v", err)
+	}
+	if !reflect.DeepEqual(ids, []string{"session-1", "session-2"}) {
+		t.Errorf("Store.List() = %v, want [session-1 session-2]", ids)
+	}
+
+	err = s.Delete(ctx, "session-1")
+	if err != nil {
+		t.Fatalf("Store.Delete() error = %v", err)
+	}
+
+	_, err = s.Load(ctx, "session-1")
+	if !errors.Is(err, thread.ErrThreadNotFound) {
+		t.Errorf("Store.Load() error = %v, want %v", err, thread.ErrThreadNotFound)
+	}
+
+	err = s.Delete(ctx, "session-1")
+	if !errors.Is(err, thread.ErrThreadNotFound) {
+		t.Errorf("Store.Delete() error = %v, want %v", err, thread.ErrThreadNotFound)
+	}
+}
+
+func TestStore_InvalidTable(t *testing.T) {
+	ctx := context.Background()
+
+	db := openTestDB(t)
+	_, err := db.ExecContext(ctx, "CREATE TABLE users (id TEXT PRIMARY KEY)")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, table := range []string{"threads; DROP TABLE users", "1threads", `"threads"`, "thread-store"} {
+		s := New(Options{DB: db, Table: table})
+
+		if err = s.Save(ctx, "session-1", thread.New()); !errors.Is(err, ErrInvalidTable) {
+			t.Errorf("Store.Save() with table %q error = %v, want %v", table, err, ErrInvalidTable)
+		}
+		if _, err = s.Load(ctx, "session-1"); !errors.Is(err, ErrInvalidTable) {
+			t.Errorf("Store.Load() with table %q error = %v, want %v", table, err, ErrInvalidTable)
+		}
+		if _, err = s.List(ctx); !errors.Is(err, ErrInvalidTable) {
+			t.Errorf("Store.List() with table %q error = %v, want %v", table, err, ErrInvalidTable)
+		}
+		if err = s.Delete(ctx, "session-1"); !errors.Is(err, ErrInvalidTable) {
+			t.Errorf("Store.Delete() with table %q error = %v, want %v", table, err, ErrInvalidTable)
+		}
+	}
+
+	rows, err := db.QueryContext(ctx, "SELECT name FROM sqlite_master WHERE type = 'table'")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	var tables []string
+	for rows.Next() {
+		var name string
+		if err = rows.Scan(&name); err != nil {
+			t.Fatal(err)
+		}
+		tables = append(tables, name)
+	}
+	if err = rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(tables, []string{"users"}) {
+		t.Errorf("tables = %v, want [users]", tables)
+	}
+}
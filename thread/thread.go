@@ -2,6 +2,7 @@ package thread
 
 import (
 	"bytes"
+	"fmt"
 	"strings"
 	"text/template"
 
@@ -19,6 +20,8 @@ const (
 	ContentTypeImage        ContentType = "image"
 	ContentTypeToolCall     ContentType = "tool_call"
 	ContentTypeToolResponse ContentType = "tool_response"
+	ContentTypeAudio        ContentType = "audio"
+	ContentTypeFile         ContentType = "file"
 )
 
 type Content struct {
@@ -41,15 +44,15 @@ type Message struct {
 }
 
 type ToolResponseData struct {
-	ID     string
-	Name   string
-	Result string
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Result string `json:"result"`
 }
 
 type ToolCallData struct {
-	ID        string
-	Name      string
-	Arguments string
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
 }
 
 func NewTextContent(text string) *Content {
@@ -139,6 +142,8 @@ func (t *Thread) String() string {
 			case ContentTypeImage:
 				if contentAsString, ok := content.Data.(string); ok {
 					str += "\tImage URL: " + contentAsString + "\n"
+				} else if imageData, isImageData := content.Data.(ImageData); isImageData {
+					str += fmt.Sprintf("\tImage: %s, %d bytes\n", imageData.MIMEType, len(imageData.Data))
 				}
 			case ContentTypeToolCall:
 				for _, toolCallData := range content.Data.([]ToolCallData) {
@@ -150,6 +155,14 @@ func (t *Thread) String() string {
 				str += "\tTool ID: " + content.Data.(ToolResponseData).ID + "\n"
 				str += "\tTool Name: " + content.Data.(ToolResponseData).Name + "\n"
 				str += "\tTool Result: " + content.Data.(ToolResponseData).Result + "\n"
+			case ContentTypeAudio:
+				if audioData := content.AsAudioData(); audioData != nil {
+					str += fmt.Sprintf("\tAudio: %s, %d bytes\n", audioData.MIMEType, len(audioData.Data))
+				}
+			case ContentTypeFile:
+				if fileData := content.AsFileData(); fileData != nil {
+					str += fmt.Sprintf("\tFile: %s, %s, %d bytes\n", fileData.Name, fileData.MIMEType, len(fileData.Data))
+				}
 			}
 		}
 	}
@@ -197,6 +210,9 @@ func (m *Message) ClearContents() *Message {
 	return m
 }
 
+// Format formats the text content as a template with the input. The content is left
+// unchanged if the template cannot be parsed or executed; use prompt.Format to get the
+// error.
 func (c *Content) Format(input types.M) *Content {
 	if c.Type != ContentTypeText || input == nil {
 		return c
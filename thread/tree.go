@@ -0,0 +1,204 @@
+package thread
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrNodeNotFound   = fmt.Errorf("node not found")
+	ErrThreadMismatch = fmt.Errorf("thread does not match the active branch")
+)
+
+// Node is a message of a conversation tree. The children of a node are the alternative
+// continuations of the conversation, e.g. the regenerated replies or the edited
+// versions of the next message.
+type Node struct {
+	ID      string
+	Message *Message
+
+	parent   *Node
+	children []*Node
+	active   *Node
+}
+
+// Parent returns the parent node, or nil for the nodes of the first message.
+func (n *Node) Parent() *Node {
+	if n.parent == nil || n.parent.Message == nil {
+		return nil
+	}
+	return n.parent
+}
+
+// Children returns the alternative continuations of the node, from the oldest.
+func (n *Node) Children() []*Node {
+	return n.children
+}
+
+// Tree is a conversation tree. The active branch goes from the first message to the
+// head of the tree, where new messages are added, and can be linearized into a Thread.
+type Tree struct {
+	root  *Node
+	head  *Node
+	nodes map[string]*Node
+}
+
+func NewTree() *Tree {
+	root := &Node{}
+	return &Tree{
+		root:  root,
+		head:  root,
+		nodes: make(map[string]*Node),
+	}
+}
+
+// NewTreeFromThread creates a tree holding the messages of the thread as its active
+// branch.
+func NewTreeFromThread(t *Thread) *Tree {
+	tree := NewTree()
+	tree.AddMessages(t.Messages...)
+	return tree
+}
+
+// AddMessage adds the message after the head of the tree, and makes it the new head.
+func (tr *Tree) AddMessage(message *Message) *Node {
+	node := &Node{
+		ID:      uuid.NewString(),
+		Message: message,
+		parent:  tr.head,
+	}
+
+	tr.head.children = append(tr.head.children, node)
+	tr.head.active = node
+	tr.head = node
+	tr.nodes[node.ID] = node
+
+	return node
+}
+
+func (tr *Tree) AddMessages(messages ...*Message) *Tree {
+	for _, message := range messages {
+		tr.AddMessage(message)
+	}
+	return tr
+}
+
+// Node returns the node with the given ID.
+func (tr *Tree) Node(id string) (*Node, error) {
+	node, ok := tr.nodes[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNodeNotFound, id)
+	}
+	return node, nil
+}
+
+// Head returns the node where new messages are added, or nil when the tree is empty or
+// forked at its first message.
+func (tr *Tree) Head() *Node {
+	if tr.head == tr.root {
+		return nil
+	}
+	return tr.head
+}
+
+// Siblings returns the alternatives of the node, including the node itself.
+func (tr *Tree) Siblings(id string) ([]*Node, error) {
+	node, err := tr.Node(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return node.parent.children, nil
+}
+
+// Fork moves the head of the tree to the parent of the node, so that the next message
+// added, or generated on the linearized thread, becomes an alternative of the node.
+// It is used to edit a message or to regenerate a reply.
+func (tr *Tree) Fork(id string) error {
+	node, err := tr.Node(id)
+	if err != nil {
+		return err
+	}
+
+	tr.activate(node.parent)
+	tr.head = node.parent
+
+	return nil
+}
+
+// Edit adds the message as an alternative of the node and makes it the new head. The
+// messages following the node are kept in the previous branch.
+func (tr *Tree) Edit(id string, message *Message) (*Node, error) {
+	err := tr.Fork(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return tr.AddMessage(message), nil
+}
+
+// Checkout makes the branch going through the node the active one. The head of the
+// tree moves to the last message of the branch, following the last active
+// continuation of each message after the node.
+func (tr *Tree) Checkout(id string) error {
+	node, err := tr.Node(id)
+	if err != nil {
+		return err
+	}
+
+	tr.activate(node)
+
+	for node.active != nil {
+		node = node.active
+	}
+	tr.head = node
+
+	return nil
+}
+
+// Branch returns the messages of the active branch, from the first one to the head.
+func (tr *Tree) Branch() []*Node {
+	var nodes []*Node
+	for node := tr.head; node != tr.root; node = node.parent {
+		nodes = append([]*Node{node}, nodes...)
+	}
+	return nodes
+}
+
+// Thread linearizes the active branch into a new thread, e.g. to generate the next
+// message. The generated messages can be added back to the tree with Update.
+func (tr *Tree) Thread() *Thread {
+	t := New()
+	for _, node := range tr.Branch() {
+		t.AddMessage(node.Message)
+	}
+	return t
+}
+
+// Update adds to the tree the messages appended to a thread returned by Thread. It
+// returns ErrThreadMismatch if the thread does not start with the active branch.
+func (tr *Tree) Update(t *Thread) error {
+	branch := tr.Branch()
+	if len(t.Messages) < len(branch) {
+		return ErrThreadMismatch
+	}
+
+	for i, node := range branch {
+		if t.Messages[i] != node.Message {
+			return ErrThreadMismatch
+		}
+	}
+
+	tr.AddMessages(t.Messages[len(branch):]...)
+
+	return nil
+}
+
+// activate makes every node on the path from the root to the node the active child of
+// its parent.
+func (tr *Tree) activate(node *Node) {
+	for ; node.parent != nil; node = node.parent {
+		node.parent.active = node
+	}
+}
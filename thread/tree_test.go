@@ -0,0 +1,138 @@
+package thread
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func branchTexts(t *Thread) []string {
+	var texts []string
+	for _, message := range t.Messages {
+		texts = append(texts, message.Contents[0].AsString())
+	}
+	return texts
+}
+
+func textMessage(role Role, text string) *Message {
+	return (&Message{Role: role}).AddContent(NewTextContent(text))
+}
+
+func TestTree(t *testing.T) {
+	tree := NewTreeFromThread(New().AddMessages(
+		textMessage(RoleUser, "question"),
+		textMessage(RoleAssistant, "answer"),
+	))
+
+	branch := tree.Branch()
+	question, answer := branch[0], branch[1]
+
+	// regenerate the answer
+	err := tree.Fork(answer.ID)
+	if err != nil {
+		t.Fatalf("Fork() error = %v", err)
+	}
+
+	generated := tree.Thread()
+	generated.AddMessage(textMessage(RoleAssistant, "better answer"))
+	err = tree.Update(generated)
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	siblings, err := tree.Siblings(answer.ID)
+	if err != nil {
+		t.Fatalf("Siblings() error = %v", err)
+	}
+	if len(siblings) != 2 || siblings[0] != answer {
+		t.Errorf("Siblings() = %v, want the two answers", siblings)
+	}
+
+	// edit the question and answer it
+	edited, err := tree.Edit(question.ID, textMessage(RoleUser, "edited question"))
+	if err != nil {
+		t.Fatalf("Edit() error = %v", err)
+	}
+	tree.AddMessage(textMessage(RoleAssistant, "edited answer"))
+
+	if got, want := branchTexts(tree.Thread()), []string{"edited question", "edited answer"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Thread() = %v, want %v", got, want)
+	}
+	if edited.Parent() != nil {
+		t.Errorf("Parent() = %v, want nil", edited.Parent())
+	}
+
+	// switch back to the original question, following its last active answer
+	err = tree.Checkout(question.ID)
+	if err != nil {
+		t.Fatalf("Checkout() error = %v", err)
+	}
+
+	if got, want := branchTexts(tree.Thread()), []string{"question", "better answer"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Thread() = %v, want %v", got, want)
+	}
+
+	err = tree.Checkout(answer.ID)
+	if err != nil {
+		t.Fatalf("Checkout() error = %v", err)
+	}
+
+	if got, want := branchTexts(tree.Thread()), []string{"question", "answer"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Thread() = %v, want %v", got, want)
+	}
+}
+
+func TestTree_Errors(t *testing.T) {
+	tree := NewTree().AddMessages(textMessage(RoleUser, "question"))
+
+	if err := tree.Checkout("unknown"); !errors.Is(err, ErrNodeNotFound) {
+		t.Errorf("Checkout() error = %v, want %v", err, ErrNodeNotFound)
+	}
+
+	if err := tree.Update(New()); !errors.Is(err, ErrThreadMismatch) {
+		t.Errorf("Update() error = %v, want %v", err, ErrThreadMismatch)
+	}
+
+	other := New().AddMessage(textMessage(RoleUser, "question"))
+	if err := tree.Update(other); !errors.Is(err, ErrThreadMismatch) {
+		t.Errorf("Update() error = %v, want %v", err, ErrThreadMismatch)
+	}
+}
+
+func TestTree_JSON(t *testing.T) {
+	tree := NewTree().AddMessages(
+		textMessage(RoleUser, "question"),
+		textMessage(RoleAssistant, "answer"),
+	)
+
+	answer := tree.Head()
+	_, err := tree.Edit(answer.ID, textMessage(RoleAssistant, "other answer"))
+	if err != nil {
+		t.Fatalf("Edit() error = %v", err)
+	}
+
+	data, err := json.Marshal(tree)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+
+	decoded := NewTree()
+	err = json.Unmarshal(data, decoded)
+	if err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+
+	if got, want := branchTexts(decoded.Thread()), []string{"question", "other answer"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Thread() = %v, want %v", got, want)
+	}
+
+	err = decoded.Checkout(answer.ID)
+	if err != nil {
+		t.Fatalf("Checkout() error = %v", err)
+	}
+
+	if got, want := branchTexts(decoded.Thread()), []string{"question", "answer"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Thread() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,286 @@
+package thread
+
+import (
+	"bytes"
+	"mime"
+	"regexp"
+	"strings"
+)
+
+const (
+	estimatedCharsPerToken      = 4
+	estimatedTokensPerMessage   = 4
+	estimatedTokensPerImageData = 85
+	estimatedBytesPerAudioToken = 1000
+	estimatedTokensPerFilePage  = 1500
+)
+
+var pdfPageRegexp = regexp.MustCompile(`/Type\s*/Page\b`)
+
+// WindowFn selects the messages of a thread that are sent to the model.
+// It must not modify the messages it receives.
+type WindowFn func([]*Message) []*Message
+
+// TokenCountFn returns the number of tokens of a message.
+type TokenCountFn func(*Message) int
+
+// Window returns a new thread holding the messages selected by the window function.
+// The original thread is left untouched. If fn is nil the thread itself is returned.
+func (t *Thread) Window(fn WindowFn) *Thread {
+	if fn == nil {
+		return t
+	}
+
+	messages := make([]*Message, len(t.Messages))
+	copy(messages, t.Messages)
+
+	return &Thread{
+		Messages: fn(messages),
+	}
+}
+
+// WindowLastMessages keeps the last n messages. The window is widened when needed
+// so that a tool call is never separated from its tool responses. If n is less than 1
+// all the messages are kept.
+func WindowLastMessages(n int) WindowFn {
+	return func(messages []*Message) []*Message {
+		if n < 1 {
+			return messages
+		}
+
+		groups := groupToolCalls(messages)
+
+		var selected [][]*Message
+		count := 0
+		for i := len(groups) - 1; i >= 0; i-- {
+			if count >= n {
+				break
+			}
+			selected = append(selected, groups[i])
+			count += len(groups[i])
+		}
+
+		return flattenReversed(selected)
+	}
+}
+
+// WindowTokenBudget keeps all system messages plus the most recent messages that fit
+// in maxTokens. The most recent message group is always kept, even if it exceeds the
+// budget. A tool call is never separated from its tool responses. If countFn is nil
+// EstimateTokens is used.
+func WindowTokenBudget(maxTokens int, countFn TokenCountFn) WindowFn {
+	if countFn == nil {
+		countFn = EstimateTokens
+	}
+
+	return func(messages []*Message) []*Message {
+		keep := make(map[*Message]bool)
+		budget := maxTokens
+
+		for _, message := range messages {
+			if message.Role == RoleSystem {
+				keep[message] = true
+				budget -= countFn(message)
+			}
+		}
+
+		groups := groupToolCalls(messages)
+		for i := len(groups) - 1; i >= 0; i-- {
+			if groups[i][0].Role == RoleSystem {
+				continue
+			}
+
+			tokens := 0
+			for _, message := range groups[i] {
+				tokens += countFn(message)
+			}
+
+			if tokens > budget && i != len(groups)-1 {
+				break
+			}
+
+			budget -= tokens
+			for _, message := range groups[i] {
+				keep[message] = true
+			}
+		}
+
+		var selected []*Message
+		for _, message := range messages {
+			if keep[message] {
+				selected = append(selected, message)
+			}
+		}
+
+		return selected
+	}
+}
+
+// WindowToolSafe wraps a window function and removes tool responses whose tool call
+// has been dropped, and tool calls whose tool responses have been dropped.
+// Use it to make custom window functions safe for providers that reject such threads.
+func WindowToolSafe(fn WindowFn) WindowFn {
+	return func(messages []*Message) []*Message {
+		messages = fn(messages)
+
+		var selected []*Message
+		for _, group := range groupToolCalls(messages) {
+			if isCompleteToolGroup(group) {
+				selected = append(selected, group...)
+			}
+		}
+
+		return selected
+	}
+}
+
+// EstimateTokens roughly estimates the number of tokens of a message without a tokenizer.
+func EstimateTokens(m *Message) int {
+	texts, tokens := tokenizableContents(m)
+
+	chars := 0
+	for _, text := range texts {
+		chars += len(text)
+	}
+
+	return tokens + (chars+estimatedCharsPerToken-1)/estimatedCharsPerToken
+}
+
+// CountTokens returns a TokenCountFn counting the texts of a message with countFn,
+// e.g. a tokenizer. Images, audio and binary files without extracted text are estimated
+// as in EstimateTokens.
+func CountTokens(countFn func(string) int) TokenCountFn {
+	return func(m *Message) int {
+		texts, tokens := tokenizableContents(m)
+
+		for _, text := range texts {
+			tokens += countFn(text)
+		}
+
+		return tokens
+	}
+}
+
+// tokenizableContents returns the texts of a message, and the estimated tokens of the
+// message overhead and of its contents that are not text.
+func tokenizableContents(m *Message) ([]string, int) {
+	var texts []string
+	tokens := estimatedTokensPerMessage
+
+	for _, content := range m.Contents {
+		switch content.Type {
+		case ContentTypeText:
+			texts = append(texts, content.AsString())
+		case ContentTypeImage:
+			tokens += estimatedTokensPerImageData
+		case ContentTypeToolCall:
+			for _, toolCallData := range content.AsToolCallData() {
+				texts = append(texts, toolCallData.Name, toolCallData.Arguments)
+			}
+		case ContentTypeToolResponse:
+			if toolResponseData := content.AsToolResponseData(); toolResponseData != nil {
+				texts = append(texts, toolResponseData.Name, toolResponseData.Result)
+			}
+		case ContentTypeAudio:
+			if audioData := content.AsAudioData(); audioData != nil {
+				tokens += len(audioData.Data) / estimatedBytesPerAudioToken
+			}
+		case ContentTypeFile:
+			if fileData := content.AsFileData(); fileData != nil {
+				fileTexts, fileTokens := fileContents(fileData)
+				texts = append(texts, fileTexts...)
+				tokens += fileTokens
+			}
+		}
+	}
+
+	return texts, tokens
+}
+
+// fileContents returns the texts of a file, and the estimated tokens of a binary file
+// whose text has not been extracted, see loader.ExtractFiles: a fixed cost per page.
+func fileContents(fileData *FileData) ([]string, int) {
+	switch {
+	case fileData.Text != "":
+		return []string{fileData.Name, fileData.Text}, 0
+	case isTextFile(fileData):
+		return []string{fileData.Name, string(fileData.Data)}, 0
+	}
+
+	pages := 1
+	if bytes.HasPrefix(fileData.Data, []byte("%PDF")) {
+		pages = max(len(pdfPageRegexp.FindAllIndex(fileData.Data, -1)), 1)
+	}
+
+	return []string{fileData.Name}, pages * estimatedTokensPerFilePage
+}
+
+func isTextFile(fileData *FileData) bool {
+	mediaType, _, _ := mime.ParseMediaType(fileData.MIMEType)
+
+	return strings.HasPrefix(mediaType, "text/") || mediaType == "application/json"
+}
+
+// groupToolCalls splits messages into groups where a message holding tool calls
+// is followed by all its tool response messages.
+func groupToolCalls(messages []*Message) [][]*Message {
+	var groups [][]*Message
+	for _, message := range messages {
+		if message.Role == RoleTool && len(groups) > 0 && hasToolCalls(groups[len(groups)-1][0]) {
+			groups[len(groups)-1] = append(groups[len(groups)-1], message)
+			continue
+		}
+
+		groups = append(groups, []*Message{message})
+	}
+
+	return groups
+}
+
+func isCompleteToolGroup(group []*Message) bool {
+	if group[0].Role == RoleTool {
+		return false
+	}
+
+	if !hasToolCalls(group[0]) {
+		return true
+	}
+
+	responses := make(map[string]bool)
+	for _, message := range group[1:] {
+		for _, content := range message.Contents {
+			if toolResponseData := content.AsToolResponseData(); toolResponseData != nil {
+				responses[toolResponseData.ID] = true
+			}
+		}
+	}
+
+	for _, content := range group[0].Contents {
+		for _, toolCallData := range content.AsToolCallData() {
+			if !responses[toolCallData.ID] {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+func hasToolCalls(m *Message) bool {
+	for _, content := range m.Contents {
+		if content.Type == ContentTypeToolCall {
+			return true
+		}
+	}
+
+	return false
+}
+
+func flattenReversed(groups [][]*Message) []*Message {
+	var messages []*Message
+	for i := len(groups) - 1; i >= 0; i-- {
+		messages = append(messages, groups[i]...)
+	}
+
+	return messages
+}
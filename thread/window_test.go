@@ -0,0 +1,132 @@
+package thread
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func newWindowTestThread() *Thread {
+	return New().AddMessages(
+		NewSystemMessage().AddContent(NewTextContent("system")),
+		NewUserMessage().AddContent(NewTextContent("first question")),
+		NewAssistantMessage().AddContent(NewTextContent("first answer")),
+		NewUserMessage().AddContent(NewTextContent("second question")),
+		NewAssistantMessage().AddContent(NewToolCallContent([]ToolCallData{
+			{ID: "1", Name: "search", Arguments: "{}"},
+			{ID: "2", Name: "search", Arguments: "{}"},
+		})),
+		NewToolMessage().AddContent(NewToolResponseContent(ToolResponseData{ID: "1", Name: "search"})),
+		NewToolMessage().AddContent(NewToolResponseContent(ToolResponseData{ID: "2", Name: "search"})),
+	)
+}
+
+func TestThread_Window(t *testing.T) {
+	th := newWindowTestThread()
+
+	tests := []struct {
+		name string
+		fn   WindowFn
+		want []*Message
+	}{
+		{
+			name: "nil window",
+			fn:   nil,
+			want: th.Messages,
+		},
+		{
+			name: "last messages keeps tool call with its responses",
+			fn:   WindowLastMessages(2),
+			want: th.Messages[4:],
+		},
+		{
+			name: "last messages",
+			fn:   WindowLastMessages(4),
+			want: th.Messages[3:],
+		},
+		{
+			name: "last messages without a window",
+			fn:   WindowLastMessages(0),
+			want: th.Messages,
+		},
+		{
+			name: "token budget keeps system and most recent messages",
+			fn: WindowTokenBudget(5, func(*Message) int {
+				return 1
+			}),
+			want: append([]*Message{th.Messages[0]}, th.Messages[3:]...),
+		},
+		{
+			name: "token budget always keeps the last group",
+			fn: WindowTokenBudget(0, func(*Message) int {
+				return 1
+			}),
+			want: append([]*Message{th.Messages[0]}, th.Messages[4:]...),
+		},
+		{
+			name: "tool safe drops orphan tool responses",
+			fn: WindowToolSafe(func(messages []*Message) []*Message {
+				return messages[5:]
+			}),
+			want: nil,
+		},
+		{
+			name: "tool safe drops tool calls without responses",
+			fn: WindowToolSafe(func(messages []*Message) []*Message {
+				return messages[3:6]
+			}),
+			want: th.Messages[3:4],
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := th.Window(tt.fn)
+			if !reflect.DeepEqual(got.Messages, tt.want) {
+				t.Errorf("Thread.Window() = %v, want %v", got.Messages, tt.want)
+			}
+			if th.CountMessages() != 7 {
+				t.Errorf("Thread.Window() modified the original thread")
+			}
+		})
+	}
+}
+
+func TestEstimateTokens_Files(t *testing.T) {
+	pdf := []byte("%PDF-1.4 /Type /Pages /Kids [] /Type /Page /Type/Page " + strings.Repeat("x", 100000))
+	extracted := NewFileContent("report.pdf", pdf, "application/pdf")
+	extracted.Data = FileData{Name: "report.pdf", MIMEType: "application/pdf", Data: pdf, Text: "12345678"}
+
+	tests := []struct {
+		name    string
+		content *Content
+		want    int
+	}{
+		{
+			name:    "text file",
+			content: NewFileContent("notes.txt", []byte("12345678"), ""),
+			want:    estimatedTokensPerMessage + 5,
+		},
+		{
+			name:    "extracted text",
+			content: extracted,
+			want:    estimatedTokensPerMessage + 5,
+		},
+		{
+			name:    "pdf pages",
+			content: NewFileContent("report.pdf", pdf, "application/pdf"),
+			want:    estimatedTokensPerMessage + 3 + 2*estimatedTokensPerFilePage,
+		},
+		{
+			name:    "binary file",
+			content: NewFileContent("data.bin", make([]byte, 100000), "application/octet-stream"),
+			want:    estimatedTokensPerMessage + 2 + estimatedTokensPerFilePage,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := EstimateTokens(NewUserMessage().AddContent(tt.content)); got != tt.want {
+				t.Errorf("EstimateTokens() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package tokenizer
+
+const (
+	Cl100kBase = "cl100k_base"
+	O200kBase  = "o200k_base"
+)
+
+// EncodingSpec holds what an encoding needs besides its ranks: the pattern splitting
+// the texts in pieces and the special tokens.
+type EncodingSpec struct {
+	Pattern       string
+	SpecialTokens map[string]int
+}
+
+// Encodings are the known encodings. cl100k_base is used by gpt-3.5-turbo, gpt-4 and the
+// text-embedding-3 models, o200k_base by gpt-4o, gpt-4.1 and the o-series models.
+//
+// The patterns are the tiktoken ones without the `\s+(?!\S)` alternative, which Go
+// regexp cannot express and Encoding emulates.
+var Encodings = map[string]EncodingSpec{
+	Cl100kBase: {
+		Pattern: `(?i:'s|'t|'re|'ve|'m|'ll|'d)|[^\r\n\p{L}\p{N}]?\p{L}+|\p{N}{1,3}|` +
+			` ?[^\s\p{L}\p{N}]+[\r\n]*|\s*[\r\n]+|\s+`,
+		SpecialTokens: map[string]int{
+			"<|endoftext|>":   100257,
+			"<|fim_prefix|>":  100258,
+			"<|fim_middle|>":  100259,
+			"<|fim_suffix|>":  100260,
+			"<|endofprompt|>": 100276,
+		},
+	},
+	O200kBase: {
+		Pattern: `[^\r\n\p{L}\p{N}]?[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]*[\p{Ll}\p{Lm}\p{Lo}\p{M}]+` +
+			`(?i:'s|'t|'re|'ve|'m|'ll|'d)?|` +
+			`[^\r\n\p{L}\p{N}]?[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]+[\p{Ll}\p{Lm}\p{Lo}\p{M}]*` +
+			`(?i:'s|'t|'re|'ve|'m|'ll|'d)?|` +
+			`\p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n/]*|\s*[\r\n]+|\s+`,
+		SpecialTokens: map[string]int{
+			"<|endoftext|>":   199999,
+			"<|endofprompt|>": 200018,
+		},
+	},
+}
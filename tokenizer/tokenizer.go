@@ -0,0 +1,300 @@
+// Package tokenizer counts tokens offline with a byte pair encoding (BPE) tokenizer.
+// Encodings are loaded from local rank files in the tiktoken format, e.g.
+// cl100k_base.tiktoken or o200k_base.tiktoken, so no network access is needed.
+//
+// Count plugs into the library wherever a token count is needed, e.g.
+// textsplitter.RecursiveCharacterTextSplitter.WithLengthFunction, and CountMessage into
+// thread.WindowTokenBudget, compactor.Compactor.WithMaxTokens,
+// ratelimit.LimitedLLM.WithTokenCounter, and cohere.Cohere.WithTokenCounter, which
+// counts the usage, hence the cost, of the responses not reporting their tokens.
+package tokenizer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"math"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/maksymenkoml/lingoose/thread"
+)
+
+var (
+	ErrInvalidRanks     = errors.New("invalid ranks")
+	ErrUnknownEncoding  = errors.New("unknown encoding")
+	ErrInvalidTokenizer = errors.New("invalid tokenizer")
+)
+
+// Encoding is a BPE encoding. It is safe for concurrent use.
+type Encoding struct {
+	name          string
+	ranks         map[string]int
+	decoder       map[int]string
+	specialTokens map[string]int
+	pattern       *regexp.Regexp
+	special       *regexp.Regexp
+}
+
+// NewEncoding creates an encoding from its mergeable ranks, the pattern splitting the
+// texts in pieces before they are encoded, and its special tokens. The pattern uses the
+// Go regexp syntax: the lookahead of the tiktoken patterns, `\s+(?!\S)`, is emulated by
+// leaving the last space of a run of spaces to the following piece.
+func NewEncoding(name string, ranks map[string]int, pattern string, specialTokens map[string]int) (*Encoding, error) {
+	compiledPattern, err := regexp.Compile(`^(?:` + pattern + `)`)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidTokenizer, err)
+	}
+
+	decoder := make(map[int]string, len(ranks)+len(specialTokens))
+	for token, rank := range ranks {
+		decoder[rank] = token
+	}
+
+	quotedSpecialTokens := make([]string, 0, len(specialTokens))
+	for token, rank := range specialTokens {
+		decoder[rank] = token
+		quotedSpecialTokens = append(quotedSpecialTokens, regexp.QuoteMeta(token))
+	}
+
+	var specialPattern *regexp.Regexp
+	if len(quotedSpecialTokens) > 0 {
+		// longest first, so that a special token prevails on its prefixes
+		sort.Slice(quotedSpecialTokens, func(i, j int) bool {
+			return len(quotedSpecialTokens[i]) > len(quotedSpecialTokens[j])
+		})
+		specialPattern = regexp.MustCompile(strings.Join(quotedSpecialTokens, "|"))
+	}
+
+	return &Encoding{
+		name:          name,
+		ranks:         ranks,
+		decoder:       decoder,
+		specialTokens: specialTokens,
+		pattern:       compiledPattern,
+		special:       specialPattern,
+	}, nil
+}
+
+// Load loads a known encoding, see Encodings, from its rank file.
+func Load(name, path string) (*Encoding, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidRanks, err)
+	}
+	defer file.Close()
+
+	return load(name, file)
+}
+
+// LoadFS loads a known encoding, see Encodings, from its rank file in fsys, e.g. an
+// embed.FS shipping the rank file with the binary.
+func LoadFS(fsys fs.FS, name, path string) (*Encoding, error) {
+	file, err := fsys.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidRanks, err)
+	}
+	defer file.Close()
+
+	return load(name, file)
+}
+
+func load(name string, r io.Reader) (*Encoding, error) {
+	spec, ok := Encodings[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownEncoding, name)
+	}
+
+	ranks, err := LoadRanks(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewEncoding(name, ranks, spec.Pattern, spec.SpecialTokens)
+}
+
+// LoadRanks reads the mergeable ranks in the tiktoken format: one token per line,
+// encoded in base64, followed by a space and its rank.
+func LoadRanks(r io.Reader) (map[string]int, error) {
+	ranks := make(map[string]int)
+
+	scanner := bufio.NewScanner(r)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		encodedToken, encodedRank, ok := bytes.Cut(line, []byte(" "))
+		if !ok {
+			return nil, fmt.Errorf("%w: line %d: missing rank", ErrInvalidRanks, lineNumber)
+		}
+
+		token, err := base64.StdEncoding.DecodeString(string(encodedToken))
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d: %w", ErrInvalidRanks, lineNumber, err)
+		}
+
+		rank, err := strconv.Atoi(string(encodedRank))
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d: %w", ErrInvalidRanks, lineNumber, err)
+		}
+
+		ranks[string(token)] = rank
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidRanks, err)
+	}
+
+	return ranks, nil
+}
+
+// Name returns the name of the encoding, e.g. cl100k_base.
+func (e *Encoding) Name() string {
+	return e.name
+}
+
+// Encode returns the tokens of the text. Special tokens are encoded as ordinary text.
+func (e *Encoding) Encode(text string) []int {
+	var tokens []int
+	for _, piece := range e.split(text) {
+		tokens = append(tokens, e.encodePiece(piece)...)
+	}
+
+	return tokens
+}
+
+// EncodeWithSpecialTokens returns the tokens of the text, encoding the special tokens
+// it holds, e.g. <|endoftext|>, as such.
+func (e *Encoding) EncodeWithSpecialTokens(text string) []int {
+	if e.special == nil {
+		return e.Encode(text)
+	}
+
+	var tokens []int
+	start := 0
+	for _, loc := range e.special.FindAllStringIndex(text, -1) {
+		tokens = append(tokens, e.Encode(text[start:loc[0]])...)
+		tokens = append(tokens, e.specialTokens[text[loc[0]:loc[1]]])
+		start = loc[1]
+	}
+
+	return append(tokens, e.Encode(text[start:])...)
+}
+
+// Decode returns the text of the tokens. Unknown tokens are skipped.
+func (e *Encoding) Decode(tokens []int) string {
+	var sb strings.Builder
+	for _, token := range tokens {
+		sb.WriteString(e.decoder[token])
+	}
+
+	return sb.String()
+}
+
+// Count returns the number of tokens of the text. It is a textsplitter.LenFunction.
+func (e *Encoding) Count(text string) int {
+	count := 0
+	for _, piece := range e.split(text) {
+		count += len(e.encodePiece(piece))
+	}
+
+	return count
+}
+
+// CountMessage returns the number of tokens of a message. It is a thread.TokenCountFn,
+// see thread.CountTokens.
+func (e *Encoding) CountMessage(m *thread.Message) int {
+	return thread.CountTokens(e.Count)(m)
+}
+
+// split splits the text in the pieces encoded separately.
+func (e *Encoding) split(text string) []string {
+	var pieces []string
+	for len(text) > 0 {
+		loc := e.pattern.FindStringIndex(text)
+		if loc == nil || loc[1] == 0 {
+			// not matched by the pattern, encode the next character alone
+			_, size := utf8.DecodeRuneInString(text)
+			loc = []int{0, size}
+		}
+
+		end := loc[1]
+		if isTrailingWhitespace(text[:end], text[end:]) {
+			// emulate `\s+(?!\S)`: leave the last space to the following word
+			_, size := utf8.DecodeLastRuneInString(text[:end])
+			end -= size
+		}
+
+		pieces = append(pieces, text[:end])
+		text = text[end:]
+	}
+
+	return pieces
+}
+
+// isTrailingWhitespace reports whether piece is a run of several spaces, not ending
+// with a new line, followed by a character that is not a space.
+func isTrailingWhitespace(piece, rest string) bool {
+	if utf8.RuneCountInString(piece) < 2 || rest == "" {
+		return false
+	}
+
+	for _, r := range piece {
+		if !unicode.IsSpace(r) {
+			return false
+		}
+	}
+
+	last, _ := utf8.DecodeLastRuneInString(piece)
+	next, _ := utf8.DecodeRuneInString(rest)
+
+	return last != '\n' && last != '\r' && !unicode.IsSpace(next)
+}
+
+// encodePiece encodes a piece merging its bytes by rank, lowest first.
+func (e *Encoding) encodePiece(piece string) []int {
+	if rank, ok := e.ranks[piece]; ok {
+		return []int{rank}
+	}
+
+	// parts holds the start of each part, and the end of the last one
+	parts := make([]int, len(piece)+1)
+	for i := range parts {
+		parts[i] = i
+	}
+
+	for len(parts) > 2 {
+		minRank, minIndex := math.MaxInt, -1
+		for i := 0; i < len(parts)-2; i++ {
+			if rank, ok := e.ranks[piece[parts[i]:parts[i+2]]]; ok && rank < minRank {
+				minRank, minIndex = rank, i
+			}
+		}
+
+		if minIndex < 0 {
+			break
+		}
+
+		parts = append(parts[:minIndex+1], parts[minIndex+2:]...)
+	}
+
+	tokens := make([]int, 0, len(parts)-1)
+	for i := 0; i < len(parts)-1; i++ {
+		if rank, ok := e.ranks[piece[parts[i]:parts[i+1]]]; ok {
+			tokens = append(tokens, rank)
+		}
+	}
+
+	return tokens
+}
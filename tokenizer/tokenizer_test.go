@@ -0,0 +1,178 @@
+package tokenizer
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/maksymenkoml/lingoose/thread"
+)
+
+// testMerges are ranked after the 256 single bytes.
+var testMerges = []string{"he", "ll", "llo", "hello", " w", "or", " wor", "ld", " world", "  "}
+
+func testRankFile() string {
+	var sb strings.Builder
+	for i := 0; i < 256; i++ {
+		fmt.Fprintf(&sb, "%s %d\n", base64.StdEncoding.EncodeToString([]byte{byte(i)}), i)
+	}
+	for i, merge := range testMerges {
+		fmt.Fprintf(&sb, "%s %d\n", base64.StdEncoding.EncodeToString([]byte(merge)), 256+i)
+	}
+
+	return sb.String()
+}
+
+func testEncoding(t *testing.T) *Encoding {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "cl100k_base.tiktoken")
+	if err := os.WriteFile(path, []byte(testRankFile()), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	encoding, err := Load(Cl100kBase, path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	return encoding
+}
+
+func TestEncoding_Encode(t *testing.T) {
+	encoding := testEncoding(t)
+
+	tests := []struct {
+		name string
+		text string
+		want []int
+	}{
+		{name: "merged", text: "hello world", want: []int{259, 264}},
+		{name: "partially merged", text: "help", want: []int{256, 'l', 'p'}},
+		{name: "trailing spaces", text: "hello   world", want: []int{259, 265, 264}},
+		{name: "new line", text: "hello\n", want: []int{259, '\n'}},
+		{name: "special token as text", text: "<|endoftext|>", want: []int{'<', '|', 'e', 'n', 'd', 'o', 'f', 't', 'e', 'x', 't', '|', '>'}},
+		{name: "multibyte", text: "è", want: []int{0xc3, 0xa8}},
+		{name: "empty", text: "", want: nil},
+	}
+	for _, tt := range tests {
+		got := encoding.Encode(tt.text)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: Encode() = %v, want %v", tt.name, got, tt.want)
+		}
+		if decoded := encoding.Decode(got); decoded != tt.text {
+			t.Errorf("%s: Decode() = %q, want %q", tt.name, decoded, tt.text)
+		}
+		if count := encoding.Count(tt.text); count != len(tt.want) {
+			t.Errorf("%s: Count() = %d, want %d", tt.name, count, len(tt.want))
+		}
+	}
+}
+
+// TestEncoding_split checks the pieces against the tiktoken ones, matched with the
+// `\s+(?!\S)` lookahead.
+func TestEncoding_split(t *testing.T) {
+	tests := []struct {
+		encoding string
+		text     string
+		want     []string
+	}{
+		{encoding: Cl100kBase, text: "hello   world", want: []string{"hello", "  ", " world"}},
+		{encoding: Cl100kBase, text: "a   123", want: []string{"a", "  ", " ", "123"}},
+		{encoding: Cl100kBase, text: "12345", want: []string{"123", "45"}},
+		{encoding: Cl100kBase, text: "a   !", want: []string{"a", "  ", " !"}},
+		{encoding: Cl100kBase, text: "a   \nb", want: []string{"a", "   \n", "b"}},
+		{encoding: Cl100kBase, text: "a\n\n  b", want: []string{"a", "\n\n", " ", " b"}},
+		{encoding: Cl100kBase, text: "a   ", want: []string{"a", "   "}},
+		{encoding: Cl100kBase, text: "a\r\nb", want: []string{"a", "\r\n", "b"}},
+		{encoding: Cl100kBase, text: "a \r\n b", want: []string{"a", " \r\n", " b"}},
+		{encoding: Cl100kBase, text: "hello.\r\nworld", want: []string{"hello", ".\r\n", "world"}},
+		{encoding: Cl100kBase, text: "HelloWorld", want: []string{"HelloWorld"}},
+		{encoding: Cl100kBase, text: "I'M", want: []string{"I", "'M"}},
+		{encoding: O200kBase, text: "HelloWorld", want: []string{"Hello", "World"}},
+		{encoding: O200kBase, text: "camelCase", want: []string{"camel", "Case"}},
+		{encoding: O200kBase, text: "JSONParser", want: []string{"JSONParser"}},
+		{encoding: O200kBase, text: "HELLO world", want: []string{"HELLO", " world"}},
+		{encoding: O200kBase, text: "I'M", want: []string{"I'M"}},
+		{encoding: O200kBase, text: "a   123", want: []string{"a", "  ", " ", "123"}},
+	}
+	for _, tt := range tests {
+		encoding, err := NewEncoding(tt.encoding, nil, Encodings[tt.encoding].Pattern, nil)
+		if err != nil {
+			t.Fatalf("NewEncoding() error = %v", err)
+		}
+
+		if got := encoding.split(tt.text); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: split(%q) = %q, want %q", tt.encoding, tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestEncoding_EncodeWithSpecialTokens(t *testing.T) {
+	encoding := testEncoding(t)
+
+	got := encoding.EncodeWithSpecialTokens("hello<|endoftext|> world")
+	want := []int{259, 100257, 264}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("EncodeWithSpecialTokens() = %v, want %v", got, want)
+	}
+	if decoded := encoding.Decode(got); decoded != "hello<|endoftext|> world" {
+		t.Errorf("Decode() = %q, want the text", decoded)
+	}
+}
+
+func TestEncoding_CountMessage(t *testing.T) {
+	encoding := testEncoding(t)
+
+	message := thread.NewUserMessage().AddContent(thread.NewTextContent("hello world"))
+	// the text tokens plus the message overhead estimated by thread.CountTokens
+	want := 2 + thread.CountTokens(func(string) int { return 0 })(message)
+	if got := encoding.CountMessage(message); got != want {
+		t.Errorf("CountMessage() = %d, want %d", got, want)
+	}
+
+	windowed := thread.New().
+		AddMessage(message).
+		AddMessage(thread.NewAssistantMessage().AddContent(thread.NewTextContent("hello"))).
+		Window(thread.WindowTokenBudget(want, encoding.CountMessage))
+	if windowed.CountMessages() != 1 || windowed.LastMessage().Contents[0].AsString() != "hello" {
+		t.Errorf("Window() = %v, want the last message only", windowed)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	fsys := fstest.MapFS{
+		"ranks.tiktoken":   {Data: []byte(testRankFile())},
+		"invalid.tiktoken": {Data: []byte("aGVsbG8= 1\nd29ybGQ=\n")},
+	}
+
+	encoding, err := LoadFS(fsys, O200kBase, "ranks.tiktoken")
+	if err != nil {
+		t.Fatalf("LoadFS() error = %v", err)
+	}
+	if encoding.Name() != O200kBase || encoding.Count("hello world") != 2 {
+		t.Errorf("LoadFS() = %s encoding, want %s", encoding.Name(), O200kBase)
+	}
+
+	tests := []struct {
+		name     string
+		encoding string
+		path     string
+		wantErr  error
+	}{
+		{name: "unknown encoding", encoding: "unknown", path: "ranks.tiktoken", wantErr: ErrUnknownEncoding},
+		{name: "missing file", encoding: Cl100kBase, path: "missing.tiktoken", wantErr: ErrInvalidRanks},
+		{name: "missing rank", encoding: Cl100kBase, path: "invalid.tiktoken", wantErr: ErrInvalidRanks},
+	}
+	for _, tt := range tests {
+		if _, err := LoadFS(fsys, tt.encoding, tt.path); !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: LoadFS() error = %v, want %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
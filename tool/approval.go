@@ -0,0 +1,98 @@
+package tool
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/maksymenkoml/lingoose/thread"
+)
+
+type Decision string
+
+const (
+	DecisionAllow Decision = "allow"
+	DecisionDeny  Decision = "deny"
+	DecisionEdit  Decision = "edit"
+)
+
+// Approval is the decision taken on a tool call. Arguments replaces the arguments of
+// the call when the decision is DecisionEdit, Reason is reported to the model when the
+// decision is DecisionDeny.
+type Approval struct {
+	Decision  Decision
+	Arguments string
+	Reason    string
+}
+
+// ApprovalFn decides whether a tool call can be executed. It is called before each
+// tool call and may block until the decision is taken, e.g. by a user in a web UI, in
+// which case it should return when ctx is done. Returning an error denies the call.
+type ApprovalFn func(ctx context.Context, toolCall thread.ToolCallData) (Approval, error)
+
+func Allow() Approval {
+	return Approval{Decision: DecisionAllow}
+}
+
+func Deny(reason string) Approval {
+	return Approval{Decision: DecisionDeny, Reason: reason}
+}
+
+func Edit(arguments string) Approval {
+	return Approval{Decision: DecisionEdit, Arguments: arguments}
+}
+
+// AlwaysAllow is an approval function that allows every tool call.
+func AlwaysAllow(context.Context, thread.ToolCallData) (Approval, error) {
+	return Allow(), nil
+}
+
+// AlwaysDeny is an approval function that denies every tool call.
+func AlwaysDeny(context.Context, thread.ToolCallData) (Approval, error) {
+	return Deny("the tool is not allowed"), nil
+}
+
+// WithApproval sets the approval function used for the tools without a specific rule.
+// By default all tool calls are allowed.
+func (r *Registry) WithApproval(approvalFn ApprovalFn) *Registry {
+	r.approvalFn = approvalFn
+	return r
+}
+
+// WithToolApproval sets the approval function used for the calls of the named tool.
+func (r *Registry) WithToolApproval(name string, approvalFn ApprovalFn) *Registry {
+	r.toolApprovalFns[name] = approvalFn
+	return r
+}
+
+// approve returns the tool call to execute, with edited arguments if requested. If the
+// call is denied, the tool result reporting the denial is returned instead.
+func (r *Registry) approve(ctx context.Context, toolCall thread.ToolCallData) (thread.ToolCallData, string, bool) {
+	approvalFn, ok := r.toolApprovalFns[toolCall.Name]
+	if !ok {
+		approvalFn = r.approvalFn
+	}
+
+	if approvalFn == nil {
+		return toolCall, "", true
+	}
+
+	approval, err := approvalFn(ctx, toolCall)
+	if err != nil {
+		return toolCall, fmt.Sprintf("error: tool call denied: %s", err), false
+	}
+
+	switch approval.Decision {
+	case DecisionAllow:
+		return toolCall, "", true
+	case DecisionEdit:
+		toolCall.Arguments = approval.Arguments
+		return toolCall, "", true
+	case DecisionDeny:
+		if approval.Reason == "" {
+			return toolCall, "error: tool call denied", false
+		}
+		return toolCall, fmt.Sprintf("error: tool call denied: %s", approval.Reason), false
+	}
+
+	return toolCall, fmt.Sprintf("error: tool call denied: unknown decision %q", approval.Decision), false
+}
@@ -0,0 +1,65 @@
+package tool
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/maksymenkoml/lingoose/thread"
+)
+
+func TestRegistry_CallToolsApproval(t *testing.T) {
+	r := NewRegistry().WithApproval(AlwaysDeny).WithToolApproval(
+		"sum",
+		func(_ context.Context, toolCall thread.ToolCallData) (Approval, error) {
+			switch toolCall.ID {
+			case "allow":
+				return Allow(), nil
+			case "edit":
+				return Edit(`{"a":10,"b":20}`), nil
+			case "error":
+				return Approval{}, errors.New("approval timed out")
+			}
+			return Deny("too many sums"), nil
+		},
+	)
+
+	err := r.Bind(sum, "sum", "sum two numbers")
+	if err != nil {
+		t.Fatalf("Registry.Bind() error = %v", err)
+	}
+
+	err = r.Bind(sum, "other", "sum two numbers")
+	if err != nil {
+		t.Fatalf("Registry.Bind() error = %v", err)
+	}
+
+	tests := []struct {
+		toolCall      thread.ToolCallData
+		want          string
+		wantArguments string
+	}{
+		{thread.ToolCallData{ID: "allow", Name: "sum", Arguments: `{"a":1,"b":2}`}, "3", `{"a":1,"b":2}`},
+		{thread.ToolCallData{ID: "edit", Name: "sum", Arguments: `{"a":1,"b":2}`}, "30", `{"a":10,"b":20}`},
+		{thread.ToolCallData{ID: "deny", Name: "sum", Arguments: `{"a":1,"b":2}`}, "error: tool call denied: too many sums", `{"a":1,"b":2}`},
+		{thread.ToolCallData{ID: "error", Name: "sum", Arguments: `{"a":1,"b":2}`}, "error: tool call denied: approval timed out", `{"a":1,"b":2}`},
+		{thread.ToolCallData{ID: "default", Name: "other", Arguments: `{"a":1,"b":2}`}, "error: tool call denied: the tool is not allowed", `{"a":1,"b":2}`},
+	}
+
+	for _, tt := range tests {
+		// the tool calls of the thread message record the executed arguments
+		toolCallMessage := thread.NewAssistantMessage().AddContent(
+			thread.NewToolCallContent([]thread.ToolCallData{tt.toolCall}),
+		)
+		messages := r.CallTools(context.Background(), toolCallMessage.Contents[0].AsToolCallData())
+
+		got := messages[0].Contents[0].AsToolResponseData()
+		if got.ID != tt.toolCall.ID || got.Result != tt.want {
+			t.Errorf("Registry.CallTools(%s) = %v, want %q", tt.toolCall.ID, got, tt.want)
+		}
+
+		if got := toolCallMessage.Contents[0].AsToolCallData()[0].Arguments; got != tt.wantArguments {
+			t.Errorf("Registry.CallTools(%s) recorded arguments = %s, want %s", tt.toolCall.ID, got, tt.wantArguments)
+		}
+	}
+}
@@ -13,6 +13,7 @@ const (
 )
 
 type Tool struct {
+	timeout time.Duration
 }
 
 type Input struct {
@@ -24,10 +25,18 @@ type Output struct {
 	ImageURL string `json:"imageURL,omitempty"`
 }
 
-type FnPrototype func(Input) Output
+type FnPrototype = func(context.Context, Input) Output
 
 func New() *Tool {
-	return &Tool{}
+	return &Tool{
+		timeout: defaultTimeoutInSeconds * time.Second,
+	}
+}
+
+// WithTimeout sets the timeout of each tool call.
+func (t *Tool) WithTimeout(timeout time.Duration) *Tool {
+	t.timeout = timeout
+	return t
 }
 
 func (t *Tool) Name() string {
@@ -42,8 +51,8 @@ func (t *Tool) Fn() any {
 	return t.fn
 }
 
-func (t *Tool) fn(i Input) Output {
-	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeoutInSeconds*time.Second)
+func (t *Tool) fn(ctx context.Context, i Input) Output {
+	ctx, cancel := context.WithTimeout(ctx, t.timeout)
 	defer cancel()
 
 	d := transformer.NewDallE().WithImageSize(transformer.DallEImageSize512x512)
@@ -17,6 +17,7 @@ type Tool struct {
 	maxResults uint
 	userAgent  string
 	restClient *restclientgo.RestClient
+	timeout    time.Duration
 }
 
 type Input struct {
@@ -28,11 +29,12 @@ type Output struct {
 	Results []result `json:"results,omitempty"`
 }
 
-type FnPrototype func(Input) Output
+type FnPrototype = func(context.Context, Input) Output
 
 func New() *Tool {
 	t := &Tool{
 		maxResults: 1,
+		timeout:    defaultTimeoutInSeconds * time.Second,
 	}
 
 	restClient := restclientgo.New("https://html.duckduckgo.com").
@@ -57,6 +59,12 @@ func (t *Tool) WithMaxResults(maxResults uint) *Tool {
 	return t
 }
 
+// WithTimeout sets the timeout of each tool call.
+func (t *Tool) WithTimeout(timeout time.Duration) *Tool {
+	t.timeout = timeout
+	return t
+}
+
 func (t *Tool) Name() string {
 	return "duckduckgo"
 }
@@ -69,8 +77,8 @@ func (t *Tool) Fn() any {
 	return t.fn
 }
 
-func (t *Tool) fn(i Input) Output {
-	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeoutInSeconds*time.Second)
+func (t *Tool) fn(ctx context.Context, i Input) Output {
+	ctx, cancel := context.WithTimeout(ctx, t.timeout)
 	defer cancel()
 
 	req := &request{Query: i.Query}
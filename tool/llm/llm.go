@@ -16,12 +16,14 @@ type LLM interface {
 }
 
 type Tool struct {
-	llm LLM
+	llm     LLM
+	timeout time.Duration
 }
 
 func New(llm LLM) *Tool {
 	return &Tool{
-		llm: llm,
+		llm:     llm,
+		timeout: defaultTimeoutInMinutes * time.Minute,
 	}
 }
 
@@ -34,7 +36,13 @@ type Output struct {
 	Result string `json:"result,omitempty"`
 }
 
-type FnPrototype func(Input) Output
+type FnPrototype = func(context.Context, Input) Output
+
+// WithTimeout sets the timeout of each tool call.
+func (t *Tool) WithTimeout(timeout time.Duration) *Tool {
+	t.timeout = timeout
+	return t
+}
 
 func (t *Tool) Name() string {
 	return "llm"
@@ -49,8 +57,8 @@ func (t *Tool) Fn() any {
 }
 
 //nolint:gosec
-func (t *Tool) fn(i Input) Output {
-	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeoutInMinutes*time.Minute)
+func (t *Tool) fn(ctx context.Context, i Input) Output {
+	ctx, cancel := context.WithTimeout(ctx, t.timeout)
 	defer cancel()
 
 	th := thread.New().AddMessage(
@@ -5,30 +5,29 @@ import (
 	"time"
 
 	"github.com/maksymenkoml/lingoose/thread"
+	"github.com/maksymenkoml/lingoose/usage"
 )
 
 const (
 	defaultTimeoutInMinutes = 6
 )
 
-type TokensUsage struct {
-	PromptTokens     int
-	CompletionTokens int
-	AudioTokens      int
-	CachedTokens     int
-}
+// TokensUsage is the usage of a generation, see usage.Usage.
+type TokensUsage = usage.Usage
 
 type LLMWithUsage interface {
 	GenerateWithUsage(context.Context, *thread.Thread) (*TokensUsage, error)
 }
 
 type Tool struct {
-	llm LLMWithUsage
+	llm     LLMWithUsage
+	timeout time.Duration
 }
 
 func New(llm LLMWithUsage) *Tool {
 	return &Tool{
-		llm: llm,
+		llm:     llm,
+		timeout: defaultTimeoutInMinutes * time.Minute,
 	}
 }
 
@@ -42,7 +41,13 @@ type Output struct {
 	Usage  *TokensUsage `json:"usage,omitempty"`
 }
 
-type FnPrototype func(Input) Output
+type FnPrototype = func(context.Context, Input) Output
+
+// WithTimeout sets the timeout of each tool call.
+func (t *Tool) WithTimeout(timeout time.Duration) *Tool {
+	t.timeout = timeout
+	return t
+}
 
 func (t *Tool) Name() string {
 	return "llm_with_usage"
@@ -57,8 +62,8 @@ func (t *Tool) Fn() any {
 }
 
 //nolint:gosec
-func (t *Tool) fn(i Input) Output {
-	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeoutInMinutes*time.Minute)
+func (t *Tool) fn(ctx context.Context, i Input) Output {
+	ctx, cancel := context.WithTimeout(ctx, t.timeout)
 	defer cancel()
 
 	th := thread.New().AddMessage(
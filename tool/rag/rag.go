@@ -13,14 +13,16 @@ const (
 )
 
 type Tool struct {
-	rag   *rag.RAG
-	topic string
+	rag     *rag.RAG
+	topic   string
+	timeout time.Duration
 }
 
 func New(rag *rag.RAG, topic string) *Tool {
 	return &Tool{
-		rag:   rag,
-		topic: topic,
+		rag:     rag,
+		topic:   topic,
+		timeout: defaultTimeoutInMinutes * time.Minute,
 	}
 }
 
@@ -33,7 +35,13 @@ type Output struct {
 	Result string `json:"result,omitempty"`
 }
 
-type FnPrototype = func(Input) Output
+type FnPrototype = func(context.Context, Input) Output
+
+// WithTimeout sets the timeout of each tool call.
+func (t *Tool) WithTimeout(timeout time.Duration) *Tool {
+	t.timeout = timeout
+	return t
+}
 
 func (t *Tool) Name() string {
 	return "rag"
@@ -48,8 +56,8 @@ func (t *Tool) Fn() any {
 }
 
 //nolint:gosec
-func (t *Tool) fn(i Input) Output {
-	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeoutInMinutes*time.Minute)
+func (t *Tool) fn(ctx context.Context, i Input) Output {
+	ctx, cancel := context.WithTimeout(ctx, t.timeout)
 	defer cancel()
 
 	results, err := t.rag.Retrieve(ctx, i.Query)
@@ -0,0 +1,86 @@
+package tool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+var (
+	ErrRepairsExhausted = errors.New("tool call repairs exhausted")
+)
+
+type repairsContextKey struct{}
+
+// repairs counts the consecutive repair requests of each function in a tool call
+// chain, and records the first function whose repairs have been exhausted.
+type repairs struct {
+	mu        sync.Mutex
+	counts    map[string]uint
+	exhausted string
+}
+
+func newRepairs() *repairs {
+	return &repairs{counts: make(map[string]uint)}
+}
+
+// ContextWithRepairs starts a new tool call chain. The repairs of the tool calls
+// executed with the returned context are counted together, e.g. over the iterations of
+// an assistant run, and CheckRepairs reports when the model has to stop. Without a
+// chain in the context, the tool calls of each CallTools are a chain on their own.
+func ContextWithRepairs(ctx context.Context) context.Context {
+	return context.WithValue(ctx, repairsContextKey{}, newRepairs())
+}
+
+// CheckRepairs returns ErrRepairsExhausted if a function of the tool call chain of ctx
+// has been called with invalid arguments more times than the repairs allowed. The
+// model must not be invoked again to repair it.
+func CheckRepairs(ctx context.Context) error {
+	r, ok := ctx.Value(repairsContextKey{}).(*repairs)
+	if !ok || r == nil {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.exhausted != "" {
+		return fmt.Errorf("%w: %s", ErrRepairsExhausted, r.exhausted)
+	}
+
+	return nil
+}
+
+func repairsFromContext(ctx context.Context) *repairs {
+	r, ok := ctx.Value(repairsContextKey{}).(*repairs)
+	if !ok || r == nil {
+		return newRepairs()
+	}
+
+	return r
+}
+
+// request records a repair request of the function and reports whether it is allowed.
+func (r *repairs) request(name string, maxRepairs uint) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.counts[name] >= maxRepairs {
+		if r.exhausted == "" {
+			r.exhausted = name
+		}
+		return false
+	}
+
+	r.counts[name]++
+
+	return true
+}
+
+func (r *repairs) reset(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.counts, name)
+}
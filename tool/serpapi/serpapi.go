@@ -19,6 +19,7 @@ type Tool struct {
 	countryCode  string
 	languageCode string
 	apiKey       string
+	timeout      time.Duration
 }
 
 type Input struct {
@@ -30,7 +31,7 @@ type Output struct {
 	Results []result `json:"results,omitempty"`
 }
 
-type FnPrototype = func(Input) Output
+type FnPrototype = func(context.Context, Input) Output
 
 func New() *Tool {
 	t := &Tool{
@@ -39,6 +40,7 @@ func New() *Tool {
 		googleDomain: "google.com",
 		countryCode:  "us",
 		languageCode: "en",
+		timeout:      defaultTimeoutInSeconds * time.Second,
 	}
 
 	return t
@@ -64,6 +66,12 @@ func (t *Tool) WithAPIKey(apiKey string) *Tool {
 	return t
 }
 
+// WithTimeout sets the timeout of each tool call.
+func (t *Tool) WithTimeout(timeout time.Duration) *Tool {
+	t.timeout = timeout
+	return t
+}
+
 func (t *Tool) Name() string {
 	return "google"
 }
@@ -76,8 +84,8 @@ func (t *Tool) Fn() any {
 	return t.fn
 }
 
-func (t *Tool) fn(i Input) Output {
-	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeoutInSeconds*time.Second)
+func (t *Tool) fn(ctx context.Context, i Input) Output {
+	ctx, cancel := context.WithTimeout(ctx, t.timeout)
 	defer cancel()
 
 	req := &request{
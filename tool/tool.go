@@ -0,0 +1,504 @@
+// Package tool provides a provider-agnostic way to describe Go functions as tools,
+// advertise them to a model and execute the tool calls it requests.
+package tool
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/invopop/jsonschema"
+
+	"github.com/maksymenkoml/lingoose/event"
+	"github.com/maksymenkoml/lingoose/thread"
+)
+
+var (
+	ErrUnknownFunction = errors.New("unknown function")
+)
+
+// Tool is implemented by all the tools in the tool/* packages.
+type Tool interface {
+	Description() string
+	Name() string
+	Fn() any
+}
+
+// Function is a Go function that can be called by a model. Fn must accept exactly
+// one struct argument, whose JSON schema is stored in Parameters, optionally preceded
+// by a context.Context argument that receives the context of the caller.
+type Function struct {
+	Name        string
+	Description string
+	Parameters  map[string]interface{}
+	Fn          interface{}
+}
+
+type FunctionParameterOption func(map[string]interface{}) error
+
+// NewFunction creates a function deriving its parameters schema from the argument of fn.
+func NewFunction(
+	fn interface{},
+	name string,
+	description string,
+	functionParameterOptions ...FunctionParameterOption,
+) (*Function, error) {
+	parameter, err := extractFunctionParameter(fn)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, option := range functionParameterOptions {
+		err = option(parameter)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &Function{
+		Name:        name,
+		Description: description,
+		Parameters:  parameter,
+		Fn:          fn,
+	}, nil
+}
+
+// NewFunctionFromTool creates a function from a tool.
+func NewFunctionFromTool(t Tool) (*Function, error) {
+	return NewFunction(t.Fn(), t.Name(), t.Description())
+}
+
+// Call decodes the JSON arguments into the function argument, calls the function
+// and returns its result encoded as JSON.
+func (f *Function) Call(argumentsAsJSON string) (string, error) {
+	return f.CallContext(context.Background(), argumentsAsJSON)
+}
+
+// CallContext is like Call but passes ctx to functions accepting a context.
+// The arguments are validated against Parameters before calling the function, an
+// *ArgumentsError is returned if they do not match.
+func (f *Function) CallContext(ctx context.Context, argumentsAsJSON string) (string, error) {
+	if f.Parameters != nil {
+		err := ValidateArguments(f.Parameters, argumentsAsJSON)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return callFnWithArgumentAsJSON(ctx, f.Fn, argumentsAsJSON)
+}
+
+const (
+	DefaultMaxRepairs     = 2
+	DefaultMaxConcurrency = 4
+)
+
+// Registry holds the functions bound to a model and executes the tool calls it
+// requests. Functions are kept in insertion order.
+type Registry struct {
+	functions       map[string]Function
+	names           []string
+	maxRepairs      uint
+	maxConcurrency  uint
+	approvalFn      ApprovalFn
+	toolApprovalFns map[string]ApprovalFn
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		functions:       make(map[string]Function),
+		maxRepairs:      DefaultMaxRepairs,
+		maxConcurrency:  DefaultMaxConcurrency,
+		toolApprovalFns: make(map[string]ApprovalFn),
+	}
+}
+
+// WithMaxRepairs sets how many consecutive times, in a tool call chain, the model is
+// asked to fix the invalid arguments of a function call before the error is reported
+// as final and the chain is stopped, see ContextWithRepairs.
+func (r *Registry) WithMaxRepairs(maxRepairs uint) *Registry {
+	r.maxRepairs = maxRepairs
+	return r
+}
+
+// WithMaxConcurrency sets the maximum number of tool calls executed at the same time by
+// CallTools. A value of 1 executes the tool calls one at a time, 0 is 1.
+func (r *Registry) WithMaxConcurrency(maxConcurrency uint) *Registry {
+	r.maxConcurrency = maxConcurrency
+	return r
+}
+
+// Add adds the functions to the registry, replacing any function with the same name.
+func (r *Registry) Add(functions ...Function) *Registry {
+	for _, function := range functions {
+		if _, ok := r.functions[function.Name]; !ok {
+			r.names = append(r.names, function.Name)
+		}
+		r.functions[function.Name] = function
+	}
+
+	return r
+}
+
+// Bind creates a function from fn and adds it to the registry.
+func (r *Registry) Bind(
+	fn interface{},
+	name string,
+	description string,
+	functionParameterOptions ...FunctionParameterOption,
+) error {
+	function, err := NewFunction(fn, name, description, functionParameterOptions...)
+	if err != nil {
+		return err
+	}
+
+	r.Add(*function)
+
+	return nil
+}
+
+// AddTools adds the tools to the registry.
+func (r *Registry) AddTools(tools ...Tool) error {
+	for _, t := range tools {
+		function, err := NewFunctionFromTool(t)
+		if err != nil {
+			return fmt.Errorf("tool %s: %w", t.Name(), err)
+		}
+
+		r.Add(*function)
+	}
+
+	return nil
+}
+
+// Clear removes all the functions from the registry.
+func (r *Registry) Clear() *Registry {
+	r.functions = make(map[string]Function)
+	r.names = nil
+	return r
+}
+
+func (r *Registry) Get(name string) (Function, bool) {
+	function, ok := r.functions[name]
+	return function, ok
+}
+
+// Functions returns the functions of the registry in insertion order.
+func (r *Registry) Functions() []Function {
+	functions := make([]Function, 0, len(r.names))
+	for _, name := range r.names {
+		functions = append(functions, r.functions[name])
+	}
+
+	return functions
+}
+
+func (r *Registry) Len() int {
+	return len(r.functions)
+}
+
+// Call executes a single tool call and returns its result encoded as JSON.
+func (r *Registry) Call(ctx context.Context, toolCall thread.ToolCallData) (string, error) {
+	function, ok := r.functions[toolCall.Name]
+	if !ok {
+		return "", fmt.Errorf("%w %s", ErrUnknownFunction, toolCall.Name)
+	}
+
+	return function.CallContext(ctx, toolCall.Arguments)
+}
+
+// CallTools executes the tool calls concurrently, up to the maximum concurrency, and
+// returns a tool message for each of them, in the order of the tool calls. Each call is subject to the approval function
+// of the tool, denied calls and errors, e.g. an unknown function, are reported to the
+// model as the tool result.
+// The arguments of the calls edited on approval are updated in toolCalls, pass the tool
+// calls of the thread message so that the thread records what has been executed.
+// A tool call event and a tool result event are emitted for each call to the event
+// emitter of the context. The events emitted during the calls, by the tools too, are
+// delivered to the emitter one at a time. The repairs of invalid arguments are counted in the tool call
+// chain of the context.
+func (r *Registry) CallTools(ctx context.Context, toolCalls []thread.ToolCallData) []*thread.Message {
+	if len(toolCalls) == 0 {
+		return nil
+	}
+
+	chain := repairsFromContext(ctx)
+	messages := make([]*thread.Message, len(toolCalls))
+	ctx = contextWithSerializedEmitter(ctx)
+	semaphore := make(chan struct{}, max(r.maxConcurrency, 1))
+
+	var wg sync.WaitGroup
+	for i, toolCall := range toolCalls {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			approvedToolCall, result, approved := r.approve(ctx, toolCall)
+			toolCalls[i] = approvedToolCall
+			if approved {
+				event.Emit(ctx, event.NewToolCall(approvedToolCall))
+
+				var err error
+				result, err = r.Call(ctx, approvedToolCall)
+				if err != nil {
+					result = r.errorResult(chain, approvedToolCall, err)
+				} else {
+					chain.reset(approvedToolCall.Name)
+				}
+			}
+
+			messages[i] = NewToolResponseMessage(approvedToolCall, result)
+			event.Emit(ctx, event.NewToolResult(*messages[i].Contents[0].AsToolResponseData()))
+		}()
+	}
+	wg.Wait()
+
+	return messages
+}
+
+// contextWithSerializedEmitter returns a context whose event emitter, if any, delivers
+// the events of concurrent tool calls one at a time.
+func contextWithSerializedEmitter(ctx context.Context) context.Context {
+	emitter := event.ContextValueEmitter(ctx)
+	if emitter == nil {
+		return ctx
+	}
+
+	var mu sync.Mutex
+	return event.ContextWithEmitter(ctx, func(e event.Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		emitter(e)
+	})
+}
+
+// errorResult returns the tool result reporting err to the model. Invalid arguments
+// are reported as a repair request, with the fields to fix, until the repairs of the
+// function in the chain are exhausted.
+func (r *Registry) errorResult(chain *repairs, toolCall thread.ToolCallData, err error) string {
+	var argumentsErr *ArgumentsError
+	if !errors.As(err, &argumentsErr) || !chain.request(toolCall.Name, r.maxRepairs) {
+		return fmt.Sprintf("error: %s", err)
+	}
+
+	return fmt.Sprintf(
+		"error: %s. Fix the arguments according to the %s parameters schema and call it again.",
+		err,
+		toolCall.Name,
+	)
+}
+
+// NewToolResponseMessage creates the tool message holding the result of a tool call.
+func NewToolResponseMessage(toolCall thread.ToolCallData, result string) *thread.Message {
+	return thread.NewToolMessage().AddContent(
+		thread.NewToolResponseContent(
+			thread.ToolResponseData{
+				ID:     toolCall.ID,
+				Name:   toolCall.Name,
+				Result: result,
+			},
+		),
+	)
+}
+
+// JSONSchema returns the JSON schema of v as a map, without references. The properties
+// of the pointer fields accept null, e.g. {"type": ["string", "null"]}.
+func JSONSchema(v interface{}) (map[string]interface{}, error) {
+	r := new(jsonschema.Reflector)
+	r.DoNotReference = true
+	schema := r.Reflect(v)
+
+	b, err := json.Marshal(schema)
+	if err != nil {
+		return nil, err
+	}
+
+	var jsonSchema map[string]interface{}
+	err = json.Unmarshal(b, &jsonSchema)
+	if err != nil {
+		return nil, err
+	}
+
+	delete(jsonSchema, "$schema")
+	nullablePointerFields(reflect.TypeOf(v), jsonSchema)
+
+	return jsonSchema, nil
+}
+
+// nullablePointerFields adds the null type to the properties of the pointer fields of
+// the struct type t, and of the structs it holds, since JSON encodes nil pointers as null.
+func nullablePointerFields(t reflect.Type, schema map[string]interface{}) {
+	if t == nil {
+		return
+	}
+
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array:
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			nullablePointerFields(t.Elem(), items)
+		}
+	case reflect.Map:
+		if additionalProperties, ok := schema["additionalProperties"].(map[string]interface{}); ok {
+			nullablePointerFields(t.Elem(), additionalProperties)
+		}
+	case reflect.Struct:
+		properties, _ := schema["properties"].(map[string]interface{})
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+
+			if field.Anonymous && name == "" {
+				// the fields of the embedded structs are inherited
+				nullablePointerFields(field.Type, schema)
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+
+			property, ok := properties[name].(map[string]interface{})
+			if !ok || !field.IsExported() {
+				continue
+			}
+
+			nullablePointerFields(field.Type, property)
+			if field.Type.Kind() == reflect.Ptr {
+				addNullType(property)
+			}
+		}
+	}
+}
+
+func addNullType(schema map[string]interface{}) {
+	switch schemaType := schema["type"].(type) {
+	case string:
+		schema["type"] = []interface{}{schemaType, "null"}
+	case []interface{}:
+		if !containsValue(schemaType, "null") {
+			schema["type"] = append(schemaType, "null")
+		}
+	default:
+		// the schemas without type accept null already
+		return
+	}
+
+	if enum, ok := schema["enum"].([]interface{}); ok && !containsValue(enum, nil) {
+		schema["enum"] = append(enum, nil)
+	}
+}
+
+func extractFunctionParameter(f interface{}) (map[string]interface{}, error) {
+	// Get the type of the input function
+	fnType := reflect.TypeOf(f)
+
+	if fnType == nil || fnType.Kind() != reflect.Func {
+		return nil, errors.New("input must be a function")
+	}
+
+	// Check that the function only has one argument, besides the optional context
+	argIndex := contextArgumentsCount(fnType)
+	if fnType.NumIn() != argIndex+1 {
+		return nil, errors.New("function must have exactly one argument")
+	}
+
+	// Check that the argument is of type struct
+	argType := fnType.In(argIndex)
+	if argType.Kind() != reflect.Struct {
+		return nil, errors.New("argument must be of type struct")
+	}
+
+	// Create a new instance of the argument type
+	argValue := reflect.New(argType).Elem().Interface()
+
+	parameter, err := JSONSchema(argValue)
+	if err != nil {
+		return nil, err
+	}
+
+	return parameter, nil
+}
+
+// contextArgumentsCount returns 1 if the first argument of the function is a
+// context.Context, 0 otherwise.
+func contextArgumentsCount(fnType reflect.Type) int {
+	contextType := reflect.TypeOf((*context.Context)(nil)).Elem()
+	if fnType.NumIn() > 0 && fnType.In(0) == contextType {
+		return 1
+	}
+
+	return 0
+}
+
+func callFnWithArgumentAsJSON(ctx context.Context, fn interface{}, argumentAsJSON string) (string, error) {
+	// Get the type of the input function
+	fnType := reflect.TypeOf(fn)
+
+	// Check that the function has one argument, besides the optional context
+	argIndex := contextArgumentsCount(fnType)
+	if fnType.NumIn() != argIndex+1 {
+		return "", fmt.Errorf("function must have one argument")
+	}
+
+	// Check that the argument is a struct
+	argType := fnType.In(argIndex)
+	if argType.Kind() != reflect.Struct {
+		return "", fmt.Errorf("argument must be a struct")
+	}
+
+	// Create a slice to hold the function arguments
+	args := make([]reflect.Value, argIndex+1)
+	if argIndex > 0 {
+		args[0] = reflect.ValueOf(ctx)
+	}
+
+	// Unmarshal the JSON string into an interface{} value
+	var argValue interface{}
+	err := json.Unmarshal([]byte(argumentAsJSON), &argValue)
+	if err != nil {
+		return "", fmt.Errorf("error unmarshaling argument: %w", err)
+	}
+
+	// Convert the argument value to the correct type
+	argValueReflect := reflect.New(argType).Elem()
+	jsonData, err := json.Marshal(argValue)
+	if err != nil {
+		return "", fmt.Errorf("error marshaling argument: %w", err)
+	}
+	err = json.Unmarshal(jsonData, argValueReflect.Addr().Interface())
+	if err != nil {
+		return "", fmt.Errorf("error unmarshaling argument: %w", err)
+	}
+
+	// Add the argument value to the slice
+	args[argIndex] = argValueReflect
+
+	// Call the function with the argument
+	fnValue := reflect.ValueOf(fn)
+	result := fnValue.Call(args)
+
+	// Marshal the function result to JSON
+	if len(result) > 0 {
+		var resultBytes bytes.Buffer
+		enc := json.NewEncoder(&resultBytes)
+		enc.SetEscapeHTML(false)
+		err = enc.Encode(result[0].Interface())
+		if err != nil {
+			return "", fmt.Errorf("error marshaling result: %w", err)
+		}
+		return strings.TrimSpace(resultBytes.String()), nil
+	}
+
+	return "", nil
+}
@@ -18,8 +18,9 @@ type TTool interface {
 }
 
 type Tool struct {
-	llm   LLM
-	tools []TTool
+	llm     LLM
+	tools   []TTool
+	timeout time.Duration
 }
 
 type LLM interface {
@@ -28,8 +29,9 @@ type LLM interface {
 
 func New(llm LLM, tools ...TTool) *Tool {
 	return &Tool{
-		tools: tools,
-		llm:   llm,
+		tools:   tools,
+		llm:     llm,
+		timeout: defaultTimeoutInMinutes * time.Minute,
 	}
 }
 
@@ -42,7 +44,13 @@ type Output struct {
 	Result any    `json:"result,omitempty"`
 }
 
-type FnPrototype func(Input) Output
+type FnPrototype = func(context.Context, Input) Output
+
+// WithTimeout sets the timeout of each tool call.
+func (t *Tool) WithTimeout(timeout time.Duration) *Tool {
+	t.timeout = timeout
+	return t
+}
 
 func (t *Tool) Name() string {
 	return "query_router"
@@ -57,8 +65,8 @@ func (t *Tool) Fn() any {
 }
 
 //nolint:gosec
-func (t *Tool) fn(i Input) Output {
-	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeoutInMinutes*time.Minute)
+func (t *Tool) fn(ctx context.Context, i Input) Output {
+	ctx, cancel := context.WithTimeout(ctx, t.timeout)
 	defer cancel()
 
 	query := "Here's a list of available tools:\n\n"
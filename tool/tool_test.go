@@ -0,0 +1,158 @@
+package tool
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/maksymenkoml/lingoose/event"
+	"github.com/maksymenkoml/lingoose/thread"
+)
+
+type sumInput struct {
+	A int `json:"a" jsonschema:"description=first number"`
+	B int `json:"b" jsonschema:"description=second number"`
+}
+
+func sum(i sumInput) int {
+	return i.A + i.B
+}
+
+func TestNewFunction(t *testing.T) {
+	function, err := NewFunction(sum, "sum", "sum two numbers")
+	if err != nil {
+		t.Fatalf("NewFunction() error = %v", err)
+	}
+
+	properties, ok := function.Parameters["properties"].(map[string]interface{})
+	if !ok || properties["a"] == nil || properties["b"] == nil {
+		t.Errorf("NewFunction() parameters = %v", function.Parameters)
+	}
+
+	_, err = NewFunction(func(int) int { return 0 }, "bad", "bad function")
+	if err == nil {
+		t.Errorf("NewFunction() error = nil, want error for non struct argument")
+	}
+}
+
+func TestRegistry_CallTools(t *testing.T) {
+	r := NewRegistry()
+	err := r.Bind(sum, "sum", "sum two numbers")
+	if err != nil {
+		t.Fatalf("Registry.Bind() error = %v", err)
+	}
+
+	messages := r.CallTools(context.Background(), []thread.ToolCallData{
+		{ID: "1", Name: "sum", Arguments: `{"a":1,"b":2}`},
+		{ID: "2", Name: "unknown", Arguments: `{}`},
+	})
+
+	if len(messages) != 2 {
+		t.Fatalf("Registry.CallTools() messages = %d, want 2", len(messages))
+	}
+
+	want := &thread.ToolResponseData{ID: "1", Name: "sum", Result: "3"}
+	if got := messages[0].Contents[0].AsToolResponseData(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Registry.CallTools() = %v, want %v", got, want)
+	}
+
+	if got := messages[1].Contents[0].AsToolResponseData(); !strings.HasPrefix(got.Result, "error:") {
+		t.Errorf("Registry.CallTools() = %v, want error result", got)
+	}
+}
+
+type contextKey string
+
+type delayInput struct {
+	Delay int `json:"delay"`
+}
+
+func TestRegistry_CallToolsContext(t *testing.T) {
+	r := NewRegistry()
+	err := r.Bind(
+		func(ctx context.Context, i delayInput) string {
+			time.Sleep(time.Duration(i.Delay) * time.Millisecond)
+			value, _ := ctx.Value(contextKey("key")).(string)
+			return value
+		},
+		"delay",
+		"wait and return the context value",
+	)
+	if err != nil {
+		t.Fatalf("Registry.Bind() error = %v", err)
+	}
+
+	ctx := context.WithValue(context.Background(), contextKey("key"), "value")
+	messages := r.CallTools(ctx, []thread.ToolCallData{
+		{ID: "1", Name: "delay", Arguments: `{"delay":20}`},
+		{ID: "2", Name: "delay", Arguments: `{"delay":0}`},
+	})
+
+	if len(messages) != 2 {
+		t.Fatalf("Registry.CallTools() messages = %d, want 2", len(messages))
+	}
+
+	for i, id := range []string{"1", "2"} {
+		want := &thread.ToolResponseData{ID: id, Name: "delay", Result: `"value"`}
+		if got := messages[i].Contents[0].AsToolResponseData(); !reflect.DeepEqual(got, want) {
+			t.Errorf("Registry.CallTools()[%d] = %v, want %v", i, got, want)
+		}
+	}
+}
+
+func TestRegistry_CallToolsConcurrency(t *testing.T) {
+	var running, maxRunning, emitting, maxEmitting, events atomic.Int32
+
+	// enter increments counter and records its maximum value in maxCounter
+	enter := func(counter, maxCounter *atomic.Int32) {
+		n := counter.Add(1)
+		for current := maxCounter.Load(); n > current; current = maxCounter.Load() {
+			if maxCounter.CompareAndSwap(current, n) {
+				return
+			}
+		}
+	}
+
+	r := NewRegistry().WithMaxConcurrency(2)
+	err := r.Bind(
+		func(i delayInput) int {
+			enter(&running, &maxRunning)
+			defer running.Add(-1)
+			time.Sleep(time.Duration(i.Delay) * time.Millisecond)
+			return i.Delay
+		},
+		"delay",
+		"wait",
+	)
+	if err != nil {
+		t.Fatalf("Registry.Bind() error = %v", err)
+	}
+
+	ctx := event.ContextWithEmitter(context.Background(), func(event.Event) {
+		enter(&emitting, &maxEmitting)
+		defer emitting.Add(-1)
+		events.Add(1)
+		time.Sleep(time.Millisecond)
+	})
+
+	var toolCalls []thread.ToolCallData
+	for i := 0; i < 6; i++ {
+		toolCalls = append(toolCalls, thread.ToolCallData{ID: fmt.Sprint(i), Name: "delay", Arguments: `{"delay":10}`})
+	}
+
+	messages := r.CallTools(ctx, toolCalls)
+	if len(messages) != len(toolCalls) {
+		t.Fatalf("Registry.CallTools() messages = %d, want %d", len(messages), len(toolCalls))
+	}
+
+	if got := maxRunning.Load(); got != 2 {
+		t.Errorf("Registry.CallTools() concurrent calls = %d, want 2", got)
+	}
+	if got := maxEmitting.Load(); got != 1 || events.Load() != 12 {
+		t.Errorf("Registry.CallTools() concurrent emits = %d, events = %d, want 1 and 12", got, events.Load())
+	}
+}
@@ -0,0 +1,290 @@
+package tool
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	ErrInvalidArguments = errors.New("invalid arguments")
+)
+
+// FieldError describes why the value of a field does not match the schema. Field is
+// the dotted path of the field, e.g. "address.city" or "items[2]".
+type FieldError struct {
+	Field   string
+	Message string
+}
+
+func (e FieldError) String() string {
+	if e.Field == "" {
+		return e.Message
+	}
+
+	return fmt.Sprintf("field %q %s", e.Field, e.Message)
+}
+
+// ArgumentsError is returned when the arguments of a tool call are malformed or do
+// not match the parameters schema of the function.
+type ArgumentsError struct {
+	Errors []FieldError
+}
+
+func (e *ArgumentsError) Error() string {
+	messages := make([]string, 0, len(e.Errors))
+	for _, fieldError := range e.Errors {
+		messages = append(messages, fieldError.String())
+	}
+
+	return fmt.Sprintf("%s: %s", ErrInvalidArguments, strings.Join(messages, "; "))
+}
+
+func (e *ArgumentsError) Unwrap() error {
+	return ErrInvalidArguments
+}
+
+// ValidateArguments checks the JSON arguments against the JSON schema of the function
+// parameters. It supports the keywords generated from struct tags: type, properties,
+// required, additionalProperties, items, enum, const, minimum, maximum,
+// exclusiveMinimum, exclusiveMaximum, minLength, maxLength, pattern, minItems and
+// maxItems.
+func ValidateArguments(schema map[string]interface{}, argumentsAsJSON string) error {
+	var arguments interface{}
+	err := json.Unmarshal([]byte(argumentsAsJSON), &arguments)
+	if err != nil {
+		return &ArgumentsError{
+			Errors: []FieldError{{Message: fmt.Sprintf("arguments are not valid JSON: %s", err)}},
+		}
+	}
+
+	fieldErrors := validateValue(schema, arguments, "")
+	if len(fieldErrors) > 0 {
+		return &ArgumentsError{Errors: fieldErrors}
+	}
+
+	return nil
+}
+
+func validateValue(schema interface{}, value interface{}, path string) []FieldError {
+	schemaAsMap, ok := schema.(map[string]interface{})
+	if !ok {
+		// boolean or missing schemas accept any value
+		return nil
+	}
+
+	if schemaType, hasType := schemaAsMap["type"]; hasType && !matchesType(schemaType, value) {
+		return []FieldError{{Field: path, Message: fmt.Sprintf("must be of type %s, got %s", typeAsString(schemaType), jsonType(value))}}
+	}
+
+	if enum, hasEnum := schemaAsMap["enum"].([]interface{}); hasEnum && !containsValue(enum, value) {
+		return []FieldError{{Field: path, Message: fmt.Sprintf("must be one of %s, got %s", marshal(enum), marshal(value))}}
+	}
+
+	if constValue, hasConst := schemaAsMap["const"]; hasConst && !reflect.DeepEqual(constValue, value) {
+		return []FieldError{{Field: path, Message: fmt.Sprintf("must be %s, got %s", marshal(constValue), marshal(value))}}
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return validateObject(schemaAsMap, v, path)
+	case []interface{}:
+		return validateArray(schemaAsMap, v, path)
+	case string:
+		return validateString(schemaAsMap, v, path)
+	case float64:
+		return validateNumber(schemaAsMap, v, path)
+	}
+
+	return nil
+}
+
+func validateObject(schema map[string]interface{}, object map[string]interface{}, path string) []FieldError {
+	var fieldErrors []FieldError
+
+	required, _ := schema["required"].([]interface{})
+	for _, name := range required {
+		nameAsString, _ := name.(string)
+		if _, found := object[nameAsString]; !found {
+			fieldErrors = append(fieldErrors, FieldError{Field: fieldPath(path, nameAsString), Message: "is required"})
+		}
+	}
+
+	properties, _ := schema["properties"].(map[string]interface{})
+
+	names := make([]string, 0, len(object))
+	for name := range object {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		propertySchema, found := properties[name]
+		if !found {
+			if additionalProperties, isBool := schema["additionalProperties"].(bool); isBool && !additionalProperties {
+				fieldErrors = append(fieldErrors, FieldError{Field: fieldPath(path, name), Message: "is not allowed"})
+				continue
+			}
+			propertySchema = schema["additionalProperties"]
+		}
+
+		fieldErrors = append(fieldErrors, validateValue(propertySchema, object[name], fieldPath(path, name))...)
+	}
+
+	return fieldErrors
+}
+
+func validateArray(schema map[string]interface{}, array []interface{}, path string) []FieldError {
+	var fieldErrors []FieldError
+
+	if minItems, ok := schema["minItems"].(float64); ok && float64(len(array)) < minItems {
+		fieldErrors = append(fieldErrors, FieldError{Field: path, Message: fmt.Sprintf("must have at least %v items", minItems)})
+	}
+
+	if maxItems, ok := schema["maxItems"].(float64); ok && float64(len(array)) > maxItems {
+		fieldErrors = append(fieldErrors, FieldError{Field: path, Message: fmt.Sprintf("must have at most %v items", maxItems)})
+	}
+
+	for i, item := range array {
+		fieldErrors = append(fieldErrors, validateValue(schema["items"], item, fmt.Sprintf("%s[%d]", path, i))...)
+	}
+
+	return fieldErrors
+}
+
+func validateString(schema map[string]interface{}, s string, path string) []FieldError {
+	var fieldErrors []FieldError
+	length := float64(utf8.RuneCountInString(s))
+
+	if minLength, ok := schema["minLength"].(float64); ok && length < minLength {
+		fieldErrors = append(fieldErrors, FieldError{Field: path, Message: fmt.Sprintf("must be at least %v characters long", minLength)})
+	}
+
+	if maxLength, ok := schema["maxLength"].(float64); ok && length > maxLength {
+		fieldErrors = append(fieldErrors, FieldError{Field: path, Message: fmt.Sprintf("must be at most %v characters long", maxLength)})
+	}
+
+	if pattern, ok := schema["pattern"].(string); ok {
+		re, err := regexp.Compile(pattern)
+		if err == nil && !re.MatchString(s) {
+			fieldErrors = append(fieldErrors, FieldError{Field: path, Message: fmt.Sprintf("must match pattern %q", pattern)})
+		}
+	}
+
+	return fieldErrors
+}
+
+func validateNumber(schema map[string]interface{}, n float64, path string) []FieldError {
+	var fieldErrors []FieldError
+
+	if minimum, ok := schema["minimum"].(float64); ok && n < minimum {
+		fieldErrors = append(fieldErrors, FieldError{Field: path, Message: fmt.Sprintf("must be greater than or equal to %v, got %v", minimum, n)})
+	}
+
+	if maximum, ok := schema["maximum"].(float64); ok && n > maximum {
+		fieldErrors = append(fieldErrors, FieldError{Field: path, Message: fmt.Sprintf("must be less than or equal to %v, got %v", maximum, n)})
+	}
+
+	if exclusiveMinimum, ok := schema["exclusiveMinimum"].(float64); ok && n <= exclusiveMinimum {
+		fieldErrors = append(fieldErrors, FieldError{Field: path, Message: fmt.Sprintf("must be greater than %v, got %v", exclusiveMinimum, n)})
+	}
+
+	if exclusiveMaximum, ok := schema["exclusiveMaximum"].(float64); ok && n >= exclusiveMaximum {
+		fieldErrors = append(fieldErrors, FieldError{Field: path, Message: fmt.Sprintf("must be less than %v, got %v", exclusiveMaximum, n)})
+	}
+
+	return fieldErrors
+}
+
+func matchesType(schemaType interface{}, value interface{}) bool {
+	switch t := schemaType.(type) {
+	case string:
+		return matchesSingleType(t, value)
+	case []interface{}:
+		for _, single := range t {
+			if singleAsString, ok := single.(string); ok && matchesSingleType(singleAsString, value) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return true
+}
+
+func matchesSingleType(schemaType string, value interface{}) bool {
+	valueType := jsonType(value)
+	switch schemaType {
+	case "integer":
+		n, ok := value.(float64)
+		return ok && n == math.Trunc(n)
+	case "number", "string", "boolean", "array", "object", "null":
+		return valueType == schemaType
+	}
+
+	return true
+}
+
+func jsonType(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+
+	return "unknown"
+}
+
+func typeAsString(schemaType interface{}) string {
+	if types, ok := schemaType.([]interface{}); ok {
+		names := make([]string, 0, len(types))
+		for _, t := range types {
+			names = append(names, fmt.Sprint(t))
+		}
+		return strings.Join(names, " or ")
+	}
+
+	return fmt.Sprint(schemaType)
+}
+
+func containsValue(values []interface{}, value interface{}) bool {
+	for _, v := range values {
+		if reflect.DeepEqual(v, value) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func fieldPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+
+	return path + "." + name
+}
+
+func marshal(value interface{}) string {
+	b, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+
+	return string(b)
+}
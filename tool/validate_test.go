@@ -0,0 +1,194 @@
+package tool
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/maksymenkoml/lingoose/thread"
+)
+
+type weatherInput struct {
+	City  string   `json:"city" jsonschema:"minLength=1"`
+	Unit  string   `json:"unit" jsonschema:"enum=celsius,enum=fahrenheit"`
+	Days  int      `json:"days,omitempty" jsonschema:"minimum=1,maximum=7"`
+	Hours []string `json:"hours,omitempty" jsonschema:"maxItems=2"`
+}
+
+func TestValidateArguments(t *testing.T) {
+	schema, err := JSONSchema(weatherInput{})
+	if err != nil {
+		t.Fatalf("JSONSchema() error = %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		arguments string
+		want      []FieldError
+	}{
+		{
+			name:      "valid",
+			arguments: `{"city":"Rome","unit":"celsius","days":3}`,
+		},
+		{
+			name:      "malformed",
+			arguments: `{"city":`,
+			want:      []FieldError{{Message: "arguments are not valid JSON: unexpected end of JSON input"}},
+		},
+		{
+			name:      "required",
+			arguments: `{"unit":"celsius"}`,
+			want:      []FieldError{{Field: "city", Message: "is required"}},
+		},
+		{
+			name:      "enum",
+			arguments: `{"city":"Rome","unit":"kelvin"}`,
+			want:      []FieldError{{Field: "unit", Message: `must be one of ["celsius","fahrenheit"], got "kelvin"`}},
+		},
+		{
+			name:      "range and type",
+			arguments: `{"city":"Rome","unit":"celsius","days":10,"hours":[1]}`,
+			want: []FieldError{
+				{Field: "days", Message: "must be less than or equal to 7, got 10"},
+				{Field: "hours[0]", Message: "must be of type string, got number"},
+			},
+		},
+		{
+			name:      "additional property",
+			arguments: `{"city":"Rome","unit":"celsius","country":"Italy"}`,
+			want:      []FieldError{{Field: "country", Message: "is not allowed"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateArguments(schema, tt.arguments)
+			if tt.want == nil {
+				if err != nil {
+					t.Errorf("ValidateArguments() error = %v, want nil", err)
+				}
+				return
+			}
+
+			var argumentsErr *ArgumentsError
+			if !errors.As(err, &argumentsErr) || !errors.Is(err, ErrInvalidArguments) {
+				t.Fatalf("ValidateArguments() error = %v, want *ArgumentsError", err)
+			}
+
+			if !reflect.DeepEqual(argumentsErr.Errors, tt.want) {
+				t.Errorf("ValidateArguments() errors = %v, want %v", argumentsErr.Errors, tt.want)
+			}
+		})
+	}
+}
+
+type bookingInput struct {
+	City   string   `json:"city"`
+	Nights *int     `json:"nights"`
+	Room   *string  `json:"room,omitempty" jsonschema:"enum=single,enum=double"`
+	Guests []*guest `json:"guests,omitempty"`
+}
+
+type guest struct {
+	Name string  `json:"name"`
+	Age  *int    `json:"age"`
+	Note *string `json:"note,omitempty"`
+}
+
+func TestValidateArguments_NullablePointers(t *testing.T) {
+	schema, err := JSONSchema(bookingInput{})
+	if err != nil {
+		t.Fatalf("JSONSchema() error = %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		arguments string
+		want      []FieldError
+	}{
+		{
+			name:      "null pointers",
+			arguments: `{"city":"Rome","nights":null,"room":null,"guests":[{"name":"Ada","age":null,"note":null}]}`,
+		},
+		{
+			name:      "pointer values",
+			arguments: `{"city":"Rome","nights":2,"room":"double","guests":[{"name":"Ada","age":36}]}`,
+		},
+		{
+			name:      "null value",
+			arguments: `{"city":null,"nights":2,"guests":[{"name":null,"age":36}]}`,
+			want: []FieldError{
+				{Field: "city", Message: "must be of type string, got null"},
+				{Field: "guests[0].name", Message: "must be of type string, got null"},
+			},
+		},
+		{
+			name:      "invalid pointer value",
+			arguments: `{"city":"Rome","nights":"two","room":"suite"}`,
+			want: []FieldError{
+				{Field: "nights", Message: "must be of type integer or null, got string"},
+				{Field: "room", Message: `must be one of ["single","double",null], got "suite"`},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateArguments(schema, tt.arguments)
+			if tt.want == nil {
+				if err != nil {
+					t.Errorf("ValidateArguments() error = %v, want nil", err)
+				}
+				return
+			}
+
+			var argumentsErr *ArgumentsError
+			if !errors.As(err, &argumentsErr) {
+				t.Fatalf("ValidateArguments() error = %v, want *ArgumentsError", err)
+			}
+
+			if !reflect.DeepEqual(argumentsErr.Errors, tt.want) {
+				t.Errorf("ValidateArguments() errors = %v, want %v", argumentsErr.Errors, tt.want)
+			}
+		})
+	}
+}
+
+func TestRegistry_CallToolsRepair(t *testing.T) {
+	r := NewRegistry().WithMaxRepairs(1)
+	err := r.Bind(func(i weatherInput) string { return i.City }, "weather", "get the weather")
+	if err != nil {
+		t.Fatalf("Registry.Bind() error = %v", err)
+	}
+
+	invalid := []thread.ToolCallData{{ID: "1", Name: "weather", Arguments: `{"city":"Rome","unit":"kelvin"}`}}
+	ctx := ContextWithRepairs(context.Background())
+
+	result := r.CallTools(ctx, invalid)[0].Contents[0].AsToolResponseData().Result
+	if !strings.Contains(result, `field "unit"`) || !strings.Contains(result, "call it again") {
+		t.Errorf("Registry.CallTools() = %q, want repair request", result)
+	}
+	if err = CheckRepairs(ctx); err != nil {
+		t.Errorf("CheckRepairs() error = %v, want nil", err)
+	}
+
+	// another chain, e.g. another thread, has its own repairs
+	otherCtx := ContextWithRepairs(context.Background())
+	result = r.CallTools(otherCtx, invalid)[0].Contents[0].AsToolResponseData().Result
+	if !strings.Contains(result, "call it again") {
+		t.Errorf("Registry.CallTools() = %q, want repair request in another chain", result)
+	}
+
+	result = r.CallTools(ctx, invalid)[0].Contents[0].AsToolResponseData().Result
+	if strings.Contains(result, "call it again") {
+		t.Errorf("Registry.CallTools() = %q, want final error", result)
+	}
+	if err = CheckRepairs(ctx); !errors.Is(err, ErrRepairsExhausted) {
+		t.Errorf("CheckRepairs() error = %v, want %v", err, ErrRepairsExhausted)
+	}
+	if err = CheckRepairs(otherCtx); err != nil {
+		t.Errorf("CheckRepairs() error = %v, want nil in another chain", err)
+	}
+}
@@ -0,0 +1,98 @@
+package usage
+
+import (
+	"strings"
+)
+
+const (
+	tokensPerPriceUnit = 1_000_000
+)
+
+// Price is the price of a model in US dollars per million tokens. Cached is the price
+// of the cached prompt tokens, the Prompt price applies when it is 0.
+type Price struct {
+	Prompt     float64
+	Completion float64
+	Cached     float64
+}
+
+// Pricing maps the model names to their price. A model that is not found is priced as
+// the longest model name it starts with, e.g. "gpt-4o-2024-08-06" as "gpt-4o".
+type Pricing map[string]Price
+
+// DefaultPricing returns the list prices of the models of the supported providers.
+// Prices change over time: use a custom Pricing for accurate accounting.
+func DefaultPricing() Pricing {
+	return Pricing{
+		// OpenAI
+		"gpt-3.5-turbo":          {Prompt: 0.5, Completion: 1.5},
+		"gpt-4":                  {Prompt: 30, Completion: 60},
+		"gpt-4-turbo":            {Prompt: 10, Completion: 30},
+		"gpt-4o":                 {Prompt: 2.5, Completion: 10, Cached: 1.25},
+		"gpt-4o-mini":            {Prompt: 0.15, Completion: 0.6, Cached: 0.075},
+		"gpt-4.1":                {Prompt: 2, Completion: 8, Cached: 0.5},
+		"gpt-4.1-mini":           {Prompt: 0.4, Completion: 1.6, Cached: 0.1},
+		"gpt-4.1-nano":           {Prompt: 0.1, Completion: 0.4, Cached: 0.025},
+		"o1":                     {Prompt: 15, Completion: 60, Cached: 7.5},
+		"o1-mini":                {Prompt: 1.1, Completion: 4.4, Cached: 0.55},
+		"o3-mini":                {Prompt: 1.1, Completion: 4.4, Cached: 0.55},
+		"text-embedding-ada-002": {Prompt: 0.1},
+		"text-embedding-3-small": {Prompt: 0.02},
+		"text-embedding-3-large": {Prompt: 0.13},
+		// Anthropic
+		"claude-3-opus":     {Prompt: 15, Completion: 75, Cached: 1.5},
+		"claude-3-sonnet":   {Prompt: 3, Completion: 15, Cached: 0.3},
+		"claude-3-haiku":    {Prompt: 0.25, Completion: 1.25, Cached: 0.03},
+		"claude-3-5-sonnet": {Prompt: 3, Completion: 15, Cached: 0.3},
+		"claude-3-5-haiku":  {Prompt: 0.8, Completion: 4, Cached: 0.08},
+		"claude-3-7-sonnet": {Prompt: 3, Completion: 15, Cached: 0.3},
+		// Cohere
+		"command":                 {Prompt: 1, Completion: 2},
+		"command-light":           {Prompt: 0.3, Completion: 0.6},
+		"command-r":               {Prompt: 0.15, Completion: 0.6},
+		"command-r-plus":          {Prompt: 2.5, Completion: 10},
+		"embed-english-v3.0":      {Prompt: 0.1},
+		"embed-multilingual-v3.0": {Prompt: 0.1},
+		// Voyage
+		"voyage-2": {Prompt: 0.1},
+	}
+}
+
+// Price returns the price of the model.
+func (p Pricing) Price(model string) (Price, bool) {
+	if price, ok := p[model]; ok {
+		return price, true
+	}
+
+	var found string
+	for name := range p {
+		if strings.HasPrefix(model, name) && len(name) > len(found) {
+			found = name
+		}
+	}
+
+	if found == "" {
+		return Price{}, false
+	}
+
+	return p[found], true
+}
+
+// Cost returns the cost of the usage in US dollars, or 0 when its model is not priced.
+func (p Pricing) Cost(u Usage) float64 {
+	price, ok := p.Price(u.Model)
+	if !ok {
+		return 0
+	}
+
+	cachedPrice := price.Cached
+	if cachedPrice == 0 {
+		cachedPrice = price.Prompt
+	}
+
+	cost := float64(u.PromptTokens-u.CachedTokens)*price.Prompt +
+		float64(u.CachedTokens)*cachedPrice +
+		float64(u.CompletionTokens)*price.Completion
+
+	return cost / tokensPerPriceUnit
+}
@@ -0,0 +1,194 @@
+// Package usage accounts the tokens used by the LLM and embedder providers, and their
+// cost according to a pricing table. The providers record the usage of each call into
+// the Tracker of the context, which aggregates it in total and per observer trace, and
+// reports when an optional budget is exceeded.
+package usage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/maksymenkoml/lingoose/observer"
+)
+
+var (
+	ErrBudgetExceeded = errors.New("usage budget exceeded")
+)
+
+type contextKey string
+
+const (
+	contextKeyTracker contextKey = "usage-tracker"
+)
+
+// Usage is the tokens used by one or more calls to a provider. PromptTokens include
+// the CachedTokens and the AudioTokens. Cost is in US dollars, it is 0 when the model
+// is not priced. Estimated reports that the tokens of some call were estimated, the
+// provider not reporting them.
+type Usage struct {
+	Provider         string
+	Model            string
+	PromptTokens     int
+	CompletionTokens int
+	AudioTokens      int
+	CachedTokens     int
+	Cost             float64
+	Estimated        bool
+}
+
+// TotalTokens returns the prompt and the completion tokens.
+func (u Usage) TotalTokens() int {
+	return u.PromptTokens + u.CompletionTokens
+}
+
+// Add returns the sum of the usages. Provider and Model are kept only if they are the
+// same in both usages.
+func (u Usage) Add(other Usage) Usage {
+	if u.Provider != other.Provider {
+		u.Provider = ""
+	}
+	if u.Model != other.Model {
+		u.Model = ""
+	}
+
+	u.PromptTokens += other.PromptTokens
+	u.CompletionTokens += other.CompletionTokens
+	u.AudioTokens += other.AudioTokens
+	u.CachedTokens += other.CachedTokens
+	u.Cost += other.Cost
+	u.Estimated = u.Estimated || other.Estimated
+
+	return u
+}
+
+// Budget caps the usage. A zero cap is not enforced.
+type Budget struct {
+	MaxCost   float64
+	MaxTokens int
+}
+
+// Tracker aggregates the recorded usage, in total and per observer trace. The usage
+// recorded into a tracker is recorded into its parent too. It is safe for concurrent
+// use.
+type Tracker struct {
+	mu      sync.Mutex
+	parent  *Tracker
+	pricing Pricing
+	budget  Budget
+	records int
+	total   Usage
+	traces  map[string]Usage
+}
+
+// NewTracker creates a tracker pricing the usage with DefaultPricing.
+func NewTracker() *Tracker {
+	return &Tracker{
+		pricing: DefaultPricing(),
+		traces:  make(map[string]Usage),
+	}
+}
+
+// WithPricing sets the pricing table used to compute the cost of the recorded usage.
+func (t *Tracker) WithPricing(pricing Pricing) *Tracker {
+	t.pricing = pricing
+	return t
+}
+
+// WithBudget sets the budget checked by CheckBudget.
+func (t *Tracker) WithBudget(budget Budget) *Tracker {
+	t.budget = budget
+	return t
+}
+
+// WithParent sets the tracker the usage is recorded into as well, e.g. a tracker of
+// the whole process.
+func (t *Tracker) WithParent(parent *Tracker) *Tracker {
+	t.parent = parent
+	return t
+}
+
+// Record adds the usage to the tracker, computing its cost when it is not set. The
+// usage is aggregated under the trace of the context too, if any.
+func (t *Tracker) Record(ctx context.Context, u Usage) {
+	if t == nil {
+		return
+	}
+
+	if u.Cost == 0 {
+		u.Cost = t.pricing.Cost(u)
+	}
+
+	t.mu.Lock()
+	if t.records == 0 {
+		t.total = u
+	} else {
+		t.total = t.total.Add(u)
+	}
+	t.records++
+
+	if traceID := observer.ContextValueTraceID(ctx); traceID != "" {
+		if traceUsage, ok := t.traces[traceID]; ok {
+			t.traces[traceID] = traceUsage.Add(u)
+		} else {
+			t.traces[traceID] = u
+		}
+	}
+	t.mu.Unlock()
+
+	t.parent.Record(ctx, u)
+}
+
+// Total returns the usage recorded so far.
+func (t *Tracker) Total() Usage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.total
+}
+
+// Trace returns the usage recorded so far under the given observer trace.
+func (t *Tracker) Trace(traceID string) Usage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.traces[traceID]
+}
+
+// CheckBudget returns ErrBudgetExceeded if the usage recorded so far exceeds the budget
+// of the tracker or of one of its parents.
+func (t *Tracker) CheckBudget() error {
+	if t == nil {
+		return nil
+	}
+
+	total := t.Total()
+
+	if t.budget.MaxCost > 0 && total.Cost > t.budget.MaxCost {
+		return fmt.Errorf("%w: cost %.4f USD over %.4f USD", ErrBudgetExceeded, total.Cost, t.budget.MaxCost)
+	}
+
+	if t.budget.MaxTokens > 0 && total.TotalTokens() > t.budget.MaxTokens {
+		return fmt.Errorf("%w: %d tokens over %d", ErrBudgetExceeded, total.TotalTokens(), t.budget.MaxTokens)
+	}
+
+	return t.parent.CheckBudget()
+}
+
+func ContextWithTracker(ctx context.Context, tracker *Tracker) context.Context {
+	return context.WithValue(ctx, contextKeyTracker, tracker)
+}
+
+func ContextValueTracker(ctx context.Context) *Tracker {
+	tracker, ok := ctx.Value(contextKeyTracker).(*Tracker)
+	if !ok {
+		return nil
+	}
+	return tracker
+}
+
+// Record records the usage into the tracker of the context, if any.
+func Record(ctx context.Context, u Usage) {
+	ContextValueTracker(ctx).Record(ctx, u)
+}
@@ -0,0 +1,114 @@
+package usage
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+	"testing"
+
+	"github.com/maksymenkoml/lingoose/observer"
+)
+
+func TestPricing_Cost(t *testing.T) {
+	pricing := DefaultPricing()
+
+	tests := []struct {
+		name  string
+		usage Usage
+		want  float64
+	}{
+		{
+			name:  "exact model",
+			usage: Usage{Model: "gpt-4o", PromptTokens: 1000000, CompletionTokens: 1000000},
+			want:  12.5,
+		},
+		{
+			name:  "dated model",
+			usage: Usage{Model: "gpt-4o-mini-2024-07-18", PromptTokens: 1000000},
+			want:  0.15,
+		},
+		{
+			name:  "cached tokens",
+			usage: Usage{Model: "claude-3-5-sonnet-20241022", PromptTokens: 2000000, CachedTokens: 1000000},
+			want:  3.3,
+		},
+		{
+			name:  "unknown model",
+			usage: Usage{Model: "llama2", PromptTokens: 1000000},
+			want:  0,
+		},
+	}
+	for _, tt := range tests {
+		if got := pricing.Cost(tt.usage); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%s: Cost() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestTracker_Record(t *testing.T) {
+	parent := NewTracker()
+	tracker := NewTracker().WithParent(parent).WithPricing(Pricing{"model": {Prompt: 1, Completion: 2}})
+
+	ctx := ContextWithTracker(context.Background(), tracker)
+	traceCtx := observer.ContextWithTraceID(ctx, "trace")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		recordCtx := ctx
+		if i%2 == 0 {
+			recordCtx = traceCtx
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			Record(recordCtx, Usage{Provider: "provider", Model: "model", PromptTokens: 100000, CompletionTokens: 50000})
+		}()
+	}
+	wg.Wait()
+
+	want := Usage{Provider: "provider", Model: "model", PromptTokens: 1000000, CompletionTokens: 500000, Cost: 2}
+	if got := tracker.Total(); got.TotalTokens() != want.TotalTokens() || math.Abs(got.Cost-want.Cost) > 1e-9 ||
+		got.Provider != want.Provider || got.Model != want.Model {
+		t.Errorf("Total() = %+v, want %+v", got, want)
+	}
+
+	if got := tracker.Trace("trace"); got.TotalTokens() != 750000 {
+		t.Errorf("Trace() = %d tokens, want 750000", got.TotalTokens())
+	}
+
+	// the cost is computed once, with the pricing of the tracker the usage is recorded into
+	if got := parent.Total(); got.TotalTokens() != want.TotalTokens() || math.Abs(got.Cost-want.Cost) > 1e-9 {
+		t.Errorf("parent Total() = %+v, want %+v", got, want)
+	}
+
+	// recording without a tracker is a no-op
+	Record(context.Background(), want)
+}
+
+func TestTracker_CheckBudget(t *testing.T) {
+	parent := NewTracker().WithBudget(Budget{MaxTokens: 100})
+	tracker := NewTracker().WithParent(parent).WithBudget(Budget{MaxCost: 1})
+
+	ctx := context.Background()
+	tracker.Record(ctx, Usage{PromptTokens: 100, Cost: 1})
+	if err := tracker.CheckBudget(); err != nil {
+		t.Errorf("CheckBudget() error = %v, want nil", err)
+	}
+
+	tracker.Record(ctx, Usage{CompletionTokens: 1})
+	if err := tracker.CheckBudget(); !errors.Is(err, ErrBudgetExceeded) {
+		t.Errorf("CheckBudget() error = %v, want the tokens budget of the parent exceeded", err)
+	}
+
+	tracker.Record(ctx, Usage{Cost: 0.5})
+	if err := tracker.CheckBudget(); !errors.Is(err, ErrBudgetExceeded) {
+		t.Errorf("CheckBudget() error = %v, want the cost budget exceeded", err)
+	}
+
+	var nilTracker *Tracker
+	if err := nilTracker.CheckBudget(); err != nil {
+		t.Errorf("CheckBudget() error = %v, want nil", err)
+	}
+}